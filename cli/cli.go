@@ -0,0 +1,242 @@
+// Package cli implements the mongorm command: migrations, index synchronization and collection
+// and schema inspection of a database.
+//
+// cmd/mongorm is the command for the index specs of a file; it has no migrations, as Go migrations
+// only exist in the binary of the service that registers them, so it offers migrate status but not
+// migrate up and down. Services that declare their indexes
+// with models or write their migrations in Go build the same command with their own App:
+//
+//	func main() {
+//		app := &cli.App{Models: []interface{}{&User{}}, Migrations: migrate.Registered()}
+//		os.Exit(app.Main(os.Args[1:]))
+//	}
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"go.mongodb.org/mongo-driver/x/mongo/driver/connstring"
+
+	"github.com/v1shn3vsk7/mongorm"
+	"github.com/v1shn3vsk7/mongorm/migrate"
+	"github.com/v1shn3vsk7/mongorm/options"
+)
+
+var (
+	// ErrUsage is returned for invalid command lines. The usage has been written to App.Stderr.
+	ErrUsage = errors.New("mongorm: invalid usage")
+
+	// ErrNoMigrations is returned together with ErrUsage for migrate up and down when the App has
+	// no migrations, e.g. in the stock mongorm command, whose usage does not list them.
+	ErrNoMigrations = errors.New("mongorm: no migrations, run the command built with the cli.App of the service")
+)
+
+const usageHead = `usage: mongorm [-uri uri] [-db name] <command> <subcommand> [flags]
+
+commands:
+`
+
+const usageMigrate = `  migrate up [-to version]        apply the pending migrations
+  migrate down [-to version]      roll back the last migration, or every one above -to
+`
+
+const usageCommands = `  migrate status                  list the migrations and whether they are applied
+  indexes diff [-f file] [-drop]  print the index changes sync would make
+  indexes sync [-f file] [-drop]  create the declared indexes, dropping undeclared ones with -drop
+  collections list                list the collections
+  collections stats [-c names]    print the document count and sizes of collections
+  schema dump [-c names] [-sample n]
+                                  print the fields and types found in sampled documents
+
+The connection URI defaults to $MONGODB_URI and the database to the one of the URI.
+Index files map collection names to index descriptions in the format of listIndexes, e.g.
+  {"users": [{"key": {"email": 1}, "unique": true}]}
+`
+
+const usageNoMigrations = `
+Migrations are written in Go, so this command only lists the applied ones. migrate up and
+down are run by the command a service builds with a cli.App holding its migrations.
+`
+
+// App is the mongorm command.
+type App struct {
+	// Models are registered on the client; indexes sync and diff use their index specs without -f.
+	Models []interface{}
+
+	// Migrations are run by the migrate commands.
+	Migrations []migrate.Migration
+
+	// Client is used instead of connecting to -uri when set.
+	Client *mongorm.Client
+
+	// Stdout and Stderr default to os.Stdout and os.Stderr.
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// Main runs the command line args until an interrupt and returns the exit code: 0 on success,
+// 2 for usage errors and 1 for other errors, which are written to Stderr.
+func (a *App) Main(args []string) int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err := a.Run(ctx, args)
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.Is(err, ErrUsage):
+		return 2
+	default:
+		fmt.Fprintln(a.stderr(), err)
+		return 1
+	}
+}
+
+// Run runs the command line args, without the program name.
+func (a *App) Run(ctx context.Context, args []string) error {
+	flags := a.flagSet("mongorm")
+	uri := flags.String("uri", os.Getenv("MONGODB_URI"), "connection `uri`")
+	dbName := flags.String("db", "", "database `name`, the one of the URI by default")
+
+	if err := flags.Parse(args); err != nil {
+		return a.usageErr(err)
+	}
+
+	args = flags.Args()
+	if len(args) < 2 {
+		return a.usageErr(nil)
+	}
+
+	run, ok := commands[args[0]+" "+args[1]]
+	if !ok {
+		return a.usageErr(fmt.Errorf("unknown command %q", args[0]+" "+args[1]))
+	}
+
+	if migrates[args[0]+" "+args[1]] && len(a.Migrations) == 0 {
+		fmt.Fprintln(a.stderr(), ErrNoMigrations)
+		fmt.Fprint(a.stderr(), a.usage())
+
+		return fmt.Errorf("%w: %w", ErrUsage, ErrNoMigrations)
+	}
+
+	client := a.Client
+	if client == nil {
+		if *uri == "" {
+			return a.usageErr(errors.New("missing -uri"))
+		}
+
+		var err error
+		if client, err = mongorm.New(ctx, options.Client().ApplyURI(*uri)); err != nil {
+			return err
+		}
+
+		defer client.Disconnect(context.Background())
+	}
+
+	if err := client.Register(a.Models...); err != nil {
+		return err
+	}
+
+	if *dbName == "" && *uri != "" {
+		cs, err := connstring.ParseAndValidate(*uri)
+		if err != nil {
+			return fmt.Errorf("mongorm: parse uri: %w", err)
+		}
+		*dbName = cs.Database
+	}
+
+	if *dbName == "" {
+		return a.usageErr(errors.New("missing -db and no database in the URI"))
+	}
+
+	return run(ctx, a, client.Database(*dbName), args[:2], args[2:])
+}
+
+// command runs the subcommand name, e.g. ["migrate", "up"], with its args.
+type command func(ctx context.Context, a *App, db *mongorm.Database, name, args []string) error
+
+var commands map[string]command
+
+// migrates are the commands that need the migrations of the App.
+var migrates = map[string]bool{"migrate up": true, "migrate down": true}
+
+func init() {
+	commands = map[string]command{
+		"migrate up":        migrateUp,
+		"migrate down":      migrateDown,
+		"migrate status":    migrateStatus,
+		"indexes diff":      indexesDiff,
+		"indexes sync":      indexesSync,
+		"collections list":  collectionsList,
+		"collections stats": collectionsStats,
+		"schema dump":       schemaDump,
+	}
+}
+
+func (a *App) flagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	flags.Usage = func() {}
+
+	return flags
+}
+
+// parse parses the flags of the subcommand name, which takes no positional arguments.
+func (a *App) parse(flags *flag.FlagSet, name, args []string) error {
+	if err := flags.Parse(args); err != nil {
+		return a.usageErr(err)
+	}
+
+	if flags.NArg() > 0 {
+		return a.usageErr(fmt.Errorf("unexpected arguments of %s %s: %v", name[0], name[1], flags.Args()))
+	}
+
+	return nil
+}
+
+// usageErr writes err and the usage to Stderr and returns ErrUsage, or flag.ErrHelp for -h.
+func (a *App) usageErr(err error) error {
+	if errors.Is(err, flag.ErrHelp) {
+		fmt.Fprint(a.stderr(), a.usage())
+		return err
+	}
+
+	if err != nil {
+		fmt.Fprintln(a.stderr(), err)
+	}
+	fmt.Fprint(a.stderr(), a.usage())
+
+	return ErrUsage
+}
+
+// usage lists migrate up and down only when the App has migrations to run.
+func (a *App) usage() string {
+	if len(a.Migrations) == 0 {
+		return usageHead + usageCommands + usageNoMigrations
+	}
+
+	return usageHead + usageMigrate + usageCommands
+}
+
+func (a *App) stdout() io.Writer {
+	if a.Stdout != nil {
+		return a.Stdout
+	}
+
+	return os.Stdout
+}
+
+func (a *App) stderr() io.Writer {
+	if a.Stderr != nil {
+		return a.Stderr
+	}
+
+	return os.Stderr
+}
@@ -0,0 +1,228 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/v1shn3vsk7/mongorm"
+	"github.com/v1shn3vsk7/mongorm/migrate"
+)
+
+type user struct {
+	ID    int    `bson:"_id"`
+	Email string `bson:"email" mongorm:"unique"`
+}
+
+// testApp returns an App on an in-memory client, with buffers as its outputs.
+func testApp(t *testing.T, app *App) (*App, *bytes.Buffer, *bytes.Buffer) {
+	t.Helper()
+
+	client, err := mongorm.NewMemoryClient()
+	if err != nil {
+		t.Fatalf("NewMemoryClient: %v", err)
+	}
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	app.Client, app.Stdout, app.Stderr = client, stdout, stderr
+
+	return app, stdout, stderr
+}
+
+func run(t *testing.T, app *App, args ...string) error {
+	t.Helper()
+
+	return app.Run(context.Background(), append([]string{"-db", "test"}, args...))
+}
+
+func TestUsage(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want error
+	}{
+		{"no command", []string{}, ErrUsage},
+		{"unknown command", []string{"indexes", "drop"}, ErrUsage},
+		{"unknown flag", []string{"collections", "list", "-x"}, ErrUsage},
+		{"positional argument", []string{"collections", "list", "x"}, ErrUsage},
+		{"help", []string{"-h"}, flag.ErrHelp},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, _, stderr := testApp(t, &App{})
+
+			if err := run(t, app, tt.args...); !errors.Is(err, tt.want) {
+				t.Errorf("Run(%v): error %v, want %v", tt.args, err, tt.want)
+			}
+
+			if !strings.Contains(stderr.String(), "usage: mongorm") {
+				t.Errorf("Run(%v) wrote %q, want the usage", tt.args, stderr)
+			}
+		})
+	}
+
+	app, _, _ := testApp(t, &App{})
+	if err := app.Run(context.Background(), []string{"collections", "list"}); !errors.Is(err, ErrUsage) {
+		t.Errorf("Run without -db: error %v, want ErrUsage", err)
+	}
+}
+
+func TestMigrateWithoutMigrations(t *testing.T) {
+	for _, sub := range []string{"up", "down"} {
+		app, stdout, stderr := testApp(t, &App{})
+
+		err := run(t, app, "migrate", sub)
+		if !errors.Is(err, ErrUsage) || !errors.Is(err, ErrNoMigrations) {
+			t.Errorf("migrate %s: error %v, want ErrUsage and ErrNoMigrations", sub, err)
+		}
+
+		if strings.Contains(stderr.String(), "migrate up [-to") || stdout.Len() > 0 {
+			t.Errorf("migrate %s wrote %q and %q, want a usage without migrate up", sub, stdout, stderr)
+		}
+	}
+
+	app, stdout, _ := testApp(t, &App{})
+	if err := run(t, app, "migrate", "status"); err != nil || !strings.HasPrefix(stdout.String(), "VERSION") {
+		t.Errorf("migrate status = %v, %q, want the header of an empty table", err, stdout)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	noop := func(context.Context, *mongorm.Database) error { return nil }
+	migrations := []migrate.Migration{
+		{Version: 1, Description: "first", Up: noop, Down: noop},
+		{Version: 2, Description: "second", Up: noop, Down: noop},
+	}
+
+	app, stdout, stderr := testApp(t, &App{Migrations: migrations})
+
+	if err := run(t, app, "-h"); !errors.Is(err, flag.ErrHelp) || !strings.Contains(stderr.String(), "migrate up [-to") {
+		t.Errorf("-h = %v, %q, want a usage with migrate up", err, stderr)
+	}
+
+	steps := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"migrate", "up", "-to", "1"}, "applied 1\n"},
+		{[]string{"migrate", "up"}, "applied 2\n"},
+		{[]string{"migrate", "up"}, "no pending migrations\n"},
+		{[]string{"migrate", "down"}, "reverted 2\n"},
+		{[]string{"migrate", "down", "-to", "0"}, "reverted 1\n"},
+		{[]string{"migrate", "down"}, "no migrations to roll back\n"},
+	}
+
+	for _, step := range steps {
+		stdout.Reset()
+
+		if err := run(t, app, step.args...); err != nil {
+			t.Fatalf("Run(%v): %v", step.args, err)
+		}
+
+		if stdout.String() != step.want {
+			t.Errorf("Run(%v) wrote %q, want %q", step.args, stdout, step.want)
+		}
+	}
+
+	stdout.Reset()
+	if err := run(t, app, "migrate", "status"); err != nil {
+		t.Fatalf("migrate status: %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(stdout.String()), "\n"); len(lines) != 3 || !strings.Contains(lines[1], "pending") {
+		t.Errorf("migrate status wrote %q, want 2 pending migrations", stdout)
+	}
+}
+
+func TestIndexes(t *testing.T) {
+	app, stdout, _ := testApp(t, &App{Models: []interface{}{user{}}})
+
+	if err := run(t, app, "indexes", "diff"); err != nil || stdout.String() != "create users.email_1 {email: 1}\n" {
+		t.Errorf("indexes diff = %v, %q, want the creation of email_1", err, stdout)
+	}
+
+	stdout.Reset()
+	if err := run(t, app, "indexes", "sync"); err != nil {
+		t.Fatalf("indexes sync: %v", err)
+	}
+
+	stdout.Reset()
+	if err := run(t, app, "indexes", "diff"); err != nil || stdout.String() != "indexes are in sync\n" {
+		t.Errorf("indexes diff after sync = %v, %q, want no changes", err, stdout)
+	}
+
+	file := filepath.Join(t.TempDir(), "indexes.json")
+	if err := os.WriteFile(file, []byte(`{"users": [{"key": {"email": 1}, "unique": true}, {"key": {"age": -1}, "partialFilterExpression": {"age": {"$gt": 0}}}]}`), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	stdout.Reset()
+	if err := run(t, app, "indexes", "diff", "-f", file); err != nil || stdout.String() != "create users.age_-1 {age: -1}\n" {
+		t.Errorf("indexes diff -f = %v, %q, want the creation of age_-1", err, stdout)
+	}
+
+	if err := os.WriteFile(file, []byte(`{"users": [{"key": {"email": 1}, "collation": {}}]}`), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := run(t, app, "indexes", "diff", "-f", file); err == nil {
+		t.Error("indexes diff of an unknown option succeeded")
+	}
+}
+
+func TestCollectionsAndSchema(t *testing.T) {
+	app, stdout, _ := testApp(t, &App{})
+
+	ctx := context.Background()
+	db := app.Client.Database("test")
+	for _, name := range []string{"b", "a"} {
+		if _, err := db.Collection(name).InsertOne(ctx, bson.D{{Key: "n", Value: 1}}); err != nil {
+			t.Fatalf("InsertOne: %v", err)
+		}
+	}
+	if _, err := db.Collection("a").InsertOne(ctx, bson.D{{Key: "n", Value: "x"}, {Key: "tags", Value: bson.A{"y"}}}); err != nil {
+		t.Fatalf("InsertOne: %v", err)
+	}
+
+	if err := run(t, app, "collections", "list"); err != nil || stdout.String() != "a\nb\n" {
+		t.Errorf("collections list = %v, %q, want a and b", err, stdout)
+	}
+
+	stdout.Reset()
+	if err := run(t, app, "schema", "dump", "-c", "a"); err != nil {
+		t.Fatalf("schema dump: %v", err)
+	}
+
+	out := stdout.String()
+	for _, want := range []string{"a (2 documents sampled)", "n", "tags", "50%"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("schema dump wrote %q, want %q in it", out, want)
+		}
+	}
+}
+
+func TestMainExitCode(t *testing.T) {
+	tests := []struct {
+		args []string
+		want int
+	}{
+		{[]string{"-db", "test", "collections", "list"}, 0},
+		{[]string{"-h"}, 0},
+		{[]string{"-db", "test", "migrate", "up"}, 2},
+		{[]string{"-db", "test", "indexes", "diff", "-f", "missing.json"}, 1},
+	}
+
+	for _, tt := range tests {
+		app, _, _ := testApp(t, &App{})
+
+		if got := app.Main(tt.args); got != tt.want {
+			t.Errorf("Main(%v) = %d, want %d", tt.args, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/v1shn3vsk7/mongorm"
+)
+
+func collectionsList(ctx context.Context, a *App, db *mongorm.Database, name, args []string) error {
+	if err := a.parse(a.flagSet("collections list"), name, args); err != nil {
+		return err
+	}
+
+	names, err := db.CollectionNames(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, collection := range names {
+		fmt.Fprintln(a.stdout(), collection)
+	}
+
+	return nil
+}
+
+type collectionStats struct {
+	Count          int64 `bson:"count"`
+	Size           int64 `bson:"size"`
+	StorageSize    int64 `bson:"storageSize"`
+	Indexes        int64 `bson:"nindexes"`
+	TotalIndexSize int64 `bson:"totalIndexSize"`
+}
+
+func collectionsStats(ctx context.Context, a *App, db *mongorm.Database, name, args []string) error {
+	flags := a.flagSet("collections stats")
+	names := collectionsFlag(flags)
+
+	if err := a.parse(flags, name, args); err != nil {
+		return err
+	}
+
+	collections, err := selectCollections(ctx, db, *names)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(a.stdout(), 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "COLLECTION\tDOCUMENTS\tSIZE\tSTORAGE SIZE\tINDEXES\tINDEX SIZE\t")
+
+	for _, collection := range collections {
+		var stats []struct {
+			StorageStats collectionStats `bson:"storageStats"`
+		}
+
+		err := db.Collection(collection).Pipeline().
+			Stage(bson.D{{Key: "$collStats", Value: bson.D{{Key: "storageStats", Value: bson.D{}}}}}).
+			Aggregate(ctx, &stats)
+		if err != nil {
+			w.Flush()
+			return err
+		}
+
+		var s collectionStats
+		if len(stats) > 0 {
+			s = stats[0].StorageStats
+		}
+
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%d\t\n", collection, s.Count, s.Size, s.StorageSize, s.Indexes, s.TotalIndexSize)
+	}
+
+	return w.Flush()
+}
+
+// collectionsFlag adds the -c flag selecting collections by a comma-separated list of names.
+func collectionsFlag(flags *flag.FlagSet) *string {
+	return flags.String("c", "", "comma-separated collection `names`, all by default")
+}
+
+// selectCollections returns the collections of the -c flag, or all of them.
+func selectCollections(ctx context.Context, db *mongorm.Database, names string) ([]string, error) {
+	if names == "" {
+		return db.CollectionNames(ctx)
+	}
+
+	collections := make([]string, 0)
+	for _, collection := range strings.Split(names, ",") {
+		if collection = strings.TrimSpace(collection); collection != "" {
+			collections = append(collections, collection)
+		}
+	}
+
+	return collections, nil
+}
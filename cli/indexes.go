@@ -0,0 +1,229 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/v1shn3vsk7/mongorm"
+	"github.com/v1shn3vsk7/mongorm/internal/matcher"
+	"github.com/v1shn3vsk7/mongorm/options"
+)
+
+func indexesDiff(ctx context.Context, a *App, db *mongorm.Database, name, args []string) error {
+	return a.syncIndexes(ctx, db, name, args, true)
+}
+
+func indexesSync(ctx context.Context, a *App, db *mongorm.Database, name, args []string) error {
+	return a.syncIndexes(ctx, db, name, args, false)
+}
+
+func (a *App) syncIndexes(ctx context.Context, db *mongorm.Database, name, args []string, dryRun bool) error {
+	flags := a.flagSet(strings.Join(name, " "))
+	file := flags.String("f", "", "index `file`, the registered models by default")
+	drop := flags.Bool("drop", false, "drop undeclared indexes")
+
+	if err := a.parse(flags, name, args); err != nil {
+		return err
+	}
+
+	opts := options.SyncIndexes().SetDryRun(dryRun).SetDropUndeclared(*drop)
+
+	var changes []mongorm.IndexChange
+	if *file == "" {
+		var err error
+		if changes, err = db.SyncIndexes(ctx, opts); err != nil {
+			return err
+		}
+	} else {
+		specs, err := readIndexFile(*file)
+		if err != nil {
+			return err
+		}
+
+		collections := make([]string, 0, len(specs))
+		for collection := range specs {
+			collections = append(collections, collection)
+		}
+		sort.Strings(collections)
+
+		for _, collection := range collections {
+			c, err := db.Collection(collection).SyncIndexes(ctx, specs[collection], opts)
+			changes = append(changes, c...)
+			if err != nil {
+				printChanges(a, changes)
+				return err
+			}
+		}
+	}
+
+	printChanges(a, changes)
+
+	return nil
+}
+
+func printChanges(a *App, changes []mongorm.IndexChange) {
+	if len(changes) == 0 {
+		fmt.Fprintln(a.stdout(), "indexes are in sync")
+		return
+	}
+
+	for _, change := range changes {
+		fmt.Fprintln(a.stdout(), change)
+	}
+}
+
+// readIndexFile reads the index specs of an index file: a JSON object mapping collection names to arrays
+// of index descriptions in the format of listIndexes.
+func readIndexFile(path string) (map[string][]mongorm.IndexSpec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("mongorm: read index file: %w", err)
+	}
+
+	var doc bson.D
+	if err := bson.UnmarshalExtJSON(data, false, &doc); err != nil {
+		return nil, fmt.Errorf("mongorm: parse index file %s: %w", path, err)
+	}
+
+	specs := make(map[string][]mongorm.IndexSpec, len(doc))
+	for _, elem := range doc {
+		list, ok := elem.Value.(bson.A)
+		if !ok {
+			return nil, fmt.Errorf("mongorm: index file %s: %s: expected an array of indexes", path, elem.Key)
+		}
+
+		for i, item := range list {
+			desc, ok := item.(bson.D)
+			if !ok {
+				return nil, fmt.Errorf("mongorm: index file %s: %s[%d]: expected an index document", path, elem.Key, i)
+			}
+
+			spec, err := indexSpec(desc)
+			if err != nil {
+				return nil, fmt.Errorf("mongorm: index file %s: %s[%d]: %w", path, elem.Key, i, err)
+			}
+
+			specs[elem.Key] = append(specs[elem.Key], spec)
+		}
+	}
+
+	return specs, nil
+}
+
+// indexSpec converts an index description in the format of listIndexes.
+func indexSpec(desc bson.D) (mongorm.IndexSpec, error) {
+	var spec mongorm.IndexSpec
+
+	for _, opt := range desc {
+		var ok bool
+
+		switch opt.Key {
+		case "v", "ns":
+			ok = true
+		case "key":
+			spec.Keys, ok = opt.Value.(bson.D)
+		case "name":
+			spec.Name, ok = opt.Value.(string)
+		case "unique":
+			spec.Unique, ok = opt.Value.(bool)
+		case "sparse":
+			spec.Sparse, ok = opt.Value.(bool)
+		case "expireAfterSeconds":
+			var seconds float64
+			if seconds, ok = matcher.ToFloat(opt.Value); ok {
+				expire := time.Duration(seconds * float64(time.Second))
+				spec.ExpireAfter = &expire
+			}
+		case "weights":
+			spec.Weights, ok = opt.Value.(bson.D)
+		case "default_language":
+			spec.DefaultLanguage, ok = opt.Value.(string)
+		case "wildcardProjection":
+			spec.WildcardProjection, ok = opt.Value.(bson.D)
+		case "partialFilterExpression":
+			filter, isDoc := opt.Value.(bson.D)
+			if !isDoc {
+				break
+			}
+
+			q, err := partialQuery(filter)
+			if err != nil {
+				return spec, err
+			}
+			spec.Partial, ok = q, true
+		default:
+			return spec, fmt.Errorf("unknown index option %q", opt.Key)
+		}
+
+		if !ok {
+			return spec, fmt.Errorf("invalid value of %s: %v", opt.Key, opt.Value)
+		}
+	}
+
+	if len(spec.Keys) == 0 {
+		return spec, fmt.Errorf("missing key")
+	}
+
+	return spec, nil
+}
+
+// partialQuery builds the query of a partial filter expression made of field conditions,
+// the only filters partial indexes accept besides $and of them.
+func partialQuery(filter bson.D) (*mongorm.Query, error) {
+	q := mongorm.NewQuery()
+
+	for _, elem := range filter {
+		if elem.Key == "$and" {
+			list, _ := elem.Value.(bson.A)
+			for _, item := range list {
+				sub, ok := item.(bson.D)
+				if !ok {
+					return nil, fmt.Errorf("invalid $and of partialFilterExpression: %v", elem.Value)
+				}
+
+				subQuery, err := partialQuery(sub)
+				if err != nil {
+					return nil, err
+				}
+				q = q.Group(func(*mongorm.Query) *mongorm.Query { return subQuery })
+			}
+			continue
+		}
+
+		if strings.HasPrefix(elem.Key, "$") {
+			return nil, fmt.Errorf("unsupported operator %s in partialFilterExpression", elem.Key)
+		}
+
+		conds, ok := elem.Value.(bson.D)
+		if !ok || len(conds) == 0 || !strings.HasPrefix(conds[0].Key, "$") {
+			q = q.Where(elem.Key, mongorm.EQ, elem.Value)
+			continue
+		}
+
+		for _, cond := range conds {
+			op, ok := condOperator(cond.Key)
+			if !ok {
+				return nil, fmt.Errorf("unsupported operator %s in partialFilterExpression", cond.Key)
+			}
+			q = q.Where(elem.Key, op, cond.Value)
+		}
+	}
+
+	return q, q.Err()
+}
+
+func condOperator(name string) (mongorm.CondOperator, bool) {
+	for op := mongorm.EQ; op <= mongorm.NEARSPHERE; op++ {
+		if op.String() == name {
+			return op, true
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/v1shn3vsk7/mongorm"
+	"github.com/v1shn3vsk7/mongorm/migrate"
+)
+
+func migrateUp(ctx context.Context, a *App, db *mongorm.Database, name, args []string) error {
+	flags := a.flagSet("migrate up")
+	to := flags.Int64("to", migrate.Latest, "target `version`")
+
+	if err := a.parse(flags, name, args); err != nil {
+		return err
+	}
+
+	m, err := migrate.New(db, a.Migrations)
+	if err != nil {
+		return err
+	}
+
+	applied, err := m.Migrate(ctx, *to)
+	for _, version := range applied {
+		fmt.Fprintf(a.stdout(), "applied %d\n", version)
+	}
+
+	if err == nil && len(applied) == 0 {
+		fmt.Fprintln(a.stdout(), "no pending migrations")
+	}
+
+	return err
+}
+
+func migrateDown(ctx context.Context, a *App, db *mongorm.Database, name, args []string) error {
+	flags := a.flagSet("migrate down")
+	to := flags.Int64("to", -1, "target `version`, the one before the last applied by default")
+
+	if err := a.parse(flags, name, args); err != nil {
+		return err
+	}
+
+	m, err := migrate.New(db, a.Migrations)
+	if err != nil {
+		return err
+	}
+
+	if *to < 0 {
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		*to = previousVersion(statuses)
+	}
+
+	reverted, err := m.Rollback(ctx, *to)
+	for _, version := range reverted {
+		fmt.Fprintf(a.stdout(), "reverted %d\n", version)
+	}
+
+	if err == nil && len(reverted) == 0 {
+		fmt.Fprintln(a.stdout(), "no migrations to roll back")
+	}
+
+	return err
+}
+
+// previousVersion returns the version of the applied migration before the last one, or 0.
+func previousVersion(statuses []migrate.Status) int64 {
+	var last, previous int64
+	for _, s := range statuses {
+		if s.Applied && !s.Missing {
+			last, previous = s.Version, last
+		}
+	}
+
+	return previous
+}
+
+func migrateStatus(ctx context.Context, a *App, db *mongorm.Database, name, args []string) error {
+	if err := a.parse(a.flagSet("migrate status"), name, args); err != nil {
+		return err
+	}
+
+	m, err := migrate.New(db, a.Migrations)
+	if err != nil {
+		return err
+	}
+
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(a.stdout(), 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tSTATUS\tAPPLIED AT\tDESCRIPTION")
+
+	for _, s := range statuses {
+		state, appliedAt := "pending", "-"
+		if s.Applied {
+			state, appliedAt = "applied", s.AppliedAt.Format(time.RFC3339)
+		}
+		if s.Missing {
+			state = "missing"
+		}
+
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", s.Version, state, appliedAt, s.Description)
+	}
+
+	return w.Flush()
+}
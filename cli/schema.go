@@ -0,0 +1,164 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/v1shn3vsk7/mongorm"
+)
+
+func schemaDump(ctx context.Context, a *App, db *mongorm.Database, name, args []string) error {
+	flags := a.flagSet("schema dump")
+	names := collectionsFlag(flags)
+	sample := flags.Int64("sample", 100, "`number` of documents sampled per collection")
+
+	if err := a.parse(flags, name, args); err != nil {
+		return err
+	}
+
+	collections, err := selectCollections(ctx, db, *names)
+	if err != nil {
+		return err
+	}
+
+	for i, collection := range collections {
+		var docs []bson.D
+		if err := db.Collection(collection).Query().Limit(*sample).All(ctx, &docs); err != nil {
+			return err
+		}
+
+		if i > 0 {
+			fmt.Fprintln(a.stdout())
+		}
+		fmt.Fprintf(a.stdout(), "%s (%d documents sampled)\n", collection, len(docs))
+
+		fields := make(map[string]*fieldStats)
+		for _, doc := range docs {
+			seen := make(map[string]bool)
+			inferFields(fields, seen, "", doc)
+		}
+
+		paths := make([]string, 0, len(fields))
+		for path := range fields {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+
+		w := tabwriter.NewWriter(a.stdout(), 0, 8, 2, ' ', 0)
+		for _, path := range paths {
+			f := fields[path]
+			fmt.Fprintf(w, "  %s\t%s\t%d%%\n", path, strings.Join(f.types(), ", "), f.count*100/len(docs))
+		}
+
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// fieldStats counts the documents with a field and the BSON types of its values.
+type fieldStats struct {
+	count     int
+	typeCount map[string]int
+}
+
+// types returns the BSON types of the field, the most frequent first.
+func (f *fieldStats) types() []string {
+	types := make([]string, 0, len(f.typeCount))
+	for t := range f.typeCount {
+		types = append(types, t)
+	}
+
+	sort.Slice(types, func(i, j int) bool {
+		if f.typeCount[types[i]] != f.typeCount[types[j]] {
+			return f.typeCount[types[i]] > f.typeCount[types[j]]
+		}
+		return types[i] < types[j]
+	})
+
+	return types
+}
+
+// inferFields records the fields of doc under prefix, descending into embedded documents and
+// documents in arrays. seen keeps a field from being counted twice for a document.
+func inferFields(fields map[string]*fieldStats, seen map[string]bool, prefix string, doc bson.D) {
+	for _, elem := range doc {
+		path := prefix + elem.Key
+
+		f, ok := fields[path]
+		if !ok {
+			f = &fieldStats{typeCount: make(map[string]int)}
+			fields[path] = f
+		}
+
+		t := bsonType(elem.Value)
+		if !seen[path] {
+			f.count++
+		}
+		if !seen[path+"\x00"+t] {
+			f.typeCount[t]++
+		}
+		seen[path], seen[path+"\x00"+t] = true, true
+
+		switch v := elem.Value.(type) {
+		case bson.D:
+			inferFields(fields, seen, path+".", v)
+		case bson.A:
+			for _, item := range v {
+				if sub, ok := item.(bson.D); ok {
+					inferFields(fields, seen, path+".", sub)
+				}
+			}
+		}
+	}
+}
+
+// bsonType returns the $type alias of a decoded value.
+func bsonType(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case int32:
+		return "int"
+	case int64:
+		return "long"
+	case float64:
+		return "double"
+	case bool:
+		return "bool"
+	case bson.D:
+		return "object"
+	case bson.A:
+		return "array"
+	case primitive.ObjectID:
+		return "objectId"
+	case primitive.DateTime:
+		return "date"
+	case primitive.Binary:
+		return "binData"
+	case primitive.Decimal128:
+		return "decimal"
+	case primitive.Regex:
+		return "regex"
+	case primitive.Timestamp:
+		return "timestamp"
+	case primitive.JavaScript, primitive.CodeWithScope:
+		return "javascript"
+	case primitive.MinKey:
+		return "minKey"
+	case primitive.MaxKey:
+		return "maxKey"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
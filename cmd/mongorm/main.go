@@ -0,0 +1,16 @@
+// Command mongorm synchronizes indexes and inspects the collections and applied migrations of a
+// database. It cannot apply migrations, which are written in Go: services run migrate up and down
+// with the same command built from a cli.App holding theirs, see package cli.
+// Run mongorm -h for the commands.
+package main
+
+import (
+	"os"
+
+	"github.com/v1shn3vsk7/mongorm/cli"
+)
+
+func main() {
+	app := &cli.App{}
+	os.Exit(app.Main(os.Args[1:]))
+}
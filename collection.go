@@ -1,23 +1,242 @@
 package mongorm
 
 import (
+	"context"
+	"reflect"
+
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	mongo_options "go.mongodb.org/mongo-driver/mongo/options"
 
 	"github.com/v1shn3vsk7/mongorm/options"
 )
 
+// Collection is a collection of a server or of an in-memory client. The raw methods with the signatures of
+// *mongo.Collection, such as InsertOne, Find or UpdateMany, run against either. The embedded *mongo.Collection
+// is nil for in-memory clients, so its other methods, e.g. Watch or Drop, only work with a server.
 type Collection struct {
 	*mongo.Collection
+
+	db    *Database
+	store store
+	model *Model
+
+	// docType is the document type of typed collections, whose hooks are called.
+	docType reflect.Type
 }
 
+// Collection returns the collection called name. It carries the registered model whose collection
+// it is, so that its queries skip soft deleted documents and its updates set the fields of the model,
+// unless several registered models share the collection.
 func (db *Database) Collection(name string, opts ...*options.CollectionOptions) *Collection {
+	var model *Model
+	if db.client != nil {
+		model, _ = db.client.models.collection(name)
+	}
+
+	if db.memory != nil {
+		return &Collection{
+			db:    db,
+			store: db.memory.Collection(name),
+			model: model,
+		}
+	}
+
 	mongoOpts := make([]*mongo_options.CollectionOptions, 0, len(opts))
 	for _, opt := range opts {
 		mongoOpts = append(mongoOpts, opt.ToMongo())
 	}
 
+	collection := db.Database.Collection(name, mongoOpts...)
+
 	return &Collection{
-		db.Database.Collection(name, mongoOpts...),
+		Collection: collection,
+		db:         db,
+		store:      driverStore{collection},
+		model:      model,
 	}
 }
+
+// Name returns the name of the collection.
+func (c *Collection) Name() string {
+	return c.storage().Name()
+}
+
+// CreateIndexes creates the indexes described by models and returns their names.
+// In-memory collections enforce unique indexes and record the others.
+func (c *Collection) CreateIndexes(ctx context.Context, models ...mongo.IndexModel) ([]string, error) {
+	s := c.storage()
+	if s == nil {
+		return nil, ErrNoCollection
+	}
+
+	names, err := s.CreateIndexes(ctx, models)
+
+	return names, c.mapErr("create indexes", err)
+}
+
+// Model returns the registered model of the documents of the collection, or nil when the type of a
+// typed collection or, for untyped collections, the collection name does not belong to one.
+func (c *Collection) Model() *Model {
+	return c.model
+}
+
+// storage returns the store of the collection, falling back to the embedded
+// *mongo.Collection for collections built without Database.Collection.
+func (c *Collection) storage() store {
+	if c.store != nil {
+		return c.store
+	}
+
+	if c.Collection != nil {
+		return driverStore{c.Collection}
+	}
+
+	return nil
+}
+
+// noCollection is the result of the raw methods of collections without a store.
+func noCollection() *mongo.SingleResult {
+	return mongo.NewSingleResultFromDocument(bson.D{}, ErrNoCollection, nil)
+}
+
+// Find runs the raw filter like (*mongo.Collection).Find, without the scopes of Query.
+func (c *Collection) Find(ctx context.Context, filter interface{}, opts ...*mongo_options.FindOptions) (*mongo.Cursor, error) {
+	s := c.storage()
+	if s == nil {
+		return nil, ErrNoCollection
+	}
+
+	return s.Find(ctx, filter, opts...)
+}
+
+// FindOne runs the raw filter like (*mongo.Collection).FindOne, without the scopes of Query.
+func (c *Collection) FindOne(ctx context.Context, filter interface{}, opts ...*mongo_options.FindOneOptions) *mongo.SingleResult {
+	s := c.storage()
+	if s == nil {
+		return noCollection()
+	}
+
+	return s.FindOne(ctx, filter, opts...)
+}
+
+// CountDocuments counts the documents matched by the raw filter like (*mongo.Collection).CountDocuments.
+func (c *Collection) CountDocuments(ctx context.Context, filter interface{}, opts ...*mongo_options.CountOptions) (int64, error) {
+	s := c.storage()
+	if s == nil {
+		return 0, ErrNoCollection
+	}
+
+	return s.CountDocuments(ctx, filter, opts...)
+}
+
+// Distinct returns the distinct values of fieldName like (*mongo.Collection).Distinct.
+func (c *Collection) Distinct(ctx context.Context, fieldName string, filter interface{}, opts ...*mongo_options.DistinctOptions) ([]interface{}, error) {
+	s := c.storage()
+	if s == nil {
+		return nil, ErrNoCollection
+	}
+
+	return s.Distinct(ctx, fieldName, filter, opts...)
+}
+
+// Aggregate runs the raw pipeline like (*mongo.Collection).Aggregate, see Pipeline for a builder.
+func (c *Collection) Aggregate(ctx context.Context, pipeline interface{}, opts ...*mongo_options.AggregateOptions) (*mongo.Cursor, error) {
+	s := c.storage()
+	if s == nil {
+		return nil, ErrNoCollection
+	}
+
+	return s.Aggregate(ctx, pipeline, opts...)
+}
+
+// InsertOne inserts document like (*mongo.Collection).InsertOne, without setting the fields of its model.
+func (c *Collection) InsertOne(ctx context.Context, document interface{}, opts ...*mongo_options.InsertOneOptions) (*mongo.InsertOneResult, error) {
+	s := c.storage()
+	if s == nil {
+		return nil, ErrNoCollection
+	}
+
+	return s.InsertOne(ctx, document, opts...)
+}
+
+// InsertMany inserts documents like (*mongo.Collection).InsertMany, without setting the fields of their model.
+func (c *Collection) InsertMany(ctx context.Context, documents []interface{}, opts ...*mongo_options.InsertManyOptions) (*mongo.InsertManyResult, error) {
+	s := c.storage()
+	if s == nil {
+		return nil, ErrNoCollection
+	}
+
+	return s.InsertMany(ctx, documents, opts...)
+}
+
+// UpdateOne applies the raw update like (*mongo.Collection).UpdateOne, without the fields of the model.
+func (c *Collection) UpdateOne(ctx context.Context, filter, update interface{}, opts ...*mongo_options.UpdateOptions) (*mongo.UpdateResult, error) {
+	s := c.storage()
+	if s == nil {
+		return nil, ErrNoCollection
+	}
+
+	return s.UpdateOne(ctx, filter, update, opts...)
+}
+
+// UpdateByID applies the raw update to the document with the given _id like (*mongo.Collection).UpdateByID.
+func (c *Collection) UpdateByID(ctx context.Context, id, update interface{}, opts ...*mongo_options.UpdateOptions) (*mongo.UpdateResult, error) {
+	if id == nil {
+		return nil, mongo.ErrNilValue
+	}
+
+	return c.UpdateOne(ctx, bson.D{{Key: "_id", Value: id}}, update, opts...)
+}
+
+// UpdateMany applies the raw update like (*mongo.Collection).UpdateMany, without the fields of the model.
+func (c *Collection) UpdateMany(ctx context.Context, filter, update interface{}, opts ...*mongo_options.UpdateOptions) (*mongo.UpdateResult, error) {
+	s := c.storage()
+	if s == nil {
+		return nil, ErrNoCollection
+	}
+
+	return s.UpdateMany(ctx, filter, update, opts...)
+}
+
+// FindOneAndUpdate applies the raw update like (*mongo.Collection).FindOneAndUpdate.
+func (c *Collection) FindOneAndUpdate(ctx context.Context, filter, update interface{}, opts ...*mongo_options.FindOneAndUpdateOptions) *mongo.SingleResult {
+	s := c.storage()
+	if s == nil {
+		return noCollection()
+	}
+
+	return s.FindOneAndUpdate(ctx, filter, update, opts...)
+}
+
+// DeleteOne removes the first document matched by the raw filter like (*mongo.Collection).DeleteOne,
+// even when its model is soft deleted.
+func (c *Collection) DeleteOne(ctx context.Context, filter interface{}, opts ...*mongo_options.DeleteOptions) (*mongo.DeleteResult, error) {
+	s := c.storage()
+	if s == nil {
+		return nil, ErrNoCollection
+	}
+
+	return s.DeleteOne(ctx, filter, opts...)
+}
+
+// DeleteMany removes the documents matched by the raw filter like (*mongo.Collection).DeleteMany,
+// even when their model is soft deleted.
+func (c *Collection) DeleteMany(ctx context.Context, filter interface{}, opts ...*mongo_options.DeleteOptions) (*mongo.DeleteResult, error) {
+	s := c.storage()
+	if s == nil {
+		return nil, ErrNoCollection
+	}
+
+	return s.DeleteMany(ctx, filter, opts...)
+}
+
+// BulkWrite runs the write models like (*mongo.Collection).BulkWrite.
+func (c *Collection) BulkWrite(ctx context.Context, models []mongo.WriteModel, opts ...*mongo_options.BulkWriteOptions) (*mongo.BulkWriteResult, error) {
+	s := c.storage()
+	if s == nil {
+		return nil, ErrNoCollection
+	}
+
+	return s.BulkWrite(ctx, models, opts...)
+}
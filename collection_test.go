@@ -0,0 +1,101 @@
+package mongorm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mongo_options "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestCollectionRawMethods(t *testing.T) {
+	ctx := context.Background()
+	c := newTestDB(t).Collection("raw")
+
+	if _, err := c.InsertOne(ctx, bson.D{{Key: "_id", Value: 1}, {Key: "n", Value: 1}}); err != nil {
+		t.Fatalf("InsertOne: %v", err)
+	}
+
+	if _, err := c.InsertMany(ctx, []interface{}{bson.D{{Key: "_id", Value: 2}, {Key: "n", Value: 2}}, bson.D{{Key: "_id", Value: 3}, {Key: "n", Value: 3}}}); err != nil {
+		t.Fatalf("InsertMany: %v", err)
+	}
+
+	if _, err := c.InsertOne(ctx, bson.D{{Key: "_id", Value: 1}}); !mongo.IsDuplicateKeyError(err) {
+		t.Errorf("InsertOne of a duplicate _id: error %v, want a duplicate key error", err)
+	}
+
+	if res, err := c.UpdateMany(ctx, bson.D{{Key: "n", Value: bson.D{{Key: "$gte", Value: 2}}}}, bson.D{{Key: "$inc", Value: bson.D{{Key: "n", Value: 10}}}}); err != nil || res.ModifiedCount != 2 {
+		t.Errorf("UpdateMany = %+v, %v, want 2 modified", res, err)
+	}
+
+	if res, err := c.UpdateByID(ctx, 1, bson.D{{Key: "$set", Value: bson.D{{Key: "n", Value: 5}}}}); err != nil || res.ModifiedCount != 1 {
+		t.Errorf("UpdateByID = %+v, %v, want 1 modified", res, err)
+	}
+
+	var doc bson.M
+	if err := c.FindOne(ctx, bson.D{{Key: "_id", Value: 1}}).Decode(&doc); err != nil || doc["n"] != int32(5) {
+		t.Errorf("FindOne = %v, %v, want n 5", doc, err)
+	}
+
+	opts := mongo_options.FindOneAndUpdate().SetReturnDocument(mongo_options.After)
+	if err := c.FindOneAndUpdate(ctx, bson.D{{Key: "_id", Value: 2}}, bson.D{{Key: "$inc", Value: bson.D{{Key: "n", Value: 2}}}}, opts).Decode(&doc); err != nil || doc["n"] != int32(14) {
+		t.Errorf("FindOneAndUpdate = %v, %v, want n 14", doc, err)
+	}
+
+	cursor, err := c.Find(ctx, bson.D{}, mongo_options.Find().SetSort(bson.D{{Key: "n", Value: -1}}))
+	if err != nil {
+		t.Fatalf("Find: %v", err)
+	}
+
+	var docs []bson.M
+	if err := cursor.All(ctx, &docs); err != nil || len(docs) != 3 || docs[0]["_id"] != int32(2) {
+		t.Errorf("Find = %v, %v, want 3 documents starting with _id 2", docs, err)
+	}
+
+	if n, err := c.CountDocuments(ctx, bson.D{{Key: "n", Value: bson.D{{Key: "$gt", Value: 10}}}}); err != nil || n != 2 {
+		t.Errorf("CountDocuments = %d, %v, want 2", n, err)
+	}
+
+	if values, err := c.Distinct(ctx, "n", bson.D{}); err != nil || len(values) != 3 {
+		t.Errorf("Distinct = %v, %v, want 3 values", values, err)
+	}
+
+	models := []mongo.WriteModel{
+		mongo.NewInsertOneModel().SetDocument(bson.D{{Key: "_id", Value: 4}}),
+		mongo.NewDeleteOneModel().SetFilter(bson.D{{Key: "_id", Value: 1}}),
+	}
+	if res, err := c.BulkWrite(ctx, models); err != nil || res.InsertedCount != 1 || res.DeletedCount != 1 {
+		t.Errorf("BulkWrite = %+v, %v, want 1 inserted and 1 deleted", res, err)
+	}
+
+	if res, err := c.DeleteOne(ctx, bson.D{{Key: "_id", Value: 4}}); err != nil || res.DeletedCount != 1 {
+		t.Errorf("DeleteOne = %+v, %v, want 1 deleted", res, err)
+	}
+
+	if res, err := c.DeleteMany(ctx, bson.D{}); err != nil || res.DeletedCount != 2 {
+		t.Errorf("DeleteMany = %+v, %v, want 2 deleted", res, err)
+	}
+
+	if err := c.FindOne(ctx, bson.D{}).Decode(&doc); !errors.Is(err, mongo.ErrNoDocuments) {
+		t.Errorf("FindOne of an empty collection: error %v, want mongo.ErrNoDocuments", err)
+	}
+}
+
+func TestCollectionWithoutStore(t *testing.T) {
+	ctx := context.Background()
+	c := &Collection{}
+
+	if _, err := c.InsertOne(ctx, bson.D{}); !errors.Is(err, ErrNoCollection) {
+		t.Errorf("InsertOne: error %v, want ErrNoCollection", err)
+	}
+
+	if err := c.FindOne(ctx, bson.D{}).Decode(&bson.M{}); !errors.Is(err, ErrNoCollection) {
+		t.Errorf("FindOne: error %v, want ErrNoCollection", err)
+	}
+
+	if _, err := c.CreateIndexes(ctx); !errors.Is(err, ErrNoCollection) {
+		t.Errorf("CreateIndexes: error %v, want ErrNoCollection", err)
+	}
+}
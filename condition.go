@@ -0,0 +1,261 @@
+package mongorm
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/v1shn3vsk7/mongorm/internal/operators"
+)
+
+// bsonTypeAliases are the string aliases accepted by $type.
+var bsonTypeAliases = map[string]struct{}{
+	"double": {}, "string": {}, "object": {}, "array": {}, "binData": {}, "undefined": {},
+	"objectId": {}, "bool": {}, "date": {}, "null": {}, "regex": {}, "dbPointer": {},
+	"javascript": {}, "symbol": {}, "javascriptWithScope": {}, "int": {}, "timestamp": {},
+	"long": {}, "decimal": {}, "minKey": {}, "maxKey": {}, "number": {},
+}
+
+// checkValue reports whether value can be used as the operand of cond.
+func checkValue(cond CondOperator, value interface{}) error {
+	if _, ok := condOperators[cond]; !ok {
+		return fmt.Errorf("%w: %d", ErrUnknownOperator, cond)
+	}
+
+	switch cond {
+	case IN, NIN, ALL:
+		if !isList(value) {
+			return invalidValue(cond, "a slice", value)
+		}
+	case EXISTS:
+		if _, ok := value.(bool); !ok {
+			return invalidValue(cond, "a bool", value)
+		}
+	case TYPE:
+		if isList(value) {
+			rv := reflect.ValueOf(value)
+			for i := 0; i < rv.Len(); i++ {
+				if !isBSONType(rv.Index(i).Interface()) {
+					return invalidValue(cond, "a BSON type alias or number", rv.Index(i).Interface())
+				}
+			}
+
+			return nil
+		}
+
+		if !isBSONType(value) {
+			return invalidValue(cond, "a BSON type alias or number", value)
+		}
+	case MOD:
+		rv := reflect.ValueOf(value)
+		if !isList(value) || rv.Len() != 2 || !isNumber(rv.Index(0).Interface()) || !isNumber(rv.Index(1).Interface()) {
+			return invalidValue(cond, "a [divisor, remainder] pair of numbers", value)
+		}
+
+		if reflect.ValueOf(rv.Index(0).Interface()).IsZero() {
+			return fmt.Errorf("%w: %s divisor must not be zero", ErrInvalidValue, cond)
+		}
+	case SIZE:
+		if !isNonNegativeInteger(value) {
+			return invalidValue(cond, "a non-negative integer", value)
+		}
+	case ELEMMATCH:
+		sub, ok := value.(*Query)
+		if !ok || sub == nil {
+			return invalidValue(cond, "a *Query", value)
+		}
+
+		if err := sub.Err(); err != nil {
+			return err
+		}
+	case REGEX:
+		switch value.(type) {
+		case string, primitive.Regex, *regexp.Regexp:
+		default:
+			return invalidValue(cond, "a string, primitive.Regex or *regexp.Regexp", value)
+		}
+	case NOT:
+		switch v := value.(type) {
+		case primitive.Regex, *regexp.Regexp:
+		case *Query:
+			if v == nil {
+				return invalidValue(cond, "a *Query or a regex", value)
+			}
+
+			if err := v.Err(); err != nil {
+				return err
+			}
+
+			// $not takes operator expressions, the conditions of a query with an empty key
+			ops := v.Bson()
+			if len(ops) == 0 {
+				return fmt.Errorf("%w: %s requires at least one condition", ErrInvalidValue, cond)
+			}
+
+			for _, op := range ops {
+				if !strings.HasPrefix(op.Key, "$") || op.Key == operators.AND || op.Key == operators.OR || op.Key == operators.NOR || op.Key == operators.EXPR {
+					return fmt.Errorf("%w: %s requires conditions with an empty key, got %q", ErrInvalidValue, cond, op.Key)
+				}
+			}
+		default:
+			return invalidValue(cond, "a *Query or a regex", value)
+		}
+	case BITSALLSET, BITSANYSET, BITSALLCLEAR, BITSANYCLEAR:
+		if !isBitMask(value) {
+			return invalidValue(cond, "a non-negative integer bitmask, a list of bit positions or binary data", value)
+		}
+	case GEOWITHIN, GEOINTERSECTS, NEAR, NEARSPHERE:
+		if !isDocument(value) {
+			return invalidValue(cond, "a document", value)
+		}
+	}
+
+	return nil
+}
+
+// operand converts value into the form the server expects for cond.
+func operand(cond CondOperator, value interface{}) interface{} {
+	switch v := value.(type) {
+	case *Query:
+		// nil queries are rejected by checkValue, render them as null
+		if v == nil {
+			return nil
+		}
+
+		return v.Bson()
+	case bsontype.Type:
+		return int32(v)
+	case *regexp.Regexp:
+		switch cond {
+		case REGEX, NOT, IN, NIN, ALL:
+			return primitive.Regex{Pattern: v.String()}
+		}
+	}
+
+	// the type aliases of $type lists and the regexps of $in, $nin and $all lists are converted too
+	switch cond {
+	case TYPE, IN, NIN, ALL:
+		if !isList(value) {
+			return value
+		}
+
+		rv := reflect.ValueOf(value)
+		arr := make(bson.A, 0, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			arr = append(arr, operand(cond, rv.Index(i).Interface()))
+		}
+
+		return arr
+	}
+
+	return value
+}
+
+func invalidValue(cond CondOperator, want string, got interface{}) error {
+	return fmt.Errorf("%w: %s requires %s, got %T", ErrInvalidValue, cond, want, got)
+}
+
+func isList(value interface{}) bool {
+	if value == nil {
+		return false
+	}
+
+	switch value.(type) {
+	case []byte, primitive.Binary:
+		return false
+	}
+
+	// byte arrays such as primitive.ObjectID are single values
+	tp := reflect.TypeOf(value)
+	if tp.Kind() == reflect.Array && tp.Elem().Kind() == reflect.Uint8 {
+		return false
+	}
+
+	return tp.Kind() == reflect.Slice || tp.Kind() == reflect.Array
+}
+
+func isDocument(value interface{}) bool {
+	switch value.(type) {
+	case bson.D, bson.M, bson.Raw:
+		return true
+	case nil:
+		return false
+	}
+
+	tp := reflect.TypeOf(value)
+	if tp.Kind() == reflect.Pointer {
+		tp = tp.Elem()
+	}
+
+	return tp.Kind() == reflect.Struct || (tp.Kind() == reflect.Map && tp.Key().Kind() == reflect.String)
+}
+
+func isNumber(value interface{}) bool {
+	if value == nil {
+		return false
+	}
+
+	switch reflect.TypeOf(value).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+
+	_, ok := value.(primitive.Decimal128)
+
+	return ok
+}
+
+func isNonNegativeInteger(value interface{}) bool {
+	if value == nil {
+		return false
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int() >= 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+
+	return false
+}
+
+func isBSONType(value interface{}) bool {
+	switch v := value.(type) {
+	case string:
+		_, ok := bsonTypeAliases[v]
+		return ok
+	case bsontype.Type:
+		return true
+	}
+
+	return isNonNegativeInteger(value)
+}
+
+func isBitMask(value interface{}) bool {
+	switch value.(type) {
+	case []byte, primitive.Binary:
+		return true
+	}
+
+	if !isList(value) {
+		return isNonNegativeInteger(value)
+	}
+
+	rv := reflect.ValueOf(value)
+	for i := 0; i < rv.Len(); i++ {
+		if !isNonNegativeInteger(rv.Index(i).Interface()) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,121 @@
+package mongorm
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestWhereBson(t *testing.T) {
+	oid, _ := primitive.ObjectIDFromHex("6ad4908ed016a8b570e63e34")
+
+	tests := []struct {
+		name  string
+		query *Query
+		want  string
+	}{
+		{"eq", NewQuery().Where("a", EQ, 1), `{"a":1}`},
+		{"gte", NewQuery().Where("a", GTE, 1), `{"a":{"$gte":1}}`},
+		{"eq object id", NewQuery().Where("_id", EQ, oid), `{"_id":{"$oid":"6ad4908ed016a8b570e63e34"}}`},
+		{"in object ids", NewQuery().Where("_id", IN, []primitive.ObjectID{oid}), `{"_id":{"$in":[{"$oid":"6ad4908ed016a8b570e63e34"}]}}`},
+		{"nin object ids", NewQuery().Where("_id", NIN, []interface{}{oid}), `{"_id":{"$nin":[{"$oid":"6ad4908ed016a8b570e63e34"}]}}`},
+		{"in regexps", NewQuery().Where("a", IN, []interface{}{regexp.MustCompile("^x"), "y"}), `{"a":{"$in":[{"$regularExpression":{"pattern":"^x","options":""}},"y"]}}`},
+		{"all regexps", NewQuery().Where("a", ALL, []*regexp.Regexp{regexp.MustCompile("^x")}), `{"a":{"$all":[{"$regularExpression":{"pattern":"^x","options":""}}]}}`},
+		{"regex", NewQuery().Where("a", REGEX, regexp.MustCompile("^x")), `{"a":{"$regex":{"$regularExpression":{"pattern":"^x","options":""}}}}`},
+		{"type list", NewQuery().Where("a", TYPE, []interface{}{"string", bsontype.Int32}), `{"a":{"$type":["string",16]}}`},
+		{"not regex", NewQuery().Where("a", NOT, regexp.MustCompile("^x")), `{"a":{"$not":{"$regularExpression":{"pattern":"^x","options":""}}}}`},
+		{"not conditions", NewQuery().Where("a", NOT, NewQuery().Where("", GT, 5)), `{"a":{"$not":{"$gt":5}}}`},
+		{"elem match", NewQuery().Where("a", ELEMMATCH, NewQuery().Where("b", EQ, 1)), `{"a":{"$elemMatch":{"b":1}}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.query.Err(); err != nil {
+				t.Fatalf("Err: %v", err)
+			}
+
+			if got := renderJSON(t, tt.query.Bson()); got != tt.want {
+				t.Errorf("Bson() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWhereInvalidValue(t *testing.T) {
+	oid := primitive.NewObjectID()
+
+	tests := []struct {
+		name  string
+		cond  CondOperator
+		value interface{}
+	}{
+		{"in object id", IN, oid},
+		{"in scalar", IN, 1},
+		{"in bytes", IN, []byte("x")},
+		{"exists number", EXISTS, 1},
+		{"type alias", TYPE, "text"},
+		{"mod zero divisor", MOD, []int{0, 1}},
+		{"size negative", SIZE, -1},
+		{"elem match nil", ELEMMATCH, (*Query)(nil)},
+		{"regex number", REGEX, 1},
+		{"not nil query", NOT, (*Query)(nil)},
+		{"not value", NOT, 5},
+		{"not keyed condition", NOT, NewQuery().Where("a", GT, 5)},
+		{"geo within list", GEOWITHIN, []int{1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := NewQuery().Where("a", tt.cond, tt.value)
+			if err := q.Err(); !errors.Is(err, ErrInvalidValue) {
+				t.Errorf("Where(%s, %v): error %v, want ErrInvalidValue", tt.cond, tt.value, err)
+			}
+
+			// rendering an invalid query must not panic
+			_ = q.Bson()
+		})
+	}
+}
+
+func TestWhereObjectIDs(t *testing.T) {
+	ctx := context.Background()
+	c := CollectionOf[bson.M](newTestDB(t), "docs")
+
+	oids := []primitive.ObjectID{primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()}
+	for i, oid := range oids {
+		if _, err := c.InsertOne(ctx, bson.M{"_id": oid, "n": i, "name": []string{"xa", "yb", "xc"}[i]}); err != nil {
+			t.Fatalf("InsertOne: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		query *Query
+		want  int
+	}{
+		{"in", c.Query().Where("_id", IN, []primitive.ObjectID{oids[0], oids[2]}), 2},
+		{"nin", c.Query().Where("_id", NIN, []primitive.ObjectID{oids[0]}), 2},
+		{"eq", c.Query().Where("_id", EQ, oids[1]), 1},
+		{"in regexps", c.Query().Where("name", IN, []interface{}{regexp.MustCompile("^x")}), 2},
+		{"not regex", c.Query().Where("name", NOT, regexp.MustCompile("^x")), 1},
+		{"not conditions", c.Query().Where("n", NOT, NewQuery().Where("", GTE, 1)), 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := tt.query.Count(ctx)
+			if err != nil {
+				t.Fatalf("Count: %v", err)
+			}
+
+			if n != int64(tt.want) {
+				t.Errorf("Count(%s) = %d, want %d", renderJSON(t, tt.query.Bson()), n, tt.want)
+			}
+		})
+	}
+}
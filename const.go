@@ -1,5 +1,11 @@
 package mongorm
 
+import (
+	"strconv"
+
+	"github.com/v1shn3vsk7/mongorm/internal/operators"
+)
+
 type CondOperator uint8
 
 const (
@@ -11,8 +17,68 @@ const (
 	LT
 	LTE
 	IN
+	NIN
+	EXISTS
+	TYPE
+	MOD
+	REGEX
+	ALL
+	SIZE
+	ELEMMATCH
+	BITSALLSET
+	BITSANYSET
+	BITSALLCLEAR
+	BITSANYCLEAR
+	GEOWITHIN
+	GEOINTERSECTS
+	NEAR
+	NEARSPHERE
+	NOT
 )
 
+var condOperators = map[CondOperator]string{
+	EQ:            operators.EQ,
+	NE:            operators.NE,
+	GT:            operators.GT,
+	GTE:           operators.GTE,
+	LT:            operators.LT,
+	LTE:           operators.LTE,
+	IN:            operators.IN,
+	NIN:           operators.NIN,
+	EXISTS:        operators.EXISTS,
+	TYPE:          operators.TYPE,
+	MOD:           operators.MOD,
+	REGEX:         operators.REGEX,
+	ALL:           operators.ALL,
+	SIZE:          operators.SIZE,
+	ELEMMATCH:     operators.ELEM_MATCH,
+	BITSALLSET:    operators.BITS_ALL_SET,
+	BITSANYSET:    operators.BITS_ANY_SET,
+	BITSALLCLEAR:  operators.BITS_ALL_CLEAR,
+	BITSANYCLEAR:  operators.BITS_ANY_CLEAR,
+	GEOWITHIN:     operators.GEO_WITHIN,
+	GEOINTERSECTS: operators.GEO_INTERSECTS,
+	NEAR:          operators.NEAR,
+	NEARSPHERE:    operators.NEAR_SPHERE,
+	NOT:           operators.NOT,
+}
+
 func (o CondOperator) Uint8() uint8 {
 	return uint8(o)
 }
+
+// String returns the MongoDB name of the operator, e.g. "$gte".
+func (o CondOperator) String() string {
+	if op, ok := condOperators[o]; ok {
+		return op
+	}
+
+	return "CondOperator(" + strconv.Itoa(int(o)) + ")"
+}
+
+type SortDirection int8
+
+const (
+	ASC  SortDirection = 1
+	DESC SortDirection = -1
+)
@@ -1,9 +1,50 @@
 package mongorm
 
 import (
+	"context"
+	"fmt"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/v1shn3vsk7/mongorm/internal/memstore"
 )
 
+// Database is a database of a server or of an in-memory client, in which case the embedded *mongo.Database is nil.
 type Database struct {
 	*mongo.Database
+
+	client *Client
+	memory *memstore.Database
+}
+
+// Name returns the name of the database.
+func (db *Database) Name() string {
+	if db.memory != nil {
+		return db.memory.Name()
+	}
+
+	return db.Database.Name()
+}
+
+// CollectionNames returns the names of the collections of the database in alphabetical order.
+func (db *Database) CollectionNames(ctx context.Context) ([]string, error) {
+	var names []string
+
+	if db.memory != nil {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		names = db.memory.CollectionNames()
+	} else {
+		var err error
+		if names, err = db.ListCollectionNames(ctx, bson.D{}); err != nil {
+			return nil, fmt.Errorf("mongorm: list collections: %w", err)
+		}
+	}
+
+	sort.Strings(names)
+
+	return names, nil
 }
@@ -0,0 +1,50 @@
+package mongorm
+
+import (
+	"errors"
+
+	"github.com/v1shn3vsk7/mongorm/internal/matcher"
+)
+
+var (
+	// ErrUnknownOperator is returned when a condition uses an operator that is not a CondOperator constant.
+	ErrUnknownOperator = errors.New("mongorm: unknown operator")
+
+	// ErrInvalidValue is returned when the value of a condition does not fit its operator.
+	ErrInvalidValue = errors.New("mongorm: invalid value")
+
+	// ErrNotFound is returned when a query that expects a document matches none.
+	ErrNotFound = errors.New("mongorm: document not found")
+
+	// ErrNoCollection is returned when a query that is not bound to a collection is executed.
+	ErrNoCollection = errors.New("mongorm: query is not bound to a collection")
+
+	// ErrConflictingUpdate is returned when two operators of an update modify the same path or a path and its parent.
+	ErrConflictingUpdate = errors.New("mongorm: conflicting update paths")
+
+	// ErrEmptyUpdate is returned when an update without operators is executed.
+	ErrEmptyUpdate = errors.New("mongorm: empty update")
+
+	// ErrInvalidPipeline is returned when the stages of a pipeline are in an order the server rejects.
+	ErrInvalidPipeline = errors.New("mongorm: invalid pipeline")
+
+	// ErrUnsupported is returned by Query.Matches and in-memory clients for operations that need a server,
+	// e.g. $text or geo queries.
+	ErrUnsupported = matcher.ErrUnsupported
+
+	// ErrInvalidCursor is returned when a pagination cursor is malformed, tampered with or issued for another query.
+	ErrInvalidCursor = errors.New("mongorm: invalid cursor")
+
+	// ErrInvalidModel is returned by Client.Register for types that are not structs or have invalid mongorm tags.
+	ErrInvalidModel = errors.New("mongorm: invalid model")
+
+	// ErrUnknownModel is returned when a type that was not registered with Client.Register is used as a model.
+	ErrUnknownModel = errors.New("mongorm: unknown model")
+
+	// ErrInvalidIndex is returned when an IndexSpec cannot be created, e.g. an index without keys.
+	ErrInvalidIndex = errors.New("mongorm: invalid index")
+
+	// ErrVersionConflict is returned when a write expecting a version of a document, see Query.Version,
+	// matches nothing because the document was changed or deleted since that version was loaded.
+	ErrVersionConflict = errors.New("mongorm: version conflict")
+)
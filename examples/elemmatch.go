@@ -0,0 +1,35 @@
+package examples
+
+import (
+	"context"
+
+	"github.com/v1shn3vsk7/mongorm"
+)
+
+func ElemMatch() {
+	ctx := context.Background()
+	client, _ := mongorm.New(ctx, nil)
+
+	ordersCL := client.Database("<database>").Collection("<collection>")
+
+	// orders with a line of at least 2 items of "<sku>"
+	lines := mongorm.NewQuery().
+		Where("sku", mongorm.EQ, "<sku>").
+		Where("qty", mongorm.GTE, 2)
+
+	// orders with a score in [80, 85)
+	scores := mongorm.NewQuery().
+		Where("", mongorm.GTE, 80).
+		Where("", mongorm.LT, 85)
+
+	query := ordersCL.
+		Query().
+		Where("items", mongorm.ELEMMATCH, lines).
+		Where("scores", mongorm.ELEMMATCH, scores)
+
+	cursor, err := ordersCL.Find(ctx, query.Bson())
+	if err != nil {
+		// handle error
+	}
+	defer cursor.Close(ctx)
+}
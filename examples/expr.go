@@ -0,0 +1,39 @@
+package examples
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/v1shn3vsk7/mongorm"
+	"github.com/v1shn3vsk7/mongorm/expr"
+)
+
+func Expr() {
+	ctx := context.Background()
+	client, _ := mongorm.New(ctx, nil)
+
+	projectsCL := client.Database("<database>").Collection("<collection>")
+
+	// projects that spent more than 110% of their budget
+	overBudget := projectsCL.
+		Query().
+		WhereExpr(expr.Gt(expr.Field("spent"), expr.Multiply(expr.Field("budget"), 1.1)))
+
+	var report []bson.M
+
+	err := projectsCL.
+		Pipeline().
+		Match(overBudget).
+		AddFields(bson.D{
+			{Key: "day", Value: expr.DateToString(expr.Field("created_at"), "%Y-%m-%d", "UTC")},
+			{Key: "severity", Value: expr.Switch([]expr.Case{
+				{If: expr.Gte(expr.Field("spent"), expr.Multiply(expr.Field("budget"), 2)), Then: "critical"},
+			}, "warning")},
+		}).
+		Group("$day", mongorm.Count("projects"), mongorm.Push("severities", expr.Field("severity"))).
+		Aggregate(ctx, &report)
+	if err != nil {
+		// handle error
+	}
+}
@@ -0,0 +1,31 @@
+package examples
+
+import (
+	"context"
+
+	"github.com/v1shn3vsk7/mongorm"
+)
+
+func Group() {
+	ctx := context.Background()
+	client, _ := mongorm.New(ctx, nil)
+
+	usersCL := client.Database("<database>").Collection("<collection>")
+
+	// (role = "admin" AND active = true) OR (role = "owner" AND age >= 18)
+	query := usersCL.
+		Query().
+		Group(func(q *mongorm.Query) *mongorm.Query {
+			return q.Where("role", mongorm.EQ, "admin").And().Where("active", mongorm.EQ, true)
+		}).
+		Or().
+		Group(func(q *mongorm.Query) *mongorm.Query {
+			return q.Where("role", mongorm.EQ, "owner").And().Where("age", mongorm.GTE, 18)
+		})
+
+	cursor, err := usersCL.Find(ctx, query.Bson())
+	if err != nil {
+		// handle error
+	}
+	defer cursor.Close(ctx)
+}
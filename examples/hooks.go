@@ -0,0 +1,59 @@
+package examples
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/v1shn3vsk7/mongorm"
+)
+
+type Member struct {
+	ID       primitive.ObjectID `bson:"_id,omitempty" mongorm:"pk"`
+	Email    string             `bson:"email" mongorm:"unique"`
+	EditedAt time.Time          `bson:"edited_at"`
+	Display  string             `bson:"-"`
+}
+
+// BeforeInsert normalizes the email of new members.
+func (m *Member) BeforeInsert(ctx context.Context) error {
+	if m.Email == "" {
+		return errors.New("email is required")
+	}
+	m.Email = strings.ToLower(m.Email)
+
+	return nil
+}
+
+// BeforeUpdate records when members are edited.
+func (m *Member) BeforeUpdate(ctx context.Context, u *mongorm.Update) (*mongorm.Update, error) {
+	return u.CurrentDate("edited_at"), nil
+}
+
+// AfterFind fills fields that are not stored.
+func (m *Member) AfterFind(ctx context.Context) error {
+	m.Display = strings.SplitN(m.Email, "@", 2)[0]
+
+	return nil
+}
+
+func Hooks() {
+	ctx := context.Background()
+	client, _ := mongorm.New(ctx, nil)
+
+	members, _ := mongorm.CollectionFor[Member](client.Database("<database>"))
+
+	// BeforeInsert lowercases the email, an error aborts the insert
+	if _, err := members.InsertOne(ctx, Member{Email: "<EMAIL>"}); err != nil {
+		// handle error
+	}
+
+	// AfterFind fills Display of every decoded member
+	var found []Member
+	if err := members.Query().All(ctx, &found); err != nil {
+		// handle error
+	}
+}
@@ -0,0 +1,55 @@
+package examples
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/v1shn3vsk7/mongorm"
+	"github.com/v1shn3vsk7/mongorm/options"
+)
+
+type Session struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	Token     string             `bson:"token" mongorm:"unique"`
+	UserID    string             `bson:"user_id" mongorm:"index:user_seen"`
+	LastSeen  time.Time          `bson:"last_seen" mongorm:"index:user_seen,desc"`
+	ExpiresAt time.Time          `bson:"expires_at" mongorm:"ttl:0s"`
+}
+
+// Indexes declares the indexes that struct tags cannot express.
+func (Session) Indexes() []mongorm.IndexSpec {
+	return []mongorm.IndexSpec{{
+		Name:    "active_user",
+		Keys:    bson.D{{Key: "user_id", Value: 1}},
+		Partial: mongorm.NewQuery().Where("revoked", mongorm.EQ, false),
+	}}
+}
+
+func Indexes() {
+	ctx := context.Background()
+	client, _ := mongorm.New(ctx, nil)
+
+	if err := client.Register(&Session{}); err != nil {
+		// handle error
+	}
+
+	db := client.Database("<database>")
+
+	// print the plan without touching the server
+	changes, err := db.SyncIndexes(ctx, options.SyncIndexes().SetDryRun(true))
+	if err != nil {
+		// handle error
+	}
+	for _, change := range changes {
+		fmt.Println(change)
+	}
+
+	// create the missing indexes and drop the undeclared ones
+	if _, err := db.SyncIndexes(ctx, options.SyncIndexes().SetDropUndeclared(true)); err != nil {
+		// handle error
+	}
+}
@@ -0,0 +1,32 @@
+package examples
+
+import (
+	"context"
+
+	"github.com/v1shn3vsk7/mongorm"
+)
+
+func List() {
+	ctx := context.Background()
+	client, _ := mongorm.New(ctx, nil)
+
+	usersCL := client.Database("<database>").Collection("<collection>")
+
+	var users []struct {
+		UserID   string `bson:"user_id"`
+		Username string `bson:"user_name"`
+	}
+
+	err := usersCL.
+		Query().
+		Where("active", mongorm.EQ, true).
+		Select("user_id", "user_name").
+		Exclude("_id").
+		Sort("created_at", mongorm.DESC).
+		Skip(20).
+		Limit(10).
+		All(ctx, &users)
+	if err != nil {
+		// handle error
+	}
+}
@@ -0,0 +1,23 @@
+package examples
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/v1shn3vsk7/mongorm"
+)
+
+func Matches() {
+	query := mongorm.NewQuery().
+		Where("status", mongorm.IN, []string{"new", "paid"}).
+		Where("items.qty", mongorm.GTE, 2)
+
+	// evaluated locally, without a server
+	ok, err := query.Matches(bson.M{
+		"status": "paid",
+		"items":  bson.A{bson.M{"sku": "<sku>", "qty": 3}},
+	})
+	if err != nil {
+		// handle error, e.g. mongorm.ErrUnsupported for geo operators
+	}
+	_ = ok
+}
@@ -0,0 +1,38 @@
+package examples
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mongo_options "go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/v1shn3vsk7/mongorm"
+)
+
+// Memory runs the same repository code as Typed against an in-memory client, e.g. in tests.
+func Memory() {
+	ctx := context.Background()
+	client, _ := mongorm.NewMemoryClient()
+
+	users := mongorm.CollectionOf[User](client.Database("<database>"), "<collection>")
+
+	_, err := users.CreateIndexes(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "user_id", Value: 1}},
+		Options: mongo_options.Index().SetUnique(true),
+	})
+	if err != nil {
+		// handle error
+	}
+
+	if _, err := users.InsertOne(ctx, User{UserID: "<id>", Username: "<name>", Active: true}); err != nil {
+		// mongo.IsDuplicateKeyError(err) reports unique index violations like on a server
+	}
+
+	_, err = users.Query().
+		Where("user_id", mongorm.EQ, "<id>").
+		UpdateOne(ctx, mongorm.NewUpdate().Set("active", false))
+	if err != nil {
+		// handle error
+	}
+}
@@ -0,0 +1,58 @@
+package examples
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/v1shn3vsk7/mongorm"
+	"github.com/v1shn3vsk7/mongorm/migrate"
+	"github.com/v1shn3vsk7/mongorm/options"
+)
+
+func init() {
+	migrate.Register(migrate.Migration{
+		Version:     20240131120000,
+		Description: "index users by email",
+		Up: func(ctx context.Context, db *mongorm.Database) error {
+			_, err := db.Collection("users").CreateIndexes(ctx, mongo.IndexModel{Keys: bson.D{{Key: "email", Value: 1}}})
+			return err
+		},
+		Down: func(ctx context.Context, db *mongorm.Database) error {
+			_, err := db.Collection("users").Indexes().DropOne(ctx, "email_1")
+			return err
+		},
+	})
+}
+
+func Migrate() {
+	ctx := context.Background()
+	client, _ := mongorm.New(ctx, nil)
+
+	m, err := migrate.New(client.Database("<database>"), migrate.Registered(), options.Migrate().SetOwner("<instance>"))
+	if err != nil {
+		// handle error
+	}
+
+	// apply every pending migration; fails with migrate.ErrLocked while another instance migrates
+	applied, err := m.Migrate(ctx, migrate.Latest)
+	if err != nil {
+		// handle error
+	}
+	fmt.Println("applied", applied)
+
+	// revert everything after the first migration
+	if _, err := m.Rollback(ctx, 20240131120000); err != nil {
+		// handle error
+	}
+
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		// handle error
+	}
+	for _, s := range statuses {
+		fmt.Println(s.Version, s.Description, s.Applied)
+	}
+}
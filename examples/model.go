@@ -0,0 +1,35 @@
+package examples
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/v1shn3vsk7/mongorm"
+)
+
+type Account struct {
+	ID    primitive.ObjectID `bson:"_id,omitempty" mongorm:"pk"`
+	Email string             `bson:"email" mongorm:"unique"`
+	First string             `bson:"first" mongorm:"index:name"`
+	Last  string             `bson:"last" mongorm:"index:name"`
+}
+
+func Model() {
+	ctx := context.Background()
+	client, _ := mongorm.New(ctx, nil)
+
+	if err := client.Register(&Account{}); err != nil {
+		// handle error
+	}
+
+	// stored in the "accounts" collection
+	accounts, err := mongorm.CollectionFor[Account](client.Database("<database>"))
+	if err != nil {
+		// handle error
+	}
+
+	if _, err := accounts.SyncIndexes(ctx, accounts.Model().Indexes); err != nil {
+		// handle error
+	}
+}
@@ -0,0 +1,32 @@
+package examples
+
+import (
+	"context"
+
+	"github.com/v1shn3vsk7/mongorm"
+)
+
+func Operators() {
+	ctx := context.Background()
+	client, _ := mongorm.New(ctx, nil)
+
+	usersCL := client.Database("<database>").Collection("<collection>")
+
+	query := usersCL.
+		Query().
+		Where("status", mongorm.IN, []string{"active", "invited"}).
+		Where("deleted_at", mongorm.EXISTS, false).
+		Where("tags", mongorm.SIZE, 3).
+		Where("email", mongorm.REGEX, "@example\\.com$")
+
+	// values that do not fit their operator are reported here
+	if err := query.Err(); err != nil {
+		// handle error
+	}
+
+	cursor, err := usersCL.Find(ctx, query.Bson())
+	if err != nil {
+		// handle error
+	}
+	defer cursor.Close(ctx)
+}
@@ -0,0 +1,38 @@
+package examples
+
+import (
+	"context"
+
+	"github.com/v1shn3vsk7/mongorm"
+	"github.com/v1shn3vsk7/mongorm/options"
+)
+
+func Paginate(after string) {
+	ctx := context.Background()
+
+	opts := options.Client()
+	opts.ApplyURI("<dsn>")
+	// share the key between instances so that cursors issued by one are accepted by the others
+	opts.SetCursorSecret([]byte("<secret>"))
+
+	client, _ := mongorm.New(ctx, opts)
+
+	usersCL := client.Database("<database>").Collection("<collection>")
+
+	var users []struct {
+		UserID   string `bson:"user_id"`
+		Username string `bson:"user_name"`
+	}
+
+	page, err := usersCL.
+		Query().
+		Where("active", mongorm.EQ, true).
+		Sort("created_at", mongorm.DESC).
+		Paginate(ctx, mongorm.PageRequest{After: after, Size: 50}, &users)
+	if err != nil {
+		// handle error
+	}
+
+	// return page.Next and page.Prev to the caller
+	_ = page.Next
+}
@@ -0,0 +1,31 @@
+package examples
+
+import (
+	"context"
+
+	"github.com/v1shn3vsk7/mongorm"
+)
+
+func Pipeline() {
+	ctx := context.Background()
+	client, _ := mongorm.New(ctx, nil)
+
+	ordersCL := client.Database("<database>").Collection("<collection>")
+
+	var totals []struct {
+		CustomerID string  `bson:"_id"`
+		Total      float64 `bson:"total"`
+		Orders     int     `bson:"orders"`
+	}
+
+	err := ordersCL.
+		Pipeline().
+		Match(mongorm.NewQuery().Where("status", mongorm.EQ, "paid")).
+		Group("$customer_id", mongorm.Sum("total", "$amount"), mongorm.Count("orders")).
+		Sort("total", mongorm.DESC).
+		Limit(10).
+		Aggregate(ctx, &totals)
+	if err != nil {
+		// handle error
+	}
+}
@@ -0,0 +1,56 @@
+package examples
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/v1shn3vsk7/mongorm"
+)
+
+type Author struct {
+	ID       primitive.ObjectID `bson:"_id,omitempty" mongorm:"pk"`
+	Name     string             `bson:"name"`
+	Articles []BlogPost         `bson:"-" mongorm:"hasMany:author_id"`
+}
+
+type BlogPost struct {
+	ID       primitive.ObjectID   `bson:"_id,omitempty" mongorm:"pk"`
+	Title    string               `bson:"title"`
+	AuthorID primitive.ObjectID   `bson:"author_id"`
+	LabelIDs []primitive.ObjectID `bson:"label_ids"`
+	Author   *Author              `bson:"-" mongorm:"belongsTo:AuthorID"`
+	Labels   []*Label             `bson:"-" mongorm:"manyToMany:LabelIDs"`
+}
+
+type Label struct {
+	ID   primitive.ObjectID `bson:"_id,omitempty" mongorm:"pk"`
+	Name string             `bson:"name"`
+}
+
+func Relations() {
+	ctx := context.Background()
+	client, _ := mongorm.New(ctx, nil)
+
+	if err := client.Register(&Author{}, &BlogPost{}, &Label{}); err != nil {
+		// handle error
+	}
+
+	db := client.Database("<database>")
+	posts, _ := mongorm.CollectionFor[BlogPost](db)
+
+	// one query for the posts, then one $in query for their authors and one for their labels
+	list, err := posts.All(ctx, posts.Query().Preload("Author", "Labels"))
+	if err != nil {
+		// handle error
+	}
+	_ = list
+
+	// a single aggregation joining the articles of the authors with $lookup
+	authors, _ := mongorm.CollectionFor[Author](db)
+
+	var found []Author
+	if err := authors.Query().PreloadLookup("Articles").All(ctx, &found); err != nil {
+		// handle error
+	}
+}
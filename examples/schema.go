@@ -0,0 +1,35 @@
+package examples
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/v1shn3vsk7/mongorm"
+)
+
+type Article struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	Title     string             `bson:"title" mongorm:"required,min:1,max:200"`
+	Slug      string             `bson:"slug" mongorm:"required,unique,pattern:^[a-z0-9-]+$"`
+	Status    string             `bson:"status" mongorm:"required,enum:draft|published|archived"`
+	Rating    int                `bson:"rating" mongorm:"min:0,max:5"`
+	Tags      []string           `bson:"tags" mongorm:"max:10"`
+	Published *time.Time         `bson:"published"`
+}
+
+func Schema() {
+	ctx := context.Background()
+	client, _ := mongorm.New(ctx, nil)
+
+	if err := client.Register(&Article{}); err != nil {
+		// handle error
+	}
+
+	// creates "articles" with the $jsonSchema validator of Article, or updates the validator with collMod
+	err := client.Database("<database>").EnsureCollection(ctx, &Article{}, mongorm.ValidationStrict, mongorm.ValidationError)
+	if err != nil {
+		// handle error
+	}
+}
@@ -0,0 +1,29 @@
+package examples
+
+import (
+	"context"
+
+	"github.com/v1shn3vsk7/mongorm"
+)
+
+func Scopes() {
+	ctx := context.Background()
+	client, _ := mongorm.New(ctx, nil)
+
+	usersCL := client.Database("<database>").Collection("<collection>")
+
+	// base scope, defined once and shared between requests
+	active := usersCL.Query().Where("active", mongorm.EQ, true)
+
+	// every builder call returns a new query, active stays unchanged
+	admins := active.Where("role", mongorm.EQ, "admin")
+	owners := active.Where("role", mongorm.EQ, "owner")
+
+	for _, query := range []*mongorm.Query{admins, owners} {
+		cursor, err := usersCL.Find(ctx, query.Bson())
+		if err != nil {
+			// handle error
+		}
+		cursor.Close(ctx)
+	}
+}
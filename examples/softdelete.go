@@ -0,0 +1,48 @@
+package examples
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/v1shn3vsk7/mongorm"
+)
+
+type Invoice struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" mongorm:"pk"`
+	Number    string             `bson:"number" mongorm:"unique"`
+	DeletedAt *time.Time         `bson:"deleted_at,omitempty" mongorm:"deletedAt"`
+}
+
+func SoftDelete() {
+	ctx := context.Background()
+	client, _ := mongorm.New(ctx, nil)
+
+	if err := client.Register(&Invoice{}); err != nil {
+		// handle error
+	}
+
+	invoices, _ := mongorm.CollectionFor[Invoice](client.Database("<database>"))
+	query := invoices.Query().Where("number", mongorm.EQ, "<number>")
+
+	// sets deleted_at instead of removing the invoice
+	if _, err := query.DeleteOne(ctx); err != nil {
+		// handle error
+	}
+
+	// queries skip deleted invoices unless asked for them
+	var deleted []Invoice
+	if err := invoices.Query().OnlyDeleted().All(ctx, &deleted); err != nil {
+		// handle error
+	}
+
+	if _, err := query.Restore(ctx); err != nil {
+		// handle error
+	}
+
+	// removes the invoice for good, deleted or not
+	if _, err := query.WithDeleted().ForceDeleteOne(ctx); err != nil {
+		// handle error
+	}
+}
@@ -0,0 +1,42 @@
+package examples
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/v1shn3vsk7/mongorm"
+	"github.com/v1shn3vsk7/mongorm/options"
+)
+
+type Post struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" mongorm:"pk"`
+	Title     string             `bson:"title"`
+	CreatedAt time.Time          `bson:"created_at" mongorm:"createdAt"`
+	UpdatedAt time.Time          `bson:"updated_at" mongorm:"updatedAt"`
+}
+
+func Timestamps() {
+	ctx := context.Background()
+
+	// a fixed clock makes the timestamps deterministic in tests
+	clock := func() time.Time { return time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC) }
+	client, _ := mongorm.NewMemoryClient(options.Client().SetClock(clock))
+
+	if err := client.Register(&Post{}); err != nil {
+		// handle error
+	}
+
+	posts, _ := mongorm.CollectionFor[Post](client.Database("<database>"))
+
+	// sets created_at and updated_at
+	if _, err := posts.InsertOne(ctx, Post{Title: "<title>"}); err != nil {
+		// handle error
+	}
+
+	// adds $set of updated_at, and $setOnInsert of created_at for upserts
+	if _, err := posts.Query().Where("title", mongorm.EQ, "<title>").UpdateOne(ctx, mongorm.NewUpdate().Set("title", "<new title>")); err != nil {
+		// handle error
+	}
+}
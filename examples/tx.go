@@ -0,0 +1,36 @@
+package examples
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+
+	"github.com/v1shn3vsk7/mongorm"
+	"github.com/v1shn3vsk7/mongorm/options"
+)
+
+func Tx() {
+	ctx := context.Background()
+	client, _ := mongorm.New(ctx, nil)
+	db := client.Database("<database>")
+
+	opts := options.Tx().SetMaxAttempts(5).SetWriteConcern(writeconcern.Majority())
+
+	err := client.Tx(ctx, func(tx mongorm.TxContext) error {
+		// every call made with tx runs in the transaction
+		if _, err := db.Collection("accounts").Query().
+			Where("_id", mongorm.EQ, "<from>").
+			UpdateOne(tx, mongorm.NewUpdate().Inc("balance", -100)); err != nil {
+			return err
+		}
+
+		_, err := db.Collection("accounts").Query().
+			Where("_id", mongorm.EQ, "<to>").
+			UpdateOne(tx, mongorm.NewUpdate().Inc("balance", 100))
+
+		return err
+	}, opts)
+	if err != nil {
+		// handle error
+	}
+}
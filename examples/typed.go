@@ -0,0 +1,45 @@
+package examples
+
+import (
+	"context"
+
+	"github.com/v1shn3vsk7/mongorm"
+)
+
+type User struct {
+	UserID   string `bson:"user_id"`
+	Username string `bson:"user_name"`
+	Active   bool   `bson:"active"`
+}
+
+func Typed() {
+	ctx := context.Background()
+	client, _ := mongorm.New(ctx, nil)
+
+	users := mongorm.CollectionOf[User](client.Database("<database>"), "<collection>")
+
+	if _, err := users.InsertOne(ctx, User{UserID: "<id>", Username: "<name>", Active: true}); err != nil {
+		// handle error
+	}
+
+	user, err := users.One(ctx, users.Query().Where("user_id", mongorm.EQ, "<id>"))
+	if err != nil {
+		// handle error
+	}
+	_ = user.Username
+
+	active, err := users.All(ctx, users.Query().Where("active", mongorm.EQ, true))
+	if err != nil {
+		// handle error
+	}
+	_ = active
+
+	users.Iter(ctx, nil)(func(user User, err error) bool {
+		if err != nil {
+			// handle error
+			return false
+		}
+
+		return true
+	})
+}
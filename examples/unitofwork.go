@@ -0,0 +1,46 @@
+package examples
+
+import (
+	"context"
+
+	"github.com/v1shn3vsk7/mongorm"
+)
+
+func UnitOfWork() {
+	ctx := context.Background()
+	client, _ := mongorm.New(ctx, nil)
+
+	if err := client.Register(&Account{}); err != nil {
+		// handle error
+	}
+
+	db := client.Database("<database>")
+	accounts, _ := mongorm.CollectionFor[Account](db)
+
+	uow := db.UnitOfWork()
+
+	// loaded accounts are tracked: only the fields changed below are written on commit
+	var loaded []Account
+	if err := uow.Load(ctx, accounts.Query().Where("last", mongorm.EQ, "<name>"), &loaded); err != nil {
+		// handle error
+	}
+
+	for i := range loaded {
+		loaded[i].Last = "<new name>"
+	}
+
+	if err := uow.Add(&Account{Email: "<email>"}); err != nil {
+		// handle error
+	}
+
+	if len(loaded) > 0 {
+		if err := uow.Remove(&loaded[0]); err != nil {
+			// handle error
+		}
+	}
+
+	// one bulk write per collection in a single transaction
+	if err := uow.Commit(ctx); err != nil {
+		// handle error
+	}
+}
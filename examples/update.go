@@ -0,0 +1,33 @@
+package examples
+
+import (
+	"context"
+
+	"github.com/v1shn3vsk7/mongorm"
+)
+
+func Update() {
+	ctx := context.Background()
+	client, _ := mongorm.New(ctx, nil)
+
+	ordersCL := client.Database("<database>").Collection("<collection>")
+
+	keep := 10
+
+	update := mongorm.NewUpdate().
+		Set("status", "shipped").
+		Inc("revision", 1).
+		CurrentDate("shipped_at").
+		PushEach("history", []string{"shipped"}, &mongorm.PushModifiers{Slice: &keep}).
+		Set("items.$[line].state", "packed").
+		ArrayFilters(mongorm.NewQuery().Where("line.qty", mongorm.GT, 0))
+
+	res, err := ordersCL.
+		Query().
+		Where("order_id", mongorm.EQ, "<id>").
+		UpdateOne(ctx, update)
+	if err != nil {
+		// handle error, e.g. mongorm.ErrConflictingUpdate
+	}
+	_ = res.ModifiedCount
+}
@@ -0,0 +1,60 @@
+package examples
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/v1shn3vsk7/mongorm"
+)
+
+type Document struct {
+	ID      primitive.ObjectID `bson:"_id,omitempty" mongorm:"pk"`
+	Body    string             `bson:"body"`
+	Version int64              `bson:"version" mongorm:"version"`
+}
+
+func Version() {
+	ctx := context.Background()
+	client, _ := mongorm.New(ctx, nil)
+
+	if err := client.Register(&Document{}); err != nil {
+		// handle error
+	}
+
+	documents, _ := mongorm.CollectionFor[Document](client.Database("<database>"))
+
+	var doc Document
+	if err := documents.Query().Where("_id", mongorm.EQ, "<id>").One(ctx, &doc); err != nil {
+		// handle error
+	}
+
+	// only updates the document if nobody changed it since it was loaded, and increments its version
+	_, err := documents.Query().
+		Where("_id", mongorm.EQ, doc.ID).
+		Version(doc.Version).
+		UpdateOne(ctx, mongorm.NewUpdate().Set("body", "<body>"))
+	if errors.Is(err, mongorm.ErrVersionConflict) {
+		// reload and try again
+	}
+
+	// without Version the update applies whatever the stored version is, and still increments it
+	_, err = documents.Query().
+		Where("_id", mongorm.EQ, doc.ID).
+		UpdateOne(ctx, mongorm.NewUpdate().Set("body", "<body>"))
+	if err != nil {
+		// handle error
+	}
+
+	// units of work check and increment the versions of the instances they track
+	uow := client.Database("<database>").UnitOfWork()
+	if err := uow.Track(&doc); err != nil {
+		// handle error
+	}
+
+	doc.Body = "<new body>"
+	if err := uow.Commit(ctx); errors.Is(err, mongorm.ErrVersionConflict) {
+		// reload and try again
+	}
+}
@@ -2,6 +2,7 @@ package examples
 
 import (
 	"context"
+	"errors"
 
 	"github.com/v1shn3vsk7/mongorm"
 )
@@ -17,13 +18,15 @@ func Where() {
 		Username string `bson:"user_name"`
 	}
 
-	query := usersCL.
+	err := usersCL.
 		Query().
 		Where("<key>", mongorm.EQ, "<value>").
 		And().
-		Where("<key>", mongorm.NE, "<value>")
-
-	err := usersCL.FindOne(ctx, query.Bson(), nil).Decode(&userDTO)
+		Where("<key>", mongorm.NE, "<value>").
+		One(ctx, &userDTO)
+	if errors.Is(err, mongorm.ErrNotFound) {
+		// handle missing user
+	}
 	if err != nil {
 		// handle error
 	}
@@ -0,0 +1,254 @@
+package mongorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// One decodes the first document matched by the query into dst.
+// ErrNotFound is returned when nothing matches.
+func (q *Query) One(ctx context.Context, dst interface{}) error {
+	if q.hasLookups() {
+		return q.lookup(ctx, dst, true)
+	}
+
+	filter, err := q.filter()
+	if err != nil {
+		return err
+	}
+
+	if err := q.collection.storage().FindOne(ctx, filter, q.findOneOptions()).Decode(dst); err != nil {
+		return q.mapErr("find one", err)
+	}
+
+	return q.found(ctx, dst, false)
+}
+
+// All decodes every document matched by the query into dst, which must be a pointer to a slice.
+func (q *Query) All(ctx context.Context, dst interface{}) error {
+	if q.hasLookups() {
+		return q.lookup(ctx, dst, false)
+	}
+
+	cursor, err := q.cursor(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := cursor.All(ctx, dst); err != nil {
+		return q.mapErr("find", err)
+	}
+
+	return q.found(ctx, dst, false)
+}
+
+// cursor opens a cursor over the documents matched by the query. The caller must close it.
+func (q *Query) cursor(ctx context.Context) (*mongo.Cursor, error) {
+	filter, err := q.filter()
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := q.collection.storage().Find(ctx, filter, q.findOptions())
+
+	return cursor, q.mapErr("find", err)
+}
+
+// Count returns the number of documents matched by the query.
+func (q *Query) Count(ctx context.Context) (int64, error) {
+	filter, err := q.filter()
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := q.collection.storage().CountDocuments(ctx, filter, q.countOptions())
+
+	return n, q.mapErr("count", err)
+}
+
+// Exists reports whether the query matches at least one document.
+func (q *Query) Exists(ctx context.Context) (bool, error) {
+	filter, err := q.filter()
+	if err != nil {
+		return false, err
+	}
+
+	n, err := q.collection.storage().CountDocuments(ctx, filter, q.countOptions().SetLimit(1))
+
+	return n > 0, q.mapErr("exists", err)
+}
+
+// DeleteOne deletes the first document matched by the query and returns the number of deleted documents.
+// Documents of models with a deletedAt field are soft deleted, see ForceDeleteOne.
+func (q *Query) DeleteOne(ctx context.Context) (int64, error) {
+	if f, err := q.deletedAt(); err == nil {
+		return q.softDelete(ctx, f, false)
+	}
+
+	return q.ForceDeleteOne(ctx)
+}
+
+// DeleteMany deletes every document matched by the query and returns the number of deleted documents.
+// Documents of models with a deletedAt field are soft deleted, see ForceDeleteMany.
+func (q *Query) DeleteMany(ctx context.Context) (int64, error) {
+	if f, err := q.deletedAt(); err == nil {
+		return q.softDelete(ctx, f, true)
+	}
+
+	return q.ForceDeleteMany(ctx)
+}
+
+// Distinct returns the distinct values of field among the documents matched by the query.
+func (q *Query) Distinct(ctx context.Context, field string) ([]interface{}, error) {
+	filter, err := q.filter()
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := q.collection.storage().Distinct(ctx, field, filter, q.distinctOptions())
+
+	return values, q.mapErr("distinct", err)
+}
+
+// filter is the single entry point of every terminal method: it checks that the
+// query can be executed and renders its filter.
+func (q *Query) filter() (bson.D, error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+
+	if q.collection == nil || q.collection.storage() == nil {
+		return nil, ErrNoCollection
+	}
+
+	return q.versioned(q.scope(q.Bson())), nil
+}
+
+// filterDelete renders the filter of a delete after calling the BeforeDelete hook.
+func (q *Query) filterDelete(ctx context.Context) (bson.D, error) {
+	filter, err := q.filter()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := beforeDelete(ctx, q.collection.hookReceiver(), q); err != nil {
+		return nil, q.mapErr("before delete", err)
+	}
+
+	return filter, nil
+}
+
+func (q *Query) mapErr(op string, err error) error {
+	return q.collection.mapErr(op, err)
+}
+
+// mapErr converts driver errors into mongorm errors and adds the operation and collection to the message.
+func (c *Collection) mapErr(op string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return fmt.Errorf("%w: %s %s", ErrNotFound, op, c.Name())
+	}
+
+	return fmt.Errorf("mongorm: %s %s: %w", op, c.Name(), err)
+}
+
+// UpdateOne applies u to the first document matched by the query. ErrVersionConflict is returned
+// when the query expects a version, see Query.Version, and nothing matches.
+func (q *Query) UpdateOne(ctx context.Context, u *Update) (*mongo.UpdateResult, error) {
+	filter, u, err := q.filterUpdate(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := q.collection.storage().UpdateOne(ctx, filter, u.Bson(), q.updateOptions(u))
+	if err != nil {
+		return nil, q.mapErr("update one", err)
+	}
+
+	return res, q.checkVersion("update one", res.MatchedCount)
+}
+
+// UpdateMany applies u to every document matched by the query. ErrVersionConflict is returned
+// when the query expects a version, see Query.Version, and nothing matches.
+func (q *Query) UpdateMany(ctx context.Context, u *Update) (*mongo.UpdateResult, error) {
+	filter, u, err := q.filterUpdate(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := q.collection.storage().UpdateMany(ctx, filter, u.Bson(), q.updateOptions(u))
+	if err != nil {
+		return nil, q.mapErr("update many", err)
+	}
+
+	return res, q.checkVersion("update many", res.MatchedCount)
+}
+
+// Upsert applies u to the first document matched by the query or inserts a new document
+// built from the equality conditions of the query and u when nothing matches.
+func (q *Query) Upsert(ctx context.Context, u *Update) (*mongo.UpdateResult, error) {
+	filter, u, err := q.filterUpdate(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := q.collection.storage().UpdateOne(ctx, filter, u.Bson(), q.updateOptions(u).SetUpsert(true))
+
+	return res, q.mapErr("upsert", err)
+}
+
+// FindOneAndUpdate applies u to the first document matched by the query and decodes
+// the updated document into dst. ErrNotFound is returned when nothing matches, or
+// ErrVersionConflict when the query expects a version.
+func (q *Query) FindOneAndUpdate(ctx context.Context, u *Update, dst interface{}) error {
+	filter, u, err := q.filterUpdate(ctx, u)
+	if err != nil {
+		return err
+	}
+
+	err = q.collection.storage().FindOneAndUpdate(ctx, filter, u.Bson(), q.findOneAndUpdateOptions(u)).Decode(dst)
+	if errors.Is(err, mongo.ErrNoDocuments) && q.version != nil {
+		return q.checkVersion("find one and update", 0)
+	}
+	if err != nil {
+		return q.mapErr("find one and update", err)
+	}
+
+	return q.found(ctx, dst, false)
+}
+
+// filterUpdate renders the filter of an update and returns the update to apply, the one returned
+// by the BeforeUpdate hook.
+func (q *Query) filterUpdate(ctx context.Context, u *Update) (bson.D, *Update, error) {
+	filter, err := q.filter()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if u != nil && u.err == nil {
+		if u, err = beforeUpdate(ctx, q.collection.hookReceiver(), u); err != nil {
+			return nil, nil, q.mapErr("before update", err)
+		}
+	}
+
+	if u == nil || len(u.ops) == 0 {
+		return nil, nil, ErrEmptyUpdate
+	}
+
+	if u.err != nil {
+		return nil, nil, u.err
+	}
+
+	if u = q.collection.model.fieldUpdates(u, q.collection.now()); u.err != nil {
+		return nil, nil, u.err
+	}
+
+	return filter, u, nil
+}
@@ -0,0 +1,86 @@
+package mongorm
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestQueryTerminals(t *testing.T) {
+	ctx := context.Background()
+	c := testCollection(t,
+		`{"_id": 1, "kind": "a", "n": 1}`,
+		`{"_id": 2, "kind": "b", "n": 2}`,
+		`{"_id": 3, "kind": "a", "n": 3}`,
+	)
+
+	var one bson.M
+	if err := c.Query().Where("n", GT, 2).One(ctx, &one); err != nil {
+		t.Fatalf("One: %v", err)
+	}
+	if one["_id"] != int32(3) {
+		t.Errorf("One = %v, want _id 3", one)
+	}
+
+	var all []bson.M
+	if err := c.Query().Where("kind", EQ, "a").All(ctx, &all); err != nil {
+		t.Fatalf("All: %v", err)
+	}
+	if len(all) != 2 {
+		t.Errorf("All = %v, want 2 documents", all)
+	}
+
+	n, err := c.Query().Where("n", LTE, 2).Count(ctx)
+	if err != nil || n != 2 {
+		t.Errorf("Count = %d, %v, want 2", n, err)
+	}
+
+	ok, err := c.Query().Where("kind", EQ, "c").Exists(ctx)
+	if err != nil || ok {
+		t.Errorf("Exists = %v, %v, want false", ok, err)
+	}
+
+	values, err := c.Query().Distinct(ctx, "kind")
+	if err != nil {
+		t.Fatalf("Distinct: %v", err)
+	}
+	kinds := make([]string, 0, len(values))
+	for _, v := range values {
+		kinds = append(kinds, v.(string))
+	}
+	sort.Strings(kinds)
+	if len(kinds) != 2 || kinds[0] != "a" || kinds[1] != "b" {
+		t.Errorf("Distinct = %v, want [a b]", values)
+	}
+
+	deleted, err := c.Query().Where("kind", EQ, "a").DeleteOne(ctx)
+	if err != nil || deleted != 1 {
+		t.Errorf("DeleteOne = %d, %v, want 1", deleted, err)
+	}
+
+	deleted, err = c.Query().DeleteMany(ctx)
+	if err != nil || deleted != 2 {
+		t.Errorf("DeleteMany = %d, %v, want 2", deleted, err)
+	}
+
+	if err := c.Query().One(ctx, &one); !errors.Is(err, ErrNotFound) {
+		t.Errorf("One on an empty collection: error %v, want ErrNotFound", err)
+	}
+}
+
+func TestQueryTerminalErrors(t *testing.T) {
+	ctx := context.Background()
+	c := testCollection(t)
+
+	var dst bson.M
+	if err := NewQuery().One(ctx, &dst); !errors.Is(err, ErrNoCollection) {
+		t.Errorf("One of an unbound query: error %v, want ErrNoCollection", err)
+	}
+
+	if _, err := c.Query().Where("n", SIZE, -1).Count(ctx); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("Count of an invalid query: error %v, want ErrInvalidValue", err)
+	}
+}
@@ -0,0 +1,78 @@
+package expr
+
+import "github.com/v1shn3vsk7/mongorm/internal/operators"
+
+// Add returns the sum of args, which are numbers or a date and numbers of milliseconds.
+func Add(args ...interface{}) Expr {
+	return variadic(operators.ADD, 2, args)
+}
+
+// Subtract returns a minus b.
+func Subtract(a, b interface{}) Expr {
+	return opArray(operators.SUBTRACT, a, b)
+}
+
+// Multiply returns the product of args.
+func Multiply(args ...interface{}) Expr {
+	return variadic(operators.MULTIPLY, 2, args)
+}
+
+// Divide returns a divided by b.
+func Divide(a, b interface{}) Expr {
+	return opArray(operators.DIVIDE, a, b)
+}
+
+// Mod returns the remainder of a divided by b.
+func Mod(a, b interface{}) Expr {
+	return opArray(operators.MOD, a, b)
+}
+
+// Pow returns base raised to exponent.
+func Pow(base, exponent interface{}) Expr {
+	return opArray(operators.POW, base, exponent)
+}
+
+// Abs returns the absolute value of n.
+func Abs(n interface{}) Expr {
+	return op(operators.ABS, n)
+}
+
+// Ceil returns the smallest integer greater than or equal to n.
+func Ceil(n interface{}) Expr {
+	return op(operators.CEIL, n)
+}
+
+// Floor returns the largest integer less than or equal to n.
+func Floor(n interface{}) Expr {
+	return op(operators.FLOOR, n)
+}
+
+// Round rounds n to an integer or, if given, to place decimal places.
+func Round(n interface{}, place ...interface{}) Expr {
+	return optional(operators.ROUND, []interface{}{n}, 1, place)
+}
+
+// Trunc truncates n to an integer or, if given, to place decimal places.
+func Trunc(n interface{}, place ...interface{}) Expr {
+	return optional(operators.TRUNC, []interface{}{n}, 1, place)
+}
+
+// Sqrt returns the square root of n.
+func Sqrt(n interface{}) Expr {
+	return op(operators.SQRT, n)
+}
+
+// Exp returns e raised to n.
+func Exp(n interface{}) Expr {
+	return op(operators.EXP, n)
+}
+
+// Ln returns the natural logarithm of n.
+func Ln(n interface{}) Expr {
+	return op(operators.LN, n)
+}
+
+// Log10 returns the base 10 logarithm of n.
+func Log10(n interface{}) Expr {
+	return op(operators.LOG10, n)
+}
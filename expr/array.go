@@ -0,0 +1,110 @@
+package expr
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/v1shn3vsk7/mongorm/internal/operators"
+)
+
+// Size returns the number of elements of arr.
+func Size(arr interface{}) Expr {
+	return opArray(operators.SIZE, arr)
+}
+
+// ArrayElemAt returns the element of arr at index; negative indexes count from the end.
+func ArrayElemAt(arr, index interface{}) Expr {
+	return opArray(operators.ARRAY_ELEM_AT, arr, index)
+}
+
+// Slice returns n elements of arr from its start (positive n) or end (negative n)
+// or, if position is given, n elements starting at position.
+func Slice(arr, n interface{}, position ...interface{}) Expr {
+	if len(position) == 1 {
+		return opArray(operators.SLICE, arr, position[0], n)
+	}
+
+	return optional(operators.SLICE, []interface{}{arr, n}, 1, position)
+}
+
+// ConcatArrays concatenates the arrays args.
+func ConcatArrays(args ...interface{}) Expr {
+	return variadic(operators.CONCAT_ARRAYS, 1, args)
+}
+
+// In reports whether v is an element of arr.
+func In(v, arr interface{}) Expr {
+	return opArray(operators.IN, v, arr)
+}
+
+// IsArray reports whether v is an array.
+func IsArray(v interface{}) Expr {
+	return opArray(operators.IS_ARRAY, v)
+}
+
+// ReverseArray returns the elements of arr in reverse order.
+func ReverseArray(arr interface{}) Expr {
+	return op(operators.REVERSE_ARRAY, arr)
+}
+
+// Filter returns the elements of arr for which cond is true. Inside cond the element is Var(as).
+func Filter(arr interface{}, as string, cond interface{}) Expr {
+	return opDoc(operators.FILTER, bson.D{
+		{Key: "input", Value: arr},
+		{Key: "as", Value: nonEmpty(as)},
+		{Key: "cond", Value: cond},
+	}, "as")
+}
+
+// Map applies in to every element of arr. Inside in the element is Var(as).
+func Map(arr interface{}, as string, in interface{}) Expr {
+	return opDoc(operators.MAP, bson.D{
+		{Key: "input", Value: arr},
+		{Key: "as", Value: nonEmpty(as)},
+		{Key: "in", Value: in},
+	}, "as")
+}
+
+// Reduce folds arr into a single value starting from initial. Inside in the accumulated
+// value is Var("value") and the element is Var("this").
+func Reduce(arr, initial, in interface{}) Expr {
+	return opDoc(operators.REDUCE, bson.D{
+		{Key: "input", Value: arr},
+		{Key: "initialValue", Value: initial},
+		{Key: "in", Value: in},
+	})
+}
+
+// SetUnion returns the distinct elements found in any of the arrays args.
+func SetUnion(args ...interface{}) Expr {
+	return variadic(operators.SET_UNION, 1, args)
+}
+
+// SetIntersection returns the distinct elements found in every one of the arrays args.
+func SetIntersection(args ...interface{}) Expr {
+	return variadic(operators.SET_INTERSECTION, 1, args)
+}
+
+// SetDifference returns the distinct elements of a that are not in b.
+func SetDifference(a, b interface{}) Expr {
+	return opArray(operators.SET_DIFFERENCE, a, b)
+}
+
+// SetEquals reports whether the arrays args have the same distinct elements.
+func SetEquals(args ...interface{}) Expr {
+	return variadic(operators.SET_EQUALS, 2, args)
+}
+
+// SetIsSubset reports whether every element of a is in b.
+func SetIsSubset(a, b interface{}) Expr {
+	return opArray(operators.SET_IS_SUBSET, a, b)
+}
+
+// AnyElementTrue reports whether at least one element of arr is true.
+func AnyElementTrue(arr interface{}) Expr {
+	return opArray(operators.ANY_ELEMENT_TRUE, arr)
+}
+
+// AllElementsTrue reports whether every element of arr is true.
+func AllElementsTrue(arr interface{}) Expr {
+	return opArray(operators.ALL_ELEMENTS_TRUE, arr)
+}
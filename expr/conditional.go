@@ -0,0 +1,99 @@
+package expr
+
+import (
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/v1shn3vsk7/mongorm/internal/operators"
+)
+
+// Case is a branch of Switch.
+type Case struct {
+	If   interface{}
+	Then interface{}
+}
+
+// Cond returns then if cond is true and otherwise els. Nil branches render as null,
+// since $cond requires all three arguments.
+func Cond(cond, then, els interface{}) Expr {
+	return opDoc(operators.COND, bson.D{
+		{Key: "if", Value: cond},
+		{Key: "then", Value: then},
+		{Key: "else", Value: els},
+	})
+}
+
+// IfNull returns the first of args that is not null or missing; the last one is the fallback.
+func IfNull(args ...interface{}) Expr {
+	return variadic(operators.IF_NULL, 2, args)
+}
+
+// Switch returns the Then of the first case whose If is true and otherwise def, which may be nil
+// if one of the cases always matches.
+func Switch(cases []Case, def interface{}) Expr {
+	branches := make(bson.A, 0, len(cases))
+	for _, c := range cases {
+		branches = append(branches, bson.D{{Key: "case", Value: c.If}, {Key: "then", Value: c.Then}})
+	}
+
+	e := opDoc(operators.SWITCH, bson.D{
+		{Key: "branches", Value: branches},
+		{Key: "default", Value: def},
+	}, "default")
+	if len(cases) == 0 && e.err == nil {
+		e.err = fmt.Errorf("%w: %s requires at least 1 case, got 0", ErrArity, operators.SWITCH)
+	}
+
+	return e
+}
+
+// Eq reports whether a equals b.
+func Eq(a, b interface{}) Expr {
+	return opArray(operators.EQ, a, b)
+}
+
+// Ne reports whether a does not equal b.
+func Ne(a, b interface{}) Expr {
+	return opArray(operators.NE, a, b)
+}
+
+// Gt reports whether a is greater than b.
+func Gt(a, b interface{}) Expr {
+	return opArray(operators.GT, a, b)
+}
+
+// Gte reports whether a is greater than or equal to b.
+func Gte(a, b interface{}) Expr {
+	return opArray(operators.GTE, a, b)
+}
+
+// Lt reports whether a is less than b.
+func Lt(a, b interface{}) Expr {
+	return opArray(operators.LT, a, b)
+}
+
+// Lte reports whether a is less than or equal to b.
+func Lte(a, b interface{}) Expr {
+	return opArray(operators.LTE, a, b)
+}
+
+// Cmp returns -1, 0 or 1 when a is less than, equal to or greater than b.
+func Cmp(a, b interface{}) Expr {
+	return opArray(operators.CMP, a, b)
+}
+
+// And reports whether every one of args is true.
+func And(args ...interface{}) Expr {
+	return variadic(operators.AND, 1, args)
+}
+
+// Or reports whether at least one of args is true.
+func Or(args ...interface{}) Expr {
+	return variadic(operators.OR, 1, args)
+}
+
+// Not negates a.
+func Not(a interface{}) Expr {
+	return opArray(operators.NOT, a)
+}
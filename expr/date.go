@@ -0,0 +1,124 @@
+package expr
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/v1shn3vsk7/mongorm/internal/operators"
+)
+
+// DateUnit is a unit of time accepted by DateAdd, DateDiff and DateTrunc.
+type DateUnit string
+
+const (
+	Year        DateUnit = "year"
+	Quarter     DateUnit = "quarter"
+	Week        DateUnit = "week"
+	Month       DateUnit = "month"
+	Day         DateUnit = "day"
+	Hour        DateUnit = "hour"
+	Minute      DateUnit = "minute"
+	Second      DateUnit = "second"
+	Millisecond DateUnit = "millisecond"
+)
+
+// DateToString formats date with format, e.g. "%Y-%m-%d". timezone may be empty.
+func DateToString(date interface{}, format, timezone string) Expr {
+	return opDoc(operators.DATE_TO_STRING, bson.D{
+		{Key: "date", Value: date},
+		{Key: "format", Value: format},
+		{Key: "timezone", Value: nonEmpty(timezone)},
+	}, "timezone")
+}
+
+// DateFromString parses s with format, which may be empty to use the ISO 8601 format.
+func DateFromString(s interface{}, format string) Expr {
+	return opDoc(operators.DATE_FROM_STRING, bson.D{
+		{Key: "dateString", Value: s},
+		{Key: "format", Value: nonEmpty(format)},
+	}, "format")
+}
+
+// DateAdd adds amount units to date.
+func DateAdd(date interface{}, unit DateUnit, amount interface{}) Expr {
+	e := opDoc(operators.DATE_ADD, bson.D{
+		{Key: "startDate", Value: date},
+		{Key: "unit", Value: string(unit)},
+		{Key: "amount", Value: amount},
+	})
+
+	return checkUnit(e, operators.DATE_ADD, unit)
+}
+
+// DateDiff returns the number of unit boundaries between start and end.
+func DateDiff(start, end interface{}, unit DateUnit) Expr {
+	e := opDoc(operators.DATE_DIFF, bson.D{
+		{Key: "startDate", Value: start},
+		{Key: "endDate", Value: end},
+		{Key: "unit", Value: string(unit)},
+	})
+
+	return checkUnit(e, operators.DATE_DIFF, unit)
+}
+
+// DateTrunc truncates date to the start of its unit.
+func DateTrunc(date interface{}, unit DateUnit) Expr {
+	e := opDoc(operators.DATE_TRUNC, bson.D{
+		{Key: "date", Value: date},
+		{Key: "unit", Value: string(unit)},
+	})
+
+	return checkUnit(e, operators.DATE_TRUNC, unit)
+}
+
+// YearOf returns the year of date.
+func YearOf(date interface{}) Expr {
+	return op(operators.YEAR, date)
+}
+
+// MonthOf returns the month of date, 1 to 12.
+func MonthOf(date interface{}) Expr {
+	return op(operators.MONTH, date)
+}
+
+// DayOfMonth returns the day of the month of date, 1 to 31.
+func DayOfMonth(date interface{}) Expr {
+	return op(operators.DAY_OF_MONTH, date)
+}
+
+// DayOfWeek returns the day of the week of date, 1 (Sunday) to 7 (Saturday).
+func DayOfWeek(date interface{}) Expr {
+	return op(operators.DAY_OF_WEEK, date)
+}
+
+// HourOf returns the hour of date, 0 to 23.
+func HourOf(date interface{}) Expr {
+	return op(operators.HOUR, date)
+}
+
+// MinuteOf returns the minute of date, 0 to 59.
+func MinuteOf(date interface{}) Expr {
+	return op(operators.MINUTE, date)
+}
+
+// SecondOf returns the second of date, 0 to 60.
+func SecondOf(date interface{}) Expr {
+	return op(operators.SECOND, date)
+}
+
+func checkUnit(e Expr, operator string, unit DateUnit) Expr {
+	switch unit {
+	case Year, Quarter, Week, Month, Day, Hour, Minute, Second, Millisecond:
+		return e
+	}
+
+	return invalid(e, operator, "a DateUnit", unit)
+}
+
+// nonEmpty turns an empty string into nil so that opDoc omits the optional argument.
+func nonEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+
+	return s
+}
@@ -0,0 +1,183 @@
+// Package expr builds aggregation expressions for pipeline stages and Query.WhereExpr.
+//
+// Every constructor returns an Expr. Arguments may be other expressions, field references
+// built with Field, or plain Go values. Mistakes such as a wrong number of arguments are
+// recorded in the expression when it is built and reported by Err; an invalid expression
+// also refuses to be marshalled, so it is never sent to the server.
+package expr
+
+import (
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+
+	"github.com/v1shn3vsk7/mongorm/internal/operators"
+)
+
+var (
+	// ErrArity is returned when an operator receives a wrong number of arguments.
+	ErrArity = errors.New("mongorm: wrong number of expression arguments")
+
+	// ErrInvalidArgument is returned when an argument of an operator has an unsupported value.
+	ErrInvalidArgument = errors.New("mongorm: invalid expression argument")
+)
+
+type Expr struct {
+	value interface{}
+	err   error
+}
+
+// Field references the value of path in the current document, e.g. Field("price") renders "$price".
+func Field(path string) Expr {
+	return Expr{value: "$" + path}
+}
+
+// Var references the variable name, e.g. Var("this") renders "$$this".
+func Var(name string) Expr {
+	return Expr{value: "$$" + name}
+}
+
+// Lit returns value without parsing it as an expression, e.g. a string starting with "$".
+func Lit(value interface{}) Expr {
+	return Expr{value: bson.D{{Key: operators.LITERAL, Value: value}}}
+}
+
+// Bson returns the rendered expression.
+func (e Expr) Bson() interface{} {
+	return e.value
+}
+
+// Err returns the first error found while building the expression or one of its arguments.
+func (e Expr) Err() error {
+	return e.err
+}
+
+func (e Expr) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	if e.err != nil {
+		return 0, nil, e.err
+	}
+
+	return bson.MarshalValue(e.value)
+}
+
+// Validate returns the first error of the expressions found in v, which may be an Expr
+// or a document or array containing expressions at any depth.
+func Validate(v interface{}) error {
+	switch val := v.(type) {
+	case Expr:
+		return val.err
+	case bson.D:
+		for _, elem := range val {
+			if err := Validate(elem.Value); err != nil {
+				return err
+			}
+		}
+	case bson.E:
+		return Validate(val.Value)
+	case bson.M:
+		for _, elem := range val {
+			if err := Validate(elem); err != nil {
+				return err
+			}
+		}
+	case bson.A:
+		for _, elem := range val {
+			if err := Validate(elem); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		return Validate(bson.A(val))
+	}
+
+	return nil
+}
+
+// op renders {operator: arg} for a single argument and {operator: [args...]} otherwise.
+func op(operator string, args ...interface{}) Expr {
+	var value interface{} = bson.A(args)
+	if len(args) == 1 {
+		value = args[0]
+	}
+
+	return Expr{
+		value: bson.D{{Key: operator, Value: value}},
+		err:   firstErr(args...),
+	}
+}
+
+// opArray renders {operator: [args...]} even for a single argument.
+func opArray(operator string, args ...interface{}) Expr {
+	return Expr{
+		value: bson.D{{Key: operator, Value: bson.A(args)}},
+		err:   firstErr(args...),
+	}
+}
+
+// opDoc renders {operator: {name: arg, ...}}. Nil arguments render as null, except the
+// optional ones, which are omitted.
+func opDoc(operator string, args bson.D, optional ...string) Expr {
+	doc := make(bson.D, 0, len(args))
+	for _, arg := range args {
+		if arg.Value == nil && isOptional(arg.Key, optional) {
+			continue
+		}
+
+		doc = append(doc, arg)
+	}
+
+	return Expr{
+		value: bson.D{{Key: operator, Value: doc}},
+		err:   Validate(doc),
+	}
+}
+
+func isOptional(key string, optional []string) bool {
+	for _, name := range optional {
+		if name == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+// variadic renders operator with args after checking that there are at least min of them.
+func variadic(operator string, min int, args []interface{}) Expr {
+	e := opArray(operator, args...)
+	if len(args) < min {
+		e.err = fmt.Errorf("%w: %s requires at least %d, got %d", ErrArity, operator, min, len(args))
+	}
+
+	return e
+}
+
+// optional renders operator with the required args followed by at most max of the optional ones.
+func optional(operator string, required []interface{}, max int, opts []interface{}) Expr {
+	e := opArray(operator, append(required, opts...)...)
+	if len(opts) > max {
+		e.err = fmt.Errorf("%w: %s accepts at most %d, got %d", ErrArity, operator, len(required)+max, len(required)+len(opts))
+	}
+
+	return e
+}
+
+func firstErr(args ...interface{}) error {
+	for _, arg := range args {
+		if err := Validate(arg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func invalid(e Expr, operator, want string, got interface{}) Expr {
+	if e.err == nil {
+		e.err = fmt.Errorf("%w: %s requires %s, got %v", ErrInvalidArgument, operator, want, got)
+	}
+
+	return e
+}
@@ -0,0 +1,68 @@
+package expr
+
+import (
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func render(t *testing.T, e Expr) string {
+	t.Helper()
+
+	data, err := bson.MarshalExtJSON(bson.D{{Key: "e", Value: e}}, false, false)
+	if err != nil {
+		t.Fatalf("err marshal %v: %v", e.Bson(), err)
+	}
+
+	return string(data)
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name string
+		expr Expr
+		want string
+	}{
+		{"cond", Cond(Gt(Field("n"), 1), "big", "small"), `{"$cond":{"if":{"$gt":["$n",1]},"then":"big","else":"small"}}`},
+		{"cond nil branches", Cond(Field("ok"), nil, nil), `{"$cond":{"if":"$ok","then":null,"else":null}}`},
+		{"switch without default", Switch([]Case{{If: Field("a"), Then: 1}}, nil), `{"$switch":{"branches":[{"case":"$a","then":1}]}}`},
+		{"reduce nil initial", Reduce(Field("a"), nil, Var("this")), `{"$reduce":{"input":"$a","initialValue":null,"in":"$$this"}}`},
+		{"map without as", Map(Field("a"), "", Var("this")), `{"$map":{"input":"$a","in":"$$this"}}`},
+		{"date to string without timezone", DateToString(Field("d"), "%Y", ""), `{"$dateToString":{"date":"$d","format":"%Y"}}`},
+		{"field", Field("a.b"), `"$a.b"`},
+		{"literal", Lit("$x"), `{"$literal":"$x"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := render(t, tt.expr); got != `{"e":`+tt.want+`}` {
+				t.Errorf("render = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		expr Expr
+		want error
+	}{
+		{"switch without cases", Switch(nil, 1), ErrArity},
+		{"and without arguments", And(), ErrArity},
+		{"nested", Cond(Switch(nil, 1), 1, 2), ErrArity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.expr.Err(); !errors.Is(err, tt.want) {
+				t.Errorf("Err() = %v, want %v", err, tt.want)
+			}
+
+			if _, err := bson.Marshal(bson.D{{Key: "e", Value: tt.expr}}); !errors.Is(err, tt.want) {
+				t.Errorf("Marshal: error %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package expr
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/v1shn3vsk7/mongorm/internal/operators"
+)
+
+// Concat concatenates the strings args.
+func Concat(args ...interface{}) Expr {
+	return variadic(operators.CONCAT, 1, args)
+}
+
+// ToLower converts s to lowercase.
+func ToLower(s interface{}) Expr {
+	return op(operators.TO_LOWER, s)
+}
+
+// ToUpper converts s to uppercase.
+func ToUpper(s interface{}) Expr {
+	return op(operators.TO_UPPER, s)
+}
+
+// ToString converts v to a string.
+func ToString(v interface{}) Expr {
+	return op(operators.TO_STRING, v)
+}
+
+// Substr returns length code points of s starting at the code point index start.
+func Substr(s, start, length interface{}) Expr {
+	return opArray(operators.SUBSTR_CP, s, start, length)
+}
+
+// StrLen returns the number of code points of s.
+func StrLen(s interface{}) Expr {
+	return op(operators.STR_LEN_CP, s)
+}
+
+// Trim removes whitespace or, if given, the characters of chars from both ends of s.
+func Trim(s interface{}, chars ...interface{}) Expr {
+	e := opDoc(operators.TRIM, bson.D{{Key: "input", Value: s}})
+	if len(chars) > 0 {
+		e = opDoc(operators.TRIM, bson.D{{Key: "input", Value: s}, {Key: "chars", Value: chars[0]}})
+	}
+
+	if len(chars) > 1 {
+		return invalid(e, operators.TRIM, "at most one set of characters", len(chars))
+	}
+
+	return e
+}
+
+// Split splits s on delimiter into an array of strings.
+func Split(s, delimiter interface{}) Expr {
+	return opArray(operators.SPLIT, s, delimiter)
+}
+
+// RegexMatch reports whether s matches regex, which may be a string or a primitive.Regex.
+func RegexMatch(s, regex interface{}) Expr {
+	return opDoc(operators.REGEX_MATCH, bson.D{{Key: "input", Value: s}, {Key: "regex", Value: regex}})
+}
+
+// ReplaceAll replaces every occurrence of find in s with replacement.
+func ReplaceAll(s, find, replacement interface{}) Expr {
+	return opDoc(operators.REPLACE_ALL, bson.D{
+		{Key: "input", Value: s},
+		{Key: "find", Value: find},
+		{Key: "replacement", Value: replacement},
+	})
+}
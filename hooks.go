@@ -0,0 +1,134 @@
+package mongorm
+
+import (
+	"context"
+	"reflect"
+)
+
+// Hooks are optional interfaces of models called around the operations of typed collections,
+// their queries and units of work. A hook returning an error aborts the operation, and the error
+// is returned. After hooks run once the operation is done, so it is only undone by their error
+// when it runs in a transaction.
+
+// BeforeInserter is implemented by models that prepare documents before they are inserted,
+// e.g. to normalize or derive fields.
+type BeforeInserter interface {
+	BeforeInsert(ctx context.Context) error
+}
+
+// AfterInserter is implemented by models that act on inserted documents.
+type AfterInserter interface {
+	AfterInsert(ctx context.Context) error
+}
+
+// BeforeUpdater is implemented by models that check or extend the updates of their documents.
+// It returns the update to apply. The hook is called on the updated instance for units of work
+// and on a zero value for queries.
+type BeforeUpdater interface {
+	BeforeUpdate(ctx context.Context, u *Update) (*Update, error)
+}
+
+// AfterFinder is implemented by models that complete documents once they are decoded.
+type AfterFinder interface {
+	AfterFind(ctx context.Context) error
+}
+
+// BeforeDeleter is implemented by models that check deletes of their documents, given by the
+// query of the delete. The hook is called on the deleted instance for units of work and on a
+// zero value for queries.
+type BeforeDeleter interface {
+	BeforeDelete(ctx context.Context, q *Query) error
+}
+
+// hookReceiver returns a zero value of the documents of the collection to call hooks on, or nil for untyped collections.
+func (c *Collection) hookReceiver() interface{} {
+	if c == nil || c.docType == nil {
+		return nil
+	}
+
+	t := c.docType
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	return reflect.New(t).Interface()
+}
+
+// receiver returns doc, or the pointer doc points to, so that hooks with pointer receivers
+// are found for documents of both struct and pointer types.
+func receiver(doc interface{}) interface{} {
+	v := reflect.ValueOf(doc)
+	if v.Kind() == reflect.Pointer && !v.IsNil() && v.Elem().Kind() == reflect.Pointer && !v.Elem().IsNil() {
+		return v.Elem().Interface()
+	}
+
+	return doc
+}
+
+func beforeInsert(ctx context.Context, doc interface{}) error {
+	if h, ok := receiver(doc).(BeforeInserter); ok {
+		return h.BeforeInsert(ctx)
+	}
+
+	return nil
+}
+
+func afterInsert(ctx context.Context, doc interface{}) error {
+	if h, ok := receiver(doc).(AfterInserter); ok {
+		return h.AfterInsert(ctx)
+	}
+
+	return nil
+}
+
+func beforeUpdate(ctx context.Context, doc interface{}, u *Update) (*Update, error) {
+	if h, ok := receiver(doc).(BeforeUpdater); ok {
+		return h.BeforeUpdate(ctx, u)
+	}
+
+	return u, nil
+}
+
+func beforeDelete(ctx context.Context, doc interface{}, q *Query) error {
+	if h, ok := receiver(doc).(BeforeDeleter); ok {
+		return h.BeforeDelete(ctx, q)
+	}
+
+	return nil
+}
+
+// afterFind calls the AfterFind hooks of dst, a pointer to a decoded document or to a slice of them.
+func afterFind(ctx context.Context, dst interface{}) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return nil
+	}
+
+	if v.Elem().Kind() != reflect.Slice {
+		if h, ok := receiver(dst).(AfterFinder); ok {
+			return h.AfterFind(ctx)
+		}
+
+		return nil
+	}
+
+	items := v.Elem()
+	for i := 0; i < items.Len(); i++ {
+		item := items.Index(i)
+		if item.Kind() != reflect.Pointer {
+			item = item.Addr()
+		}
+
+		if item.IsNil() {
+			continue
+		}
+
+		if h, ok := item.Interface().(AfterFinder); ok {
+			if err := h.AfterFind(ctx); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
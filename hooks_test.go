@@ -0,0 +1,278 @@
+package mongorm
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var errHook = errors.New("hook failed")
+
+type hookKey struct{}
+
+// hookLog records the hooks called with a context and fails the one named fail.
+type hookLog struct {
+	calls []string
+	fail  string
+}
+
+func hookContext(fail string) (context.Context, *hookLog) {
+	l := &hookLog{fail: fail}
+
+	return context.WithValue(context.Background(), hookKey{}, l), l
+}
+
+func hookCall(ctx context.Context, call string) error {
+	l, ok := ctx.Value(hookKey{}).(*hookLog)
+	if !ok {
+		return nil
+	}
+
+	l.calls = append(l.calls, call)
+	if strings.HasPrefix(call, l.fail+" ") {
+		return errHook
+	}
+
+	return nil
+}
+
+type hookDoc struct {
+	ID      int    `bson:"_id"`
+	Name    string `bson:"name"`
+	Slug    string `bson:"slug"`
+	Touched bool   `bson:"touched"`
+	Loaded  bool   `bson:"-"`
+}
+
+func (d *hookDoc) BeforeInsert(ctx context.Context) error {
+	d.Slug = strings.ToLower(d.Name)
+
+	return hookCall(ctx, "before-insert "+d.Name)
+}
+
+func (d *hookDoc) AfterInsert(ctx context.Context) error {
+	return hookCall(ctx, "after-insert "+d.Name)
+}
+
+func (d *hookDoc) BeforeUpdate(ctx context.Context, u *Update) (*Update, error) {
+	if err := hookCall(ctx, "before-update "+d.Name); err != nil {
+		return nil, err
+	}
+
+	return u.Set("touched", true), nil
+}
+
+func (d *hookDoc) AfterFind(ctx context.Context) error {
+	d.Loaded = true
+
+	return hookCall(ctx, "after-find "+d.Name)
+}
+
+func (d *hookDoc) BeforeDelete(ctx context.Context, q *Query) error {
+	return hookCall(ctx, "before-delete "+d.Name)
+}
+
+func hookDocs(t *testing.T, c *Collection) []hookDoc {
+	t.Helper()
+
+	docs, err := CollectionOf[hookDoc](c.db, c.Name()).All(context.Background(), c.Query().Sort("_id", ASC))
+	if err != nil {
+		t.Fatalf("All: %v", err)
+	}
+
+	return docs
+}
+
+func TestInsertHooks(t *testing.T) {
+	db := newTestDB(t)
+	c := CollectionOf[hookDoc](db, "hook_docs")
+
+	ctx, log := hookContext("")
+	if _, err := c.InsertOne(ctx, hookDoc{ID: 1, Name: "Ann"}); err != nil {
+		t.Fatalf("InsertOne: %v", err)
+	}
+	if _, err := c.InsertMany(ctx, []hookDoc{{ID: 2, Name: "Bob"}, {ID: 3, Name: "Eve"}}); err != nil {
+		t.Fatalf("InsertMany: %v", err)
+	}
+
+	want := []string{"before-insert Ann", "after-insert Ann", "before-insert Bob", "before-insert Eve", "after-insert Bob", "after-insert Eve"}
+	if !reflect.DeepEqual(log.calls, want) {
+		t.Errorf("hooks = %v, want %v", log.calls, want)
+	}
+
+	ctx, _ = hookContext("before-insert")
+	if _, err := c.InsertOne(ctx, hookDoc{ID: 4, Name: "Joe"}); !errors.Is(err, errHook) {
+		t.Errorf("InsertOne: error %v, want the error of BeforeInsert", err)
+	}
+	if _, err := c.InsertMany(ctx, []hookDoc{{ID: 5, Name: "Max"}}); !errors.Is(err, errHook) {
+		t.Errorf("InsertMany: error %v, want the error of BeforeInsert", err)
+	}
+
+	// the error of AfterInsert is returned once the document is inserted
+	ctx, _ = hookContext("after-insert")
+	if id, err := c.InsertOne(ctx, hookDoc{ID: 6, Name: "Sue"}); !errors.Is(err, errHook) || id != int32(6) {
+		t.Errorf("InsertOne = %v, %v, want the _id and the error of AfterInsert", id, err)
+	}
+
+	var slugs []string
+	for _, doc := range hookDocs(t, c.Collection) {
+		slugs = append(slugs, doc.Slug)
+	}
+	if want := []string{"ann", "bob", "eve", "sue"}; !reflect.DeepEqual(slugs, want) {
+		t.Errorf("stored slugs = %v, want %v", slugs, want)
+	}
+
+	// untyped collections do not call hooks
+	ctx, log = hookContext("")
+	if _, err := db.Collection("hook_docs").InsertOne(ctx, &hookDoc{ID: 7, Name: "Raw"}); err != nil {
+		t.Fatalf("InsertOne: %v", err)
+	}
+	if len(log.calls) > 0 {
+		t.Errorf("hooks of an untyped insert = %v, want none", log.calls)
+	}
+}
+
+func TestAfterFindHooks(t *testing.T) {
+	db := newTestDB(t)
+	c := CollectionOf[hookDoc](db, "hook_docs")
+	if _, err := c.InsertMany(context.Background(), []hookDoc{{ID: 1, Name: "Ann"}, {ID: 2, Name: "Bob"}}); err != nil {
+		t.Fatalf("InsertMany: %v", err)
+	}
+
+	ctx, log := hookContext("")
+
+	one, err := c.One(ctx, c.Query().Where("_id", EQ, 2))
+	if err != nil || !one.Loaded {
+		t.Errorf("One = %+v, %v, want a loaded document", one, err)
+	}
+
+	all, err := CollectionOf[*hookDoc](db, "hook_docs").All(ctx, nil)
+	if err != nil || len(all) != 2 || !all[0].Loaded || !all[1].Loaded {
+		t.Errorf("All of pointers = %v, %v, want 2 loaded documents", all, err)
+	}
+
+	c.Iter(ctx, nil)(func(doc hookDoc, err error) bool {
+		if err != nil || !doc.Loaded {
+			t.Errorf("Iter yielded %+v, %v, want a loaded document", doc, err)
+		}
+		return true
+	})
+
+	var untyped hookDoc
+	if err := db.Collection("hook_docs").Query().One(ctx, &untyped); err != nil || !untyped.Loaded {
+		t.Errorf("One of an untyped query = %+v, %v, want a loaded document", untyped, err)
+	}
+
+	want := []string{"after-find Bob", "after-find Ann", "after-find Bob", "after-find Ann", "after-find Bob", "after-find Ann"}
+	if !reflect.DeepEqual(log.calls, want) {
+		t.Errorf("hooks = %v, want %v", log.calls, want)
+	}
+
+	ctx, _ = hookContext("after-find")
+	if _, err := c.All(ctx, nil); !errors.Is(err, errHook) {
+		t.Errorf("All: error %v, want the error of AfterFind", err)
+	}
+
+	var errs int
+	c.Iter(ctx, nil)(func(doc hookDoc, err error) bool {
+		if err != nil {
+			errs++
+		}
+		return true
+	})
+	if errs != 1 {
+		t.Errorf("Iter yielded %d errors, want the error of AfterFind once", errs)
+	}
+}
+
+func TestUpdateAndDeleteHooks(t *testing.T) {
+	db := newTestDB(t)
+	c := CollectionOf[hookDoc](db, "hook_docs")
+	if _, err := c.InsertMany(context.Background(), []hookDoc{{ID: 1, Name: "Ann"}, {ID: 2, Name: "Bob"}}); err != nil {
+		t.Fatalf("InsertMany: %v", err)
+	}
+
+	// query hooks are called on a zero value, and the update they return is applied
+	ctx, log := hookContext("")
+	if _, err := c.Query().Where("_id", EQ, 1).UpdateOne(ctx, NewUpdate().Set("name", "Amy")); err != nil {
+		t.Fatalf("UpdateOne: %v", err)
+	}
+	if _, err := c.Query().Where("_id", EQ, 2).DeleteOne(ctx); err != nil {
+		t.Fatalf("DeleteOne: %v", err)
+	}
+
+	if want := []string{"before-update ", "before-delete "}; !reflect.DeepEqual(log.calls, want) {
+		t.Errorf("hooks = %v, want %v", log.calls, want)
+	}
+
+	docs := hookDocs(t, c.Collection)
+	if len(docs) != 1 || docs[0].Name != "Amy" || !docs[0].Touched {
+		t.Errorf("documents = %+v, want Amy touched by BeforeUpdate", docs)
+	}
+
+	ctx, _ = hookContext("before-update")
+	if _, err := c.Query().UpdateMany(ctx, NewUpdate().Set("name", "Max")); !errors.Is(err, errHook) {
+		t.Errorf("UpdateMany: error %v, want the error of BeforeUpdate", err)
+	}
+
+	ctx, _ = hookContext("before-delete")
+	if _, err := c.Query().DeleteMany(ctx); !errors.Is(err, errHook) {
+		t.Errorf("DeleteMany: error %v, want the error of BeforeDelete", err)
+	}
+
+	if docs := hookDocs(t, c.Collection); len(docs) != 1 || docs[0].Name != "Amy" {
+		t.Errorf("documents after failed hooks = %+v, want Amy unchanged", docs)
+	}
+}
+
+func TestUnitOfWorkHooks(t *testing.T) {
+	db := newTestDB(t)
+	if err := db.client.Register(&hookDoc{}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	ctx, log := hookContext("")
+	u := db.UnitOfWork()
+
+	ann, bob := &hookDoc{ID: 1, Name: "Ann"}, &hookDoc{ID: 2, Name: "Bob"}
+	if err := u.Add(ann, bob); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if err := u.Commit(ctx); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	// the hooks of units of work are called on the instances
+	ann.Name = "Amy"
+	if err := u.Remove(bob); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if err := u.Commit(ctx); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	want := []string{"before-insert Ann", "before-insert Bob", "after-insert Ann", "after-insert Bob", "before-update Amy", "before-delete Bob"}
+	if !reflect.DeepEqual(log.calls, want) {
+		t.Errorf("hooks = %v, want %v", log.calls, want)
+	}
+
+	docs := hookDocs(t, db.Collection("hook_docs"))
+	if len(docs) != 1 || docs[0].Name != "Amy" || docs[0].Slug != "ann" || !docs[0].Touched {
+		t.Errorf("documents = %+v, want Amy touched by BeforeUpdate", docs)
+	}
+
+	// the transaction of a commit is aborted by the error of an after hook
+	ctx, _ = hookContext("after-insert")
+	if err := u.Add(&hookDoc{ID: 3, Name: "Eve"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if err := u.Commit(ctx); !errors.Is(err, errHook) {
+		t.Errorf("Commit: error %v, want the error of AfterInsert", err)
+	}
+
+	if docs := hookDocs(t, db.Collection("hook_docs")); len(docs) != 1 {
+		t.Errorf("documents after an aborted commit = %+v, want Amy only", docs)
+	}
+}
@@ -0,0 +1,492 @@
+package mongorm
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mongo_options "go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/v1shn3vsk7/mongorm/internal/matcher"
+	"github.com/v1shn3vsk7/mongorm/options"
+)
+
+// IndexSpec declares an index of a collection.
+type IndexSpec struct {
+	// Name of the index. When empty the server derives it from the keys, e.g. "email_1".
+	Name string
+
+	// Keys are the indexed paths with their order, 1 or -1, or their index type: "text", "2dsphere",
+	// "2d" or "hashed". A wildcard index uses the path "$**" or "<path>.$**".
+	Keys bson.D
+
+	Unique bool
+	Sparse bool
+
+	// Partial restricts the index to the documents matched by the query.
+	Partial *Query
+
+	// ExpireAfter makes a TTL index: documents are removed once the indexed date is older than ExpireAfter.
+	ExpireAfter *time.Duration
+
+	// Weights and DefaultLanguage configure a text index.
+	Weights         bson.D
+	DefaultLanguage string
+
+	// WildcardProjection selects the paths of a wildcard index.
+	WildcardProjection bson.D
+
+	Collation *mongo_options.Collation
+}
+
+// IndexAction is the kind of an IndexChange.
+type IndexAction string
+
+const (
+	// IndexCreate is a declared index that does not exist.
+	IndexCreate IndexAction = "create"
+
+	// IndexDrop is an undeclared index that is dropped because of SyncIndexesOptions.DropUndeclared.
+	IndexDrop IndexAction = "drop"
+
+	// IndexDrift is a declared index that exists with other keys or options. It is reported but not changed,
+	// since rebuilding an index can be expensive; drop it to have it created again.
+	IndexDrift IndexAction = "drift"
+
+	// IndexUndeclared is an index that exists but is not declared and is kept.
+	IndexUndeclared IndexAction = "undeclared"
+)
+
+// IndexChange is a difference between the declared and the existing indexes of a collection.
+type IndexChange struct {
+	Collection string
+	Action     IndexAction
+	Name       string
+	Keys       bson.D
+
+	// Diff lists the differences of a drifted index, e.g. "unique: declared true, found false".
+	Diff []string
+}
+
+func (c IndexChange) String() string {
+	keys := make([]string, 0, len(c.Keys))
+	for _, key := range c.Keys {
+		keys = append(keys, fmt.Sprintf("%s: %v", key.Key, key.Value))
+	}
+
+	s := fmt.Sprintf("%s %s.%s {%s}", c.Action, c.Collection, c.Name, strings.Join(keys, ", "))
+	if len(c.Diff) > 0 {
+		s += ": " + strings.Join(c.Diff, "; ")
+	}
+
+	return s
+}
+
+// Model returns the driver model of the index, e.g. for Collection.CreateIndexes.
+func (s IndexSpec) Model() mongo.IndexModel {
+	opts := mongo_options.Index()
+	if s.Name != "" {
+		opts.SetName(s.Name)
+	}
+	if s.Unique {
+		opts.SetUnique(true)
+	}
+	if s.Sparse {
+		opts.SetSparse(true)
+	}
+	if s.Partial != nil {
+		opts.SetPartialFilterExpression(s.Partial.Bson())
+	}
+	if s.ExpireAfter != nil {
+		opts.SetExpireAfterSeconds(int32(s.ExpireAfter.Seconds()))
+	}
+	if s.Weights != nil {
+		opts.SetWeights(s.Weights)
+	}
+	if s.DefaultLanguage != "" {
+		opts.SetDefaultLanguage(s.DefaultLanguage)
+	}
+	if s.WildcardProjection != nil {
+		opts.SetWildcardProjection(s.WildcardProjection)
+	}
+	if s.Collation != nil {
+		opts.SetCollation(s.Collation)
+	}
+
+	return mongo.IndexModel{Keys: s.Keys, Options: opts}
+}
+
+// name returns the name of the index, derived from the keys like the server does when Name is empty.
+func (s IndexSpec) name() string {
+	if s.Name != "" {
+		return s.Name
+	}
+
+	parts := make([]string, 0, len(s.Keys))
+	for _, key := range s.Keys {
+		parts = append(parts, fmt.Sprintf("%s_%v", key.Key, key.Value))
+	}
+
+	return strings.Join(parts, "_")
+}
+
+func (s IndexSpec) validate() error {
+	if len(s.Keys) == 0 {
+		return fmt.Errorf("%w: index %q has no keys", ErrInvalidIndex, s.Name)
+	}
+
+	for _, key := range s.Keys {
+		switch v := key.Value.(type) {
+		case string:
+			switch v {
+			case "text", "2dsphere", "2d", "hashed":
+			default:
+				return fmt.Errorf("%w: index %q: unknown index type %q of %q", ErrInvalidIndex, s.name(), v, key.Key)
+			}
+		default:
+			if !isIndexOrder(key.Value) {
+				return fmt.Errorf("%w: index %q: order of %q must be 1 or -1, got %v", ErrInvalidIndex, s.name(), key.Key, key.Value)
+			}
+		}
+	}
+
+	if s.ExpireAfter != nil && (len(s.Keys) != 1 || *s.ExpireAfter < 0) {
+		return fmt.Errorf("%w: TTL index %q must have one key and a non-negative expiry", ErrInvalidIndex, s.name())
+	}
+
+	if s.Partial != nil && s.Partial.Err() != nil {
+		return fmt.Errorf("%w: index %q: %w", ErrInvalidIndex, s.name(), s.Partial.Err())
+	}
+
+	return nil
+}
+
+// isIndexOrder reports whether v is the number 1 or -1.
+func isIndexOrder(v interface{}) bool {
+	if v == nil {
+		return false
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int() == 1 || rv.Int() == -1
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return rv.Uint() == 1
+	case reflect.Float32, reflect.Float64:
+		return rv.Float() == 1 || rv.Float() == -1
+	}
+
+	return false
+}
+
+// document describes the index the way listIndexes does, restricted to the options SyncIndexes compares.
+func (s IndexSpec) document() (bson.D, error) {
+	doc := bson.D{{Key: "key", Value: s.Keys}, {Key: "name", Value: s.name()}}
+
+	if s.Unique {
+		doc = append(doc, bson.E{Key: "unique", Value: true})
+	}
+	if s.Sparse {
+		doc = append(doc, bson.E{Key: "sparse", Value: true})
+	}
+	if s.Partial != nil {
+		doc = append(doc, bson.E{Key: "partialFilterExpression", Value: s.Partial.Bson()})
+	}
+	if s.ExpireAfter != nil {
+		doc = append(doc, bson.E{Key: "expireAfterSeconds", Value: int32(s.ExpireAfter.Seconds())})
+	}
+	if s.Weights != nil {
+		doc = append(doc, bson.E{Key: "weights", Value: s.Weights})
+	}
+	if s.DefaultLanguage != "" {
+		doc = append(doc, bson.E{Key: "default_language", Value: s.DefaultLanguage})
+	}
+	if s.WildcardProjection != nil {
+		doc = append(doc, bson.E{Key: "wildcardProjection", Value: s.WildcardProjection})
+	}
+	if s.Collation != nil {
+		doc = append(doc, bson.E{Key: "collation", Value: s.Collation.ToDocument()})
+	}
+
+	return matcher.Normalize(doc)
+}
+
+// SyncIndexes compares the declared indexes with the existing ones of the collection, creates the
+// missing ones and reports drifted and undeclared ones, which are dropped with DropUndeclared.
+// The returned changes are only planned when DryRun is set.
+func (c *Collection) SyncIndexes(ctx context.Context, specs []IndexSpec, opts ...*options.SyncIndexesOptions) ([]IndexChange, error) {
+	s := c.storage()
+	if s == nil {
+		return nil, ErrNoCollection
+	}
+
+	opt := options.MergeSyncIndexesOptions(opts...)
+	dropUndeclared := opt.DropUndeclared != nil && *opt.DropUndeclared
+
+	changes, err := c.planIndexes(ctx, specs, dropUndeclared)
+	if err != nil || (opt.DryRun != nil && *opt.DryRun) {
+		return changes, err
+	}
+
+	var models []mongo.IndexModel
+	for _, change := range changes {
+		switch change.Action {
+		case IndexCreate:
+			for _, spec := range specs {
+				if spec.name() == change.Name {
+					models = append(models, spec.Model())
+					break
+				}
+			}
+		case IndexDrop:
+			if err := s.DropIndex(ctx, change.Name); err != nil {
+				return nil, c.mapErr("drop index", err)
+			}
+		}
+	}
+
+	if len(models) > 0 {
+		if _, err := s.CreateIndexes(ctx, models); err != nil {
+			return nil, c.mapErr("create indexes", err)
+		}
+	}
+
+	return changes, nil
+}
+
+// SyncIndexes synchronizes the indexes of the collections of every registered model with
+// the indexes declared by the models. See Collection.SyncIndexes.
+func (db *Database) SyncIndexes(ctx context.Context, opts ...*options.SyncIndexesOptions) ([]IndexChange, error) {
+	if db.client == nil {
+		return []IndexChange{}, nil
+	}
+
+	specs := make(map[string][]IndexSpec)
+	for _, m := range db.client.models.all() {
+		specs[m.Collection] = append(specs[m.Collection], m.Indexes...)
+	}
+
+	names := make([]string, 0, len(specs))
+	for name := range specs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	changes := make([]IndexChange, 0)
+	for _, name := range names {
+		c, err := db.Collection(name).SyncIndexes(ctx, specs[name], opts...)
+		if err != nil {
+			return nil, err
+		}
+
+		changes = append(changes, c...)
+	}
+
+	return changes, nil
+}
+
+// planIndexes returns the changes that make the existing indexes match specs.
+func (c *Collection) planIndexes(ctx context.Context, specs []IndexSpec, dropUndeclared bool) ([]IndexChange, error) {
+	existing, err := c.storage().ListIndexes(ctx)
+	if err != nil {
+		return nil, c.mapErr("list indexes", err)
+	}
+
+	changes := make([]IndexChange, 0)
+	matched := make(map[int]bool)
+	declared := make(map[string]bool)
+
+	for _, spec := range specs {
+		if err := spec.validate(); err != nil {
+			return nil, err
+		}
+
+		name := spec.name()
+		if declared[name] {
+			continue
+		}
+		declared[name] = true
+
+		want, err := spec.document()
+		if err != nil {
+			return nil, err
+		}
+
+		i := findIndex(existing, want)
+		if i < 0 {
+			changes = append(changes, IndexChange{Collection: c.Name(), Action: IndexCreate, Name: name, Keys: spec.Keys})
+			continue
+		}
+		matched[i] = true
+
+		if diff := indexDiff(want, existing[i]); len(diff) > 0 {
+			changes = append(changes, IndexChange{Collection: c.Name(), Action: IndexDrift, Name: name, Keys: spec.Keys, Diff: diff})
+		}
+	}
+
+	for i, doc := range existing {
+		name := nameOf(doc)
+		if matched[i] || name == "_id_" {
+			continue
+		}
+
+		action := IndexUndeclared
+		if dropUndeclared {
+			action = IndexDrop
+		}
+
+		changes = append(changes, IndexChange{
+			Collection: c.Name(),
+			Action:     action,
+			Name:       name,
+			Keys:       indexKeys(doc),
+		})
+	}
+
+	return changes, nil
+}
+
+// findIndex returns the position of the existing index with the name of want or, failing that, its keys.
+func findIndex(existing []bson.D, want bson.D) int {
+	for i, doc := range existing {
+		if nameOf(doc) == nameOf(want) {
+			return i
+		}
+	}
+
+	keys := indexKeys(want)
+	for i, doc := range existing {
+		if matcher.Equal(keys, indexKeys(doc)) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// indexDiff lists the differences between a declared and an existing index. Options that are
+// not declared are only compared when their absence changes the index, e.g. partialFilterExpression.
+func indexDiff(want, got bson.D) []string {
+	var diff []string
+
+	if w, g := indexKeys(want), indexKeys(got); !matcher.Equal(w, g) {
+		diff = append(diff, fmt.Sprintf("keys: declared %v, found %v", w, g))
+	}
+
+	if w, g := nameOf(want), nameOf(got); w != g {
+		diff = append(diff, fmt.Sprintf("name: declared %s, found %s", w, g))
+	}
+
+	for _, key := range []string{"unique", "sparse"} {
+		w, _ := matcher.Get(want, key)
+		g, _ := matcher.Get(got, key)
+		if matcher.Truthy(w) != matcher.Truthy(g) {
+			diff = append(diff, fmt.Sprintf("%s: declared %t, found %t", key, matcher.Truthy(w), matcher.Truthy(g)))
+		}
+	}
+
+	for _, key := range []string{"partialFilterExpression", "expireAfterSeconds", "wildcardProjection", "weights", "default_language", "collation"} {
+		w, declared := matcher.Get(want, key)
+		g, found := matcher.Get(got, key)
+
+		switch key {
+		case "weights":
+			w, g = sortedDoc(w), sortedDoc(g)
+		case "collation":
+			// the server reports every collation field, declared or not
+			if wd, ok := w.(bson.D); ok {
+				if gd, ok := g.(bson.D); ok {
+					g = subset(gd, wd)
+				}
+			}
+		}
+
+		switch {
+		case !declared && !found:
+		case !declared && (key == "weights" || key == "default_language" || key == "collation"):
+			// defaulted by the server
+		case declared != found || !matcher.Equal(w, g):
+			diff = append(diff, fmt.Sprintf("%s: declared %v, found %v", key, w, g))
+		}
+	}
+
+	return diff
+}
+
+// indexKeys returns the keys of an index description, with the internal keys of text indexes
+// replaced by the weighted fields and the fields of text indexes in name order.
+func indexKeys(doc bson.D) bson.D {
+	v, _ := matcher.Get(doc, "key")
+	keys, _ := v.(bson.D)
+
+	var weights bson.D
+	if w, ok := matcher.Get(doc, "weights"); ok {
+		weights, _ = w.(bson.D)
+	}
+
+	out := make(bson.D, 0, len(keys))
+	var text []string
+
+	flush := func() {
+		sort.Strings(text)
+		for _, field := range text {
+			out = append(out, bson.E{Key: field, Value: "text"})
+		}
+		text = nil
+	}
+
+	for _, key := range keys {
+		switch {
+		case key.Key == "_fts":
+			for _, w := range weights {
+				text = append(text, w.Key)
+			}
+		case key.Key == "_ftsx":
+		case key.Value == "text":
+			text = append(text, key.Key)
+		default:
+			flush()
+			out = append(out, key)
+		}
+	}
+	flush()
+
+	return out
+}
+
+func nameOf(doc bson.D) string {
+	name, _ := matcher.Get(doc, "name")
+	s, _ := name.(string)
+
+	return s
+}
+
+func sortedDoc(v interface{}) interface{} {
+	doc, ok := v.(bson.D)
+	if !ok {
+		return v
+	}
+
+	out := append(bson.D{}, doc...)
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+
+	return out
+}
+
+// subset returns the fields of doc that are present in keys, in the order of keys.
+func subset(doc, keys bson.D) bson.D {
+	out := bson.D{}
+	for _, key := range keys {
+		if v, ok := matcher.Get(doc, key.Key); ok {
+			out = append(out, bson.E{Key: key.Key, Value: v})
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,146 @@
+package mongorm
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/v1shn3vsk7/mongorm/options"
+)
+
+func TestIndexSpecValidate(t *testing.T) {
+	hour := time.Hour
+
+	tests := []struct {
+		name  string
+		spec  IndexSpec
+		valid bool
+	}{
+		{"ascending", IndexSpec{Keys: bson.D{{Key: "a", Value: 1}}}, true},
+		{"descending int64", IndexSpec{Keys: bson.D{{Key: "a", Value: int64(-1)}}}, true},
+		{"float order", IndexSpec{Keys: bson.D{{Key: "a", Value: 1.0}}}, true},
+		{"text", IndexSpec{Keys: bson.D{{Key: "a", Value: "text"}}}, true},
+		{"ttl", IndexSpec{Keys: bson.D{{Key: "a", Value: 1}}, ExpireAfter: &hour}, true},
+		{"no keys", IndexSpec{}, false},
+		{"order 5", IndexSpec{Keys: bson.D{{Key: "a", Value: 5}}}, false},
+		{"order 0", IndexSpec{Keys: bson.D{{Key: "a", Value: 0}}}, false},
+		{"order 0.5", IndexSpec{Keys: bson.D{{Key: "a", Value: 0.5}}}, false},
+		{"bool order", IndexSpec{Keys: bson.D{{Key: "a", Value: true}}}, false},
+		{"unknown type", IndexSpec{Keys: bson.D{{Key: "a", Value: "btree"}}}, false},
+		{"ttl compound", IndexSpec{Keys: bson.D{{Key: "a", Value: 1}, {Key: "b", Value: 1}}, ExpireAfter: &hour}, false},
+		{"invalid partial", IndexSpec{Keys: bson.D{{Key: "a", Value: 1}}, Partial: NewQuery().Where("a", SIZE, -1)}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.spec.validate()
+			if tt.valid && err != nil {
+				t.Errorf("validate: %v", err)
+			}
+
+			if !tt.valid && !errors.Is(err, ErrInvalidIndex) {
+				t.Errorf("validate: error %v, want ErrInvalidIndex", err)
+			}
+		})
+	}
+}
+
+func changeSummary(changes []IndexChange) []string {
+	out := make([]string, 0, len(changes))
+	for _, c := range changes {
+		out = append(out, string(c.Action)+" "+c.Name)
+	}
+
+	return out
+}
+
+func TestSyncIndexes(t *testing.T) {
+	ctx := context.Background()
+	c := newTestDB(t).Collection("c")
+
+	if _, err := c.CreateIndexes(ctx,
+		IndexSpec{Keys: bson.D{{Key: "email", Value: 1}}}.Model(),
+		IndexSpec{Name: "old", Keys: bson.D{{Key: "old", Value: 1}}}.Model(),
+	); err != nil {
+		t.Fatalf("CreateIndexes: %v", err)
+	}
+
+	specs := []IndexSpec{
+		{Keys: bson.D{{Key: "email", Value: 1}}, Unique: true},
+		{Name: "name", Keys: bson.D{{Key: "first", Value: 1}, {Key: "last", Value: -1}}},
+	}
+
+	changes, err := c.SyncIndexes(ctx, specs, options.SyncIndexes().SetDryRun(true))
+	if err != nil {
+		t.Fatalf("SyncIndexes dry run: %v", err)
+	}
+
+	want := []string{"drift email_1", "create name", "undeclared old"}
+	if got := changeSummary(changes); !reflect.DeepEqual(got, want) {
+		t.Errorf("SyncIndexes dry run = %v, want %v", got, want)
+	}
+	if changes[0].Diff[0] != "unique: declared true, found false" {
+		t.Errorf("drift diff = %v, want the unique option", changes[0].Diff)
+	}
+
+	// the dry run did not create anything
+	if changes, _ := c.SyncIndexes(ctx, specs, options.SyncIndexes().SetDryRun(true)); len(changes) != 3 {
+		t.Errorf("changes after a dry run = %v, want the same 3", changeSummary(changes))
+	}
+
+	if _, err := c.SyncIndexes(ctx, specs, options.SyncIndexes().SetDropUndeclared(true)); err != nil {
+		t.Fatalf("SyncIndexes: %v", err)
+	}
+
+	// drifted indexes are only reported
+	changes, err = c.SyncIndexes(ctx, specs)
+	if err != nil {
+		t.Fatalf("SyncIndexes: %v", err)
+	}
+	if got := changeSummary(changes); !reflect.DeepEqual(got, []string{"drift email_1"}) {
+		t.Errorf("SyncIndexes after sync = %v, want [drift email_1]", got)
+	}
+
+	if _, err := c.SyncIndexes(ctx, []IndexSpec{{Keys: bson.D{{Key: "a", Value: 5}}}}); !errors.Is(err, ErrInvalidIndex) {
+		t.Errorf("SyncIndexes of an invalid spec: error %v, want ErrInvalidIndex", err)
+	}
+}
+
+func TestDatabaseSyncIndexes(t *testing.T) {
+	ctx := context.Background()
+
+	client, err := NewMemoryClient()
+	if err != nil {
+		t.Fatalf("NewMemoryClient: %v", err)
+	}
+
+	if err := client.Register(userProfile{}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	db := client.Database("test")
+
+	changes, err := db.SyncIndexes(ctx)
+	if err != nil {
+		t.Fatalf("SyncIndexes: %v", err)
+	}
+
+	want := []string{"create email_1", "create name", "create address.city_1"}
+	if got := changeSummary(changes); !reflect.DeepEqual(got, want) {
+		t.Errorf("SyncIndexes = %v, want %v", got, want)
+	}
+
+	if changes, err := db.SyncIndexes(ctx); err != nil || len(changes) != 0 {
+		t.Errorf("SyncIndexes again = %v, %v, want no changes", changeSummary(changes), err)
+	}
+
+	// the unique index is enforced
+	users := db.Collection("user_profiles")
+	if _, err := users.InsertMany(ctx, []interface{}{bson.M{"email": "a"}, bson.M{"email": "a"}}); err == nil {
+		t.Error("InsertMany of duplicate emails succeeded")
+	}
+}
@@ -5,4 +5,5 @@ type Action struct {
 	Key      string
 	Operator uint8
 	Value    interface{}
+	Children []*Action
 }
@@ -7,4 +7,7 @@ const (
 	Where
 	And
 	Or
+	Group
+	Nor
+	Expr
 )
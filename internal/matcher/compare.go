@@ -0,0 +1,260 @@
+package matcher
+
+import (
+	"bytes"
+	"math"
+	"math/big"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// canonical type order of MongoDB comparisons, see
+// https://www.mongodb.com/docs/manual/reference/bson-type-comparison-order/
+const (
+	orderMinKey = iota + 1
+	orderNull
+	orderNumber
+	orderString
+	orderObject
+	orderArray
+	orderBinary
+	orderObjectID
+	orderBool
+	orderDate
+	orderTimestamp
+	orderRegex
+	orderMaxKey
+	orderOther
+)
+
+// Order returns the position of the type of v in the canonical comparison order.
+// Values are expected in the form produced by Normalize.
+func Order(v interface{}) int {
+	switch v.(type) {
+	case primitive.MinKey:
+		return orderMinKey
+	case nil, primitive.Null, primitive.Undefined:
+		return orderNull
+	case int32, int64, float64, primitive.Decimal128:
+		return orderNumber
+	case string, primitive.Symbol:
+		return orderString
+	case bson.D:
+		return orderObject
+	case bson.A:
+		return orderArray
+	case primitive.Binary:
+		return orderBinary
+	case primitive.ObjectID:
+		return orderObjectID
+	case bool:
+		return orderBool
+	case primitive.DateTime:
+		return orderDate
+	case primitive.Timestamp:
+		return orderTimestamp
+	case primitive.Regex:
+		return orderRegex
+	case primitive.MaxKey:
+		return orderMaxKey
+	}
+
+	return orderOther
+}
+
+// Compare returns -1, 0 or 1 when a sorts before, together with or after b.
+// Values of different types are ordered by Order.
+func Compare(a, b interface{}) int {
+	oa, ob := Order(a), Order(b)
+	if oa != ob {
+		return sign(oa - ob)
+	}
+
+	switch oa {
+	case orderNumber:
+		return compareNumbers(a, b)
+	case orderString:
+		return strings.Compare(toString(a), toString(b))
+	case orderObject:
+		return compareDocs(a.(bson.D), b.(bson.D))
+	case orderArray:
+		return compareArrays(a.(bson.A), b.(bson.A))
+	case orderBinary:
+		ba, bb := a.(primitive.Binary), b.(primitive.Binary)
+		if len(ba.Data) != len(bb.Data) {
+			return sign(len(ba.Data) - len(bb.Data))
+		}
+		if ba.Subtype != bb.Subtype {
+			return sign(int(ba.Subtype) - int(bb.Subtype))
+		}
+		return bytes.Compare(ba.Data, bb.Data)
+	case orderObjectID:
+		oa, ob := a.(primitive.ObjectID), b.(primitive.ObjectID)
+		return bytes.Compare(oa[:], ob[:])
+	case orderBool:
+		ba, bb := a.(bool), b.(bool)
+		switch {
+		case ba == bb:
+			return 0
+		case bb:
+			return -1
+		}
+		return 1
+	case orderDate:
+		return compareInts(int64(a.(primitive.DateTime)), int64(b.(primitive.DateTime)))
+	case orderTimestamp:
+		ta, tb := a.(primitive.Timestamp), b.(primitive.Timestamp)
+		return primitive.CompareTimestamp(ta, tb)
+	case orderRegex:
+		ra, rb := a.(primitive.Regex), b.(primitive.Regex)
+		if c := strings.Compare(ra.Pattern, rb.Pattern); c != 0 {
+			return c
+		}
+		return strings.Compare(ra.Options, rb.Options)
+	}
+
+	return 0
+}
+
+// Equal reports whether a and b are equal, e.g. int32(1) equals 1.0.
+func Equal(a, b interface{}) bool {
+	return Order(a) == Order(b) && Compare(a, b) == 0
+}
+
+func compareDocs(a, b bson.D) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := sign(Order(a[i].Value) - Order(b[i].Value)); c != 0 {
+			return c
+		}
+		if c := strings.Compare(a[i].Key, b[i].Key); c != 0 {
+			return c
+		}
+		if c := Compare(a[i].Value, b[i].Value); c != 0 {
+			return c
+		}
+	}
+
+	return sign(len(a) - len(b))
+}
+
+func compareArrays(a, b bson.A) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := Compare(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+
+	return sign(len(a) - len(b))
+}
+
+func compareNumbers(a, b interface{}) int {
+	ia, aInt := a.(int64)
+	if v, ok := a.(int32); ok {
+		ia, aInt = int64(v), true
+	}
+
+	ib, bInt := b.(int64)
+	if v, ok := b.(int32); ok {
+		ib, bInt = int64(v), true
+	}
+
+	if aInt && bInt {
+		return compareInts(ia, ib)
+	}
+
+	fa, fb := toBigFloat(a), toBigFloat(b)
+	if fa == nil || fb == nil {
+		// NaN sorts before every other number
+		switch {
+		case fa == nil && fb == nil:
+			return 0
+		case fa == nil:
+			return -1
+		}
+		return 1
+	}
+
+	return fa.Cmp(fb)
+}
+
+// toBigFloat converts a number to an exact big.Float, or nil for NaN.
+func toBigFloat(v interface{}) *big.Float {
+	switch n := v.(type) {
+	case int32:
+		return new(big.Float).SetInt64(int64(n))
+	case int64:
+		return new(big.Float).SetInt64(n)
+	case float64:
+		if math.IsNaN(n) {
+			return nil
+		}
+		return new(big.Float).SetFloat64(n)
+	case primitive.Decimal128:
+		f, _, err := big.ParseFloat(n.String(), 10, 128, big.ToNearestEven)
+		if err != nil {
+			switch n.String() {
+			case "Infinity":
+				return new(big.Float).SetInf(false)
+			case "-Infinity":
+				return new(big.Float).SetInf(true)
+			}
+			return nil
+		}
+		return f
+	}
+
+	return nil
+}
+
+// ToFloat converts a number to float64.
+func ToFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	case primitive.Decimal128:
+		f := toBigFloat(n)
+		if f == nil {
+			return math.NaN(), true
+		}
+		r, _ := f.Float64()
+		return r, true
+	}
+
+	return 0, false
+}
+
+func toString(v interface{}) string {
+	if s, ok := v.(primitive.Symbol); ok {
+		return string(s)
+	}
+
+	return v.(string)
+}
+
+func compareInts(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+
+	return 0
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+
+	return 0
+}
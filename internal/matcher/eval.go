@@ -0,0 +1,338 @@
+package matcher
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/v1shn3vsk7/mongorm/internal/operators"
+)
+
+// Eval evaluates the aggregation expression e against doc. Only the operators that make
+// sense in $expr filters are supported: field references, literals, comparison, boolean,
+// arithmetic, conditional and a few string and array operators.
+func Eval(e interface{}, doc bson.D) (interface{}, error) {
+	switch v := e.(type) {
+	case string:
+		switch {
+		case v == "$$ROOT" || v == "$$CURRENT":
+			return doc, nil
+		case strings.HasPrefix(v, "$$"):
+			return nil, fmt.Errorf("%w: variable %s", ErrUnsupported, v)
+		case strings.HasPrefix(v, "$"):
+			val, _ := Resolve(doc, strings.Split(v[1:], "."))
+			return val, nil
+		}
+
+		return v, nil
+	case bson.A:
+		out := make(bson.A, 0, len(v))
+		for _, item := range v {
+			val, err := Eval(item, doc)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, val)
+		}
+
+		return out, nil
+	case bson.D:
+		if len(v) == 1 && strings.HasPrefix(v[0].Key, "$") {
+			return evalOp(v[0].Key, v[0].Value, doc)
+		}
+
+		out := make(bson.D, 0, len(v))
+		for _, elem := range v {
+			val, err := Eval(elem.Value, doc)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, bson.E{Key: elem.Key, Value: val})
+		}
+
+		return out, nil
+	}
+
+	return e, nil
+}
+
+func evalOp(op string, arg interface{}, doc bson.D) (interface{}, error) {
+	if op == operators.LITERAL {
+		return arg, nil
+	}
+
+	if op == operators.COND {
+		return evalCond(arg, doc)
+	}
+
+	args, err := evalArgs(arg, doc)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op {
+	case operators.EQ, operators.NE, operators.GT, operators.GTE, operators.LT, operators.LTE, operators.CMP:
+		if err := arity(op, args, 2); err != nil {
+			return nil, err
+		}
+
+		c := Compare(args[0], args[1])
+
+		switch op {
+		case operators.EQ:
+			return c == 0, nil
+		case operators.NE:
+			return c != 0, nil
+		case operators.GT:
+			return c > 0, nil
+		case operators.GTE:
+			return c >= 0, nil
+		case operators.LT:
+			return c < 0, nil
+		case operators.LTE:
+			return c <= 0, nil
+		}
+
+		return int32(c), nil
+	case operators.AND:
+		for _, a := range args {
+			if !Truthy(a) {
+				return false, nil
+			}
+		}
+		return true, nil
+	case operators.OR:
+		for _, a := range args {
+			if Truthy(a) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case operators.NOT:
+		if err := arity(op, args, 1); err != nil {
+			return nil, err
+		}
+		return !Truthy(args[0]), nil
+	case operators.ADD, operators.MULTIPLY:
+		return evalArithmetic(op, args)
+	case operators.SUBTRACT, operators.DIVIDE, operators.MOD:
+		if err := arity(op, args, 2); err != nil {
+			return nil, err
+		}
+		return evalArithmetic(op, args)
+	case operators.ABS:
+		if err := arity(op, args, 1); err != nil {
+			return nil, err
+		}
+		if isNull(args[0]) {
+			return nil, nil
+		}
+		f, ok := ToFloat(args[0])
+		if !ok {
+			return nil, fmt.Errorf("mongorm: %s requires a number", op)
+		}
+		return numberLike(args, math.Abs(f)), nil
+	case operators.IF_NULL:
+		if len(args) < 2 {
+			return nil, fmt.Errorf("mongorm: %s requires at least 2 arguments, got %d", op, len(args))
+		}
+		for _, a := range args[:len(args)-1] {
+			if !isNull(a) {
+				return a, nil
+			}
+		}
+		return args[len(args)-1], nil
+	case operators.SIZE:
+		if err := arity(op, args, 1); err != nil {
+			return nil, err
+		}
+		arr, ok := args[0].(bson.A)
+		if !ok {
+			return nil, fmt.Errorf("mongorm: %s requires an array", op)
+		}
+		return int32(len(arr)), nil
+	case operators.IN:
+		if err := arity(op, args, 2); err != nil {
+			return nil, err
+		}
+		arr, ok := args[1].(bson.A)
+		if !ok {
+			return nil, fmt.Errorf("mongorm: %s requires an array", op)
+		}
+		for _, item := range arr {
+			if Equal(args[0], item) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case operators.CONCAT:
+		var sb strings.Builder
+		for _, a := range args {
+			if isNull(a) {
+				return nil, nil
+			}
+			s, ok := a.(string)
+			if !ok {
+				return nil, fmt.Errorf("mongorm: %s requires strings", op)
+			}
+			sb.WriteString(s)
+		}
+		return sb.String(), nil
+	case operators.TO_LOWER, operators.TO_UPPER:
+		if err := arity(op, args, 1); err != nil {
+			return nil, err
+		}
+		s, _ := args[0].(string)
+		if op == operators.TO_LOWER {
+			return strings.ToLower(s), nil
+		}
+		return strings.ToUpper(s), nil
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrUnsupported, op)
+}
+
+func evalArgs(arg interface{}, doc bson.D) (bson.A, error) {
+	list, ok := arg.(bson.A)
+	if !ok {
+		list = bson.A{arg}
+	}
+
+	val, err := Eval(list, doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return val.(bson.A), nil
+}
+
+func evalCond(arg interface{}, doc bson.D) (interface{}, error) {
+	var cond, then, els interface{}
+
+	switch v := arg.(type) {
+	case bson.A:
+		if len(v) != 3 {
+			return nil, fmt.Errorf("mongorm: %s requires 3 arguments", operators.COND)
+		}
+		cond, then, els = v[0], v[1], v[2]
+	case bson.D:
+		cond, _ = Get(v, "if")
+		then, _ = Get(v, "then")
+		els, _ = Get(v, "else")
+	default:
+		return nil, fmt.Errorf("mongorm: %s requires an array or a document", operators.COND)
+	}
+
+	c, err := Eval(cond, doc)
+	if err != nil {
+		return nil, err
+	}
+
+	if Truthy(c) {
+		return Eval(then, doc)
+	}
+
+	return Eval(els, doc)
+}
+
+// evalArithmetic applies op to numbers, keeping integer results as int64 and
+// supporting date arithmetic in milliseconds for $add and $subtract.
+func evalArithmetic(op string, args bson.A) (interface{}, error) {
+	for _, a := range args {
+		if isNull(a) {
+			return nil, nil
+		}
+	}
+
+	if op == operators.ADD || op == operators.SUBTRACT {
+		if date, ok := args[0].(primitive.DateTime); ok {
+			if other, ok := args[1].(primitive.DateTime); ok && op == operators.SUBTRACT {
+				return int64(date) - int64(other), nil
+			}
+		}
+	}
+
+	var dates int
+	ints := true
+	var isum int64
+	var fsum float64
+
+	for i, a := range args {
+		if d, ok := a.(primitive.DateTime); ok {
+			dates++
+			a = int64(d)
+		}
+
+		f, ok := ToFloat(a)
+		if !ok {
+			return nil, fmt.Errorf("mongorm: %s requires numbers", op)
+		}
+
+		n, isInt := a.(int64)
+		if v, ok := a.(int32); ok {
+			n, isInt = int64(v), true
+		}
+		ints = ints && isInt
+
+		if i == 0 {
+			isum, fsum = n, f
+			continue
+		}
+
+		switch op {
+		case operators.ADD:
+			isum, fsum = isum+n, fsum+f
+		case operators.SUBTRACT:
+			isum, fsum = isum-n, fsum-f
+		case operators.MULTIPLY:
+			isum, fsum = isum*n, fsum*f
+		case operators.DIVIDE:
+			if f == 0 {
+				return nil, fmt.Errorf("mongorm: %s by zero", op)
+			}
+			ints, fsum = false, fsum/f
+		case operators.MOD:
+			if f == 0 {
+				return nil, fmt.Errorf("mongorm: %s by zero", op)
+			}
+			if ints {
+				isum = isum % n
+			}
+			fsum = math.Mod(fsum, f)
+		}
+	}
+
+	switch {
+	case dates > 0:
+		if ints {
+			return primitive.DateTime(isum), nil
+		}
+		return primitive.DateTime(int64(fsum)), nil
+	case ints:
+		return isum, nil
+	}
+
+	return fsum, nil
+}
+
+func numberLike(args bson.A, f float64) interface{} {
+	switch args[0].(type) {
+	case int32:
+		return int32(f)
+	case int64:
+		return int64(f)
+	}
+
+	return f
+}
+
+func arity(op string, args bson.A, n int) error {
+	if len(args) != n {
+		return fmt.Errorf("mongorm: %s requires %d arguments, got %d", op, n, len(args))
+	}
+
+	return nil
+}
@@ -0,0 +1,669 @@
+// Package matcher evaluates query filters against documents in memory, following the
+// comparison and type ordering rules of MongoDB.
+package matcher
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/v1shn3vsk7/mongorm/internal/operators"
+)
+
+// ErrUnsupported is returned for operations that cannot be evaluated without a server, e.g. $text or geo queries.
+var ErrUnsupported = errors.New("mongorm: not supported in memory")
+
+// typeCodes maps the $type aliases to BSON type numbers.
+var typeCodes = map[string]int32{
+	"double": 1, "string": 2, "object": 3, "array": 4, "binData": 5, "undefined": 6,
+	"objectId": 7, "bool": 8, "date": 9, "null": 10, "regex": 11, "dbPointer": 12,
+	"javascript": 13, "symbol": 14, "javascriptWithScope": 15, "int": 16, "timestamp": 17,
+	"long": 18, "decimal": 19, "minKey": -1, "maxKey": 127,
+}
+
+// Normalize converts v, a struct, map, bson.D or bson.Raw, into a bson.D holding the values
+// the driver decodes from BSON, so that Go types compare the same way they would on the server.
+func Normalize(v interface{}) (bson.D, error) {
+	raw, ok := v.(bson.Raw)
+	if !ok {
+		var err error
+		if raw, err = bson.Marshal(v); err != nil {
+			return nil, fmt.Errorf("mongorm: err marshal document: %w", err)
+		}
+	}
+
+	doc := bson.D{}
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("mongorm: err unmarshal document: %w", err)
+	}
+
+	return doc, nil
+}
+
+// Match reports whether doc matches filter. Both must be normalized.
+func Match(filter, doc bson.D) (bool, error) {
+	for _, elem := range filter {
+		ok, err := matchElem(elem, doc)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+func matchElem(elem bson.E, doc bson.D) (bool, error) {
+	switch elem.Key {
+	case operators.AND, operators.OR, operators.NOR:
+		filters, ok := elem.Value.(bson.A)
+		if !ok || len(filters) == 0 {
+			return false, fmt.Errorf("mongorm: %s requires a non-empty array", elem.Key)
+		}
+
+		return matchLogical(elem.Key, filters, doc)
+	case operators.EXPR:
+		res, err := Eval(elem.Value, doc)
+		if err != nil {
+			return false, err
+		}
+
+		return Truthy(res), nil
+	case "$comment":
+		return true, nil
+	}
+
+	if strings.HasPrefix(elem.Key, "$") {
+		return false, fmt.Errorf("%w: %s", ErrUnsupported, elem.Key)
+	}
+
+	parts := strings.Split(elem.Key, ".")
+
+	values := Lookup(doc, parts)
+	if len(values) > 0 && lacks(doc, parts) {
+		values = append(values, missing{})
+	}
+
+	return matchValue(values, elem.Value)
+}
+
+func matchLogical(op string, filters bson.A, doc bson.D) (bool, error) {
+	for _, f := range filters {
+		sub, ok := f.(bson.D)
+		if !ok {
+			return false, fmt.Errorf("mongorm: %s requires an array of documents", op)
+		}
+
+		matched, err := Match(sub, doc)
+		if err != nil {
+			return false, err
+		}
+
+		switch {
+		case op == operators.AND && !matched:
+			return false, nil
+		case op == operators.OR && matched:
+			return true, nil
+		case op == operators.NOR && matched:
+			return false, nil
+		}
+	}
+
+	return op != operators.OR, nil
+}
+
+// matchValue reports whether the values found at a path satisfy cond, either a
+// document of operators or a value the field must be equal to.
+func matchValue(values []interface{}, cond interface{}) (bool, error) {
+	ops, ok := operatorDoc(cond)
+	if !ok {
+		if re, ok := cond.(primitive.Regex); ok {
+			return matchRegex(values, re)
+		}
+
+		return matchEq(values, cond), nil
+	}
+
+	for _, op := range ops {
+		matched, err := matchOp(values, op, ops)
+		if err != nil || !matched {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+func matchOp(values []interface{}, op bson.E, ops bson.D) (bool, error) {
+	switch op.Key {
+	case operators.EQ:
+		return matchEq(values, op.Value), nil
+	case operators.NE:
+		return !matchEq(values, op.Value), nil
+	case operators.GT, operators.GTE, operators.LT, operators.LTE:
+		return matchCmp(values, op.Key, op.Value), nil
+	case operators.IN, operators.NIN:
+		in, err := matchIn(values, op.Key, op.Value)
+		return in == (op.Key == operators.IN), err
+	case operators.EXISTS:
+		exists := anyValue(values, func(v interface{}) bool { return v != missing{} })
+		return exists == Truthy(op.Value), nil
+	case operators.TYPE:
+		return matchType(values, op.Value)
+	case operators.MOD:
+		return matchMod(values, op.Value)
+	case operators.REGEX:
+		re, err := regexOf(op.Value, ops)
+		if err != nil {
+			return false, err
+		}
+		return matchRegex(values, re)
+	case operators.OPTIONS:
+		if _, ok := Get(ops, operators.REGEX); !ok {
+			return false, fmt.Errorf("mongorm: %s requires %s", operators.OPTIONS, operators.REGEX)
+		}
+		return true, nil
+	case operators.SIZE:
+		return matchSize(values, op.Value)
+	case operators.ALL:
+		return matchAll(values, op.Value)
+	case operators.ELEM_MATCH:
+		return matchElemMatch(values, op.Value)
+	case operators.NOT:
+		matched, err := matchValue(values, op.Value)
+		return !matched && err == nil, err
+	case operators.BITS_ALL_SET, operators.BITS_ANY_SET, operators.BITS_ALL_CLEAR, operators.BITS_ANY_CLEAR:
+		return matchBits(values, op.Key, op.Value)
+	}
+
+	return false, fmt.Errorf("%w: %s", ErrUnsupported, op.Key)
+}
+
+// operatorDoc reports whether cond is a document of query operators.
+func operatorDoc(cond interface{}) (bson.D, bool) {
+	doc, ok := cond.(bson.D)
+	if !ok || len(doc) == 0 || !strings.HasPrefix(doc[0].Key, "$") {
+		return nil, false
+	}
+
+	return doc, true
+}
+
+// anyValue reports whether fn holds for one of values or, if a value is an array, one of its elements.
+func anyValue(values []interface{}, fn func(v interface{}) bool) bool {
+	for _, v := range values {
+		if fn(v) {
+			return true
+		}
+
+		if arr, ok := v.(bson.A); ok {
+			for _, elem := range arr {
+				if fn(elem) {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+func matchEq(values []interface{}, x interface{}) bool {
+	if isNull(x) {
+		return len(values) == 0 || anyValue(values, func(v interface{}) bool { return isNull(v) || v == missing{} })
+	}
+
+	return anyValue(values, func(v interface{}) bool {
+		return Equal(v, x)
+	})
+}
+
+func matchCmp(values []interface{}, op string, x interface{}) bool {
+	if isNull(x) && (op == operators.GTE || op == operators.LTE) {
+		return matchEq(values, x)
+	}
+
+	return anyValue(values, func(v interface{}) bool {
+		ov, ox := Order(v), Order(x)
+		if ov != ox && ox != orderMinKey && ox != orderMaxKey {
+			return false
+		}
+
+		c := Compare(v, x)
+
+		switch op {
+		case operators.GT:
+			return c > 0
+		case operators.GTE:
+			return c >= 0
+		case operators.LT:
+			return c < 0
+		}
+
+		return c <= 0
+	})
+}
+
+func matchIn(values []interface{}, op string, x interface{}) (bool, error) {
+	list, ok := x.(bson.A)
+	if !ok {
+		return false, fmt.Errorf("mongorm: %s requires an array", op)
+	}
+
+	for _, item := range list {
+		if re, ok := item.(primitive.Regex); ok {
+			matched, err := matchRegex(values, re)
+			if err != nil || matched {
+				return matched, err
+			}
+
+			continue
+		}
+
+		if matchEq(values, item) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func matchType(values []interface{}, x interface{}) (bool, error) {
+	types, ok := x.(bson.A)
+	if !ok {
+		types = bson.A{x}
+	}
+
+	for _, t := range types {
+		for _, v := range values {
+			if _, ok := v.(bson.A); ok && (t == "array" || t == int32(4) || t == int64(4) || t == 4.0) {
+				return true, nil
+			}
+		}
+
+		if alias, ok := t.(string); ok && alias == "number" {
+			if anyValue(values, func(v interface{}) bool { return Order(v) == orderNumber }) {
+				return true, nil
+			}
+
+			continue
+		}
+
+		code, ok := typeCode(t)
+		if !ok {
+			return false, fmt.Errorf("mongorm: unknown %s %v", operators.TYPE, t)
+		}
+
+		if anyValue(values, func(v interface{}) bool { return codeOf(v) == code }) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func typeCode(t interface{}) (int32, bool) {
+	if alias, ok := t.(string); ok {
+		code, ok := typeCodes[alias]
+		return code, ok
+	}
+
+	f, ok := ToFloat(t)
+
+	return int32(f), ok
+}
+
+func codeOf(v interface{}) int32 {
+	switch v.(type) {
+	case float64:
+		return 1
+	case string:
+		return 2
+	case bson.D:
+		return 3
+	case bson.A:
+		return 4
+	case primitive.Binary:
+		return 5
+	case primitive.Undefined:
+		return 6
+	case primitive.ObjectID:
+		return 7
+	case bool:
+		return 8
+	case primitive.DateTime:
+		return 9
+	case nil, primitive.Null:
+		return 10
+	case primitive.Regex:
+		return 11
+	case primitive.DBPointer:
+		return 12
+	case primitive.JavaScript:
+		return 13
+	case primitive.Symbol:
+		return 14
+	case primitive.CodeWithScope:
+		return 15
+	case int32:
+		return 16
+	case primitive.Timestamp:
+		return 17
+	case int64:
+		return 18
+	case primitive.Decimal128:
+		return 19
+	case primitive.MinKey:
+		return -1
+	case primitive.MaxKey:
+		return 127
+	}
+
+	return 0
+}
+
+func matchMod(values []interface{}, x interface{}) (bool, error) {
+	args, ok := x.(bson.A)
+	if !ok || len(args) != 2 {
+		return false, fmt.Errorf("mongorm: %s requires [divisor, remainder]", operators.MOD)
+	}
+
+	d, okD := ToFloat(args[0])
+	r, okR := ToFloat(args[1])
+	if !okD || !okR || int64(d) == 0 {
+		return false, fmt.Errorf("mongorm: %s requires a non-zero divisor and a remainder", operators.MOD)
+	}
+
+	return anyValue(values, func(v interface{}) bool {
+		f, ok := ToFloat(v)
+		if !ok || math.IsNaN(f) || math.IsInf(f, 0) {
+			return false
+		}
+
+		return int64(f)%int64(d) == int64(r)
+	}), nil
+}
+
+func regexOf(pattern interface{}, ops bson.D) (primitive.Regex, error) {
+	re := primitive.Regex{}
+
+	switch p := pattern.(type) {
+	case string:
+		re.Pattern = p
+	case primitive.Regex:
+		re = p
+	default:
+		return re, fmt.Errorf("mongorm: %s requires a string or a regular expression", operators.REGEX)
+	}
+
+	if opts, ok := Get(ops, operators.OPTIONS); ok {
+		s, ok := opts.(string)
+		if !ok {
+			return re, fmt.Errorf("mongorm: %s requires a string", operators.OPTIONS)
+		}
+
+		re.Options = s
+	}
+
+	return re, nil
+}
+
+func matchRegex(values []interface{}, re primitive.Regex) (bool, error) {
+	flags := ""
+	for _, opt := range re.Options {
+		switch opt {
+		case 'i', 'm', 's':
+			flags += string(opt)
+		default:
+			return false, fmt.Errorf("%w: regular expression option %q", ErrUnsupported, opt)
+		}
+	}
+
+	pattern := re.Pattern
+	if flags != "" {
+		pattern = "(?" + flags + ")" + pattern
+	}
+
+	compiled, err := regexp.Compile(pattern)
+	if err != nil {
+		return false, fmt.Errorf("%w: regular expression %q: %v", ErrUnsupported, re.Pattern, err)
+	}
+
+	return anyValue(values, func(v interface{}) bool {
+		switch s := v.(type) {
+		case string:
+			return compiled.MatchString(s)
+		case primitive.Symbol:
+			return compiled.MatchString(string(s))
+		case primitive.Regex:
+			return s == re
+		}
+
+		return false
+	}), nil
+}
+
+func matchSize(values []interface{}, x interface{}) (bool, error) {
+	n, ok := ToFloat(x)
+	if !ok || n < 0 || n != math.Trunc(n) {
+		return false, fmt.Errorf("mongorm: %s requires a non-negative integer", operators.SIZE)
+	}
+
+	for _, v := range values {
+		if arr, ok := v.(bson.A); ok && len(arr) == int(n) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func matchAll(values []interface{}, x interface{}) (bool, error) {
+	list, ok := x.(bson.A)
+	if !ok {
+		return false, fmt.Errorf("mongorm: %s requires an array", operators.ALL)
+	}
+
+	if len(list) == 0 {
+		return false, nil
+	}
+
+	for _, item := range list {
+		if doc, ok := item.(bson.D); ok && len(doc) == 1 && doc[0].Key == operators.ELEM_MATCH {
+			matched, err := matchElemMatch(values, doc[0].Value)
+			if err != nil || !matched {
+				return false, err
+			}
+
+			continue
+		}
+
+		if re, ok := item.(primitive.Regex); ok {
+			matched, err := matchRegex(values, re)
+			if err != nil || !matched {
+				return false, err
+			}
+
+			continue
+		}
+
+		if !matchEq(values, item) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+func matchElemMatch(values []interface{}, x interface{}) (bool, error) {
+	sub, ok := x.(bson.D)
+	if !ok {
+		return false, fmt.Errorf("mongorm: %s requires a document", operators.ELEM_MATCH)
+	}
+
+	for _, v := range values {
+		arr, ok := v.(bson.A)
+		if !ok {
+			continue
+		}
+
+		for _, elem := range arr {
+			matched, err := MatchElement(elem, sub)
+			if err != nil || matched {
+				return matched, err
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// MatchElement reports whether the array element elem matches cond the way $elemMatch and $pull see it:
+// {$gte: 1} applies to the element itself, while {a: 1} and {$or: [...]} apply to the element as a document.
+func MatchElement(elem interface{}, cond bson.D) (bool, error) {
+	if ops, ok := operatorDoc(cond); ok {
+		switch ops[0].Key {
+		case operators.AND, operators.OR, operators.NOR, operators.EXPR:
+		default:
+			return matchValue([]interface{}{elem}, cond)
+		}
+	}
+
+	doc, ok := elem.(bson.D)
+	if !ok {
+		return false, nil
+	}
+
+	return Match(cond, doc)
+}
+
+func matchBits(values []interface{}, op string, x interface{}) (bool, error) {
+	positions, err := bitPositions(x)
+	if err != nil {
+		return false, fmt.Errorf("mongorm: %s %w", op, err)
+	}
+
+	return anyValue(values, func(v interface{}) bool {
+		bit, ok := bitReader(v)
+		if !ok {
+			return false
+		}
+
+		switch op {
+		case operators.BITS_ALL_SET, operators.BITS_ALL_CLEAR:
+			want := op == operators.BITS_ALL_SET
+			for _, pos := range positions {
+				if bit(pos) != want {
+					return false
+				}
+			}
+			return true
+		}
+
+		want := op == operators.BITS_ANY_SET
+		for _, pos := range positions {
+			if bit(pos) == want {
+				return true
+			}
+		}
+
+		return false
+	}), nil
+}
+
+func bitPositions(x interface{}) ([]int, error) {
+	switch v := x.(type) {
+	case bson.A:
+		positions := make([]int, 0, len(v))
+		for _, p := range v {
+			f, ok := ToFloat(p)
+			if !ok || f < 0 {
+				return nil, errors.New("requires non-negative bit positions")
+			}
+			positions = append(positions, int(f))
+		}
+		return positions, nil
+	case primitive.Binary:
+		positions := make([]int, 0)
+		for i, b := range v.Data {
+			for j := 0; j < 8; j++ {
+				if b&(1<<j) != 0 {
+					positions = append(positions, i*8+j)
+				}
+			}
+		}
+		return positions, nil
+	}
+
+	f, ok := ToFloat(x)
+	if !ok || f < 0 || f != math.Trunc(f) {
+		return nil, errors.New("requires a non-negative integer bitmask")
+	}
+
+	positions := make([]int, 0)
+	for mask, i := uint64(f), 0; mask != 0; mask, i = mask>>1, i+1 {
+		if mask&1 != 0 {
+			positions = append(positions, i)
+		}
+	}
+
+	return positions, nil
+}
+
+// bitReader returns a function reporting whether the bit at a position of v is set.
+func bitReader(v interface{}) (func(pos int) bool, bool) {
+	if bin, ok := v.(primitive.Binary); ok {
+		return func(pos int) bool {
+			return pos/8 < len(bin.Data) && bin.Data[pos/8]&(1<<(pos%8)) != 0
+		}, true
+	}
+
+	f, ok := ToFloat(v)
+	if !ok || f != math.Trunc(f) || math.Abs(f) > math.MaxInt64 {
+		return nil, false
+	}
+
+	n := int64(f)
+	if i, ok := v.(int64); ok {
+		n = i
+	}
+
+	return func(pos int) bool {
+		if pos >= 64 {
+			return n < 0
+		}
+
+		return n&(1<<pos) != 0
+	}, true
+}
+
+func isNull(v interface{}) bool {
+	switch v.(type) {
+	case nil, primitive.Null, primitive.Undefined:
+		return true
+	}
+
+	return false
+}
+
+// Truthy reports whether v is true in the sense of aggregation expressions:
+// false, null, missing and zero are false, everything else is true.
+func Truthy(v interface{}) bool {
+	if isNull(v) {
+		return false
+	}
+
+	if b, ok := v.(bool); ok {
+		return b
+	}
+
+	if f, ok := ToFloat(v); ok {
+		return f != 0
+	}
+
+	return true
+}
@@ -0,0 +1,185 @@
+package matcher
+
+import (
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func extJSON(t *testing.T, s string) bson.D {
+	t.Helper()
+
+	doc := bson.D{}
+	if err := bson.UnmarshalExtJSON([]byte(s), false, &doc); err != nil {
+		t.Fatalf("err parse %s: %v", s, err)
+	}
+
+	return doc
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter string
+		doc    string
+		want   bool
+	}{
+		// $ne and $nin on arrays and missing fields
+		{"ne array with value", `{"a": {"$ne": 1}}`, `{"a": [1, 2]}`, false},
+		{"ne array without value", `{"a": {"$ne": 1}}`, `{"a": [2, 3]}`, true},
+		{"ne missing", `{"a": {"$ne": 1}}`, `{}`, true},
+		{"ne null field", `{"a": {"$ne": 1}}`, `{"a": null}`, true},
+		{"ne whole array", `{"a": {"$ne": [1, 2]}}`, `{"a": [1, 2]}`, false},
+		{"nin array with value", `{"a": {"$nin": [1, 5]}}`, `{"a": [1, 2]}`, false},
+		{"nin array without value", `{"a": {"$nin": [1, 5]}}`, `{"a": [2, 3]}`, true},
+		{"nin missing", `{"a": {"$nin": [1, 5]}}`, `{}`, true},
+		{"nin null matches missing", `{"a": {"$nin": [null]}}`, `{}`, false},
+		{"nin embedded missing", `{"a.b": {"$nin": [1]}}`, `{"a": [{"c": 1}]}`, true},
+
+		// null and missing
+		{"null matches missing", `{"a": null}`, `{}`, true},
+		{"null matches null", `{"a": null}`, `{"a": null}`, true},
+		{"null does not match value", `{"a": null}`, `{"a": 0}`, false},
+		{"null matches array with null", `{"a": null}`, `{"a": [1, null]}`, true},
+		{"null matches element without field", `{"a.b": null}`, `{"a": [{"b": 1}, {"c": 2}]}`, true},
+		{"null with every element set", `{"a.b": null}`, `{"a": [{"b": 1}, {"b": 2}]}`, false},
+		{"in null element without field", `{"a.b": {"$in": [null]}}`, `{"a": [{"b": 1}, {"c": 2}]}`, true},
+		{"ne null element without field", `{"a.b": {"$ne": null}}`, `{"a": [{"b": 1}, {"c": 2}]}`, false},
+		{"exists no element with field", `{"a.b": {"$exists": true}}`, `{"a": [{"c": 1}]}`, false},
+		{"not exists element with field", `{"a.b": {"$exists": false}}`, `{"a": [{"c": 1}, {"b": 2}]}`, false},
+		{"type null element without field", `{"a.b": {"$type": "null"}}`, `{"a": [{"c": 1}, {"b": 2}]}`, false},
+		{"ne null missing", `{"a": {"$ne": null}}`, `{}`, false},
+		{"ne null null", `{"a": {"$ne": null}}`, `{"a": null}`, false},
+		{"ne null value", `{"a": {"$ne": null}}`, `{"a": 1}`, true},
+		{"in null matches missing", `{"a": {"$in": [null, 1]}}`, `{}`, true},
+		{"exists null", `{"a": {"$exists": true}}`, `{"a": null}`, true},
+		{"exists missing", `{"a": {"$exists": true}}`, `{}`, false},
+		{"not exists missing", `{"a": {"$exists": false}}`, `{}`, true},
+		{"not exists null", `{"a": {"$exists": false}}`, `{"a": null}`, false},
+		{"type null null", `{"a": {"$type": "null"}}`, `{"a": null}`, true},
+		{"type null missing", `{"a": {"$type": "null"}}`, `{}`, false},
+
+		// comparisons only match values of the same type bracket
+		{"gt number string", `{"a": {"$gt": 1}}`, `{"a": "2"}`, false},
+		{"lt string number", `{"a": {"$lt": "a"}}`, `{"a": 1}`, false},
+		{"gt number null", `{"a": {"$gt": 0}}`, `{"a": null}`, false},
+		{"lte null missing", `{"a": {"$lte": null}}`, `{}`, true},
+		{"gte int double", `{"a": {"$gte": 2}}`, `{"a": 2.5}`, true},
+		{"eq long double", `{"a": {"$numberLong": "2"}}`, `{"a": 2.0}`, true},
+		{"lt int decimal", `{"a": {"$lt": 3}}`, `{"a": {"$numberDecimal": "2.5"}}`, true},
+		{"gt date string", `{"a": {"$gt": {"$date": "2020-01-01T00:00:00Z"}}}`, `{"a": "2021"}`, false},
+		{"gt dates", `{"a": {"$gt": {"$date": "2020-01-01T00:00:00Z"}}}`, `{"a": {"$date": "2021-01-01T00:00:00Z"}}`, true},
+		{"gt bool", `{"a": {"$gt": false}}`, `{"a": true}`, true},
+		{"gt array elements", `{"a": {"$gt": 5}}`, `{"a": ["9", 6]}`, true},
+
+		// array paths
+		{"array element equal", `{"a": 2}`, `{"a": [1, 2]}`, true},
+		{"array whole equal", `{"a": [1, 2]}`, `{"a": [1, 2]}`, true},
+		{"array order matters", `{"a": [2, 1]}`, `{"a": [1, 2]}`, false},
+		{"nested array element", `{"a": [1, 2]}`, `{"a": [[1, 2], 3]}`, true},
+		{"embedded in array", `{"a.b": 2}`, `{"a": [{"b": 1}, {"b": 2}]}`, true},
+		{"array index", `{"a.0": 1}`, `{"a": [1, 2]}`, true},
+		{"array index out of range", `{"a.5": 1}`, `{"a": [1, 2]}`, false},
+		{"array index embedded", `{"a.1.b": 2}`, `{"a": [{"b": 1}, {"b": 2}]}`, true},
+		{"numeric key of document", `{"a.0": 1}`, `{"a": {"0": 1}}`, true},
+		{"range across elements", `{"a": {"$gt": 1, "$lt": 3}}`, `{"a": [0, 5]}`, true},
+		{"elemMatch one element", `{"a": {"$elemMatch": {"$gt": 1, "$lt": 3}}}`, `{"a": [0, 5]}`, false},
+		{"elemMatch documents", `{"a": {"$elemMatch": {"b": 1, "c": 2}}}`, `{"a": [{"b": 1}, {"b": 2, "c": 2}]}`, false},
+		{"size", `{"a": {"$size": 2}}`, `{"a": [1, [2, 3]]}`, true},
+		{"all", `{"a": {"$all": [1, 3]}}`, `{"a": [1, 2, 3]}`, true},
+		{"all missing element", `{"a": {"$all": [1, 4]}}`, `{"a": [1, 2, 3]}`, false},
+		{"all empty", `{"a": {"$all": []}}`, `{"a": [1]}`, false},
+
+		// regexes and $not
+		{"regex in array", `{"a": {"$regex": "^x"}}`, `{"a": ["y", "xy"]}`, true},
+		{"regex in list", `{"a": {"$in": [{"$regularExpression": {"pattern": "^x", "options": ""}}]}}`, `{"a": "xy"}`, true},
+		{"regex nin list", `{"a": {"$nin": [{"$regularExpression": {"pattern": "^x", "options": ""}}]}}`, `{"a": "xy"}`, false},
+		{"regex options", `{"a": {"$regex": "^X", "$options": "i"}}`, `{"a": "xy"}`, true},
+		{"regex number", `{"a": {"$regex": "1"}}`, `{"a": 1}`, false},
+		{"not operators", `{"a": {"$not": {"$gt": 5}}}`, `{"a": 3}`, true},
+		{"not missing", `{"a": {"$not": {"$gt": 5}}}`, `{}`, true},
+		{"not array", `{"a": {"$not": {"$gt": 5}}}`, `{"a": [3, 6]}`, false},
+		{"not regex", `{"a": {"$not": {"$regularExpression": {"pattern": "^x", "options": ""}}}}`, `{"a": "xy"}`, false},
+
+		// logical operators
+		{"or", `{"$or": [{"a": 1}, {"b": 1}]}`, `{"b": 1}`, true},
+		{"and", `{"$and": [{"a": 1}, {"b": 1}]}`, `{"b": 1}`, false},
+		{"nor", `{"$nor": [{"a": 1}, {"b": 1}]}`, `{"c": 1}`, true},
+		{"expr", `{"$expr": {"$gt": ["$a", "$b"]}}`, `{"a": 2, "b": 1}`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Match(extJSON(t, tt.filter), extJSON(t, tt.doc))
+			if err != nil {
+				t.Fatalf("Match(%s, %s): %v", tt.filter, tt.doc, err)
+			}
+
+			if got != tt.want {
+				t.Errorf("Match(%s, %s) = %v, want %v", tt.filter, tt.doc, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		filter      string
+		unsupported bool
+	}{
+		{"text", `{"$text": {"$search": "x"}}`, true},
+		{"geo", `{"a": {"$near": {"$geometry": {"type": "Point", "coordinates": [0, 0]}}}}`, true},
+		{"empty or", `{"$or": []}`, false},
+		{"in without array", `{"a": {"$in": 1}}`, false},
+		{"options without regex", `{"a": {"$options": "i"}}`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Match(extJSON(t, tt.filter), extJSON(t, `{"a": 1}`))
+			if err == nil {
+				t.Fatalf("Match(%s): want error", tt.filter)
+			}
+
+			if errors.Is(err, ErrUnsupported) != tt.unsupported {
+				t.Errorf("Match(%s): error %v, unsupported %v", tt.filter, err, tt.unsupported)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want int
+	}{
+		{"null before numbers", `{"v": null}`, `{"v": 0}`, -1},
+		{"numbers before strings", `{"v": 100}`, `{"v": "1"}`, -1},
+		{"strings before documents", `{"v": "z"}`, `{"v": {}}`, -1},
+		{"documents before arrays", `{"v": {"a": 1}}`, `{"v": [1]}`, -1},
+		{"objectIds before bools", `{"v": {"$oid": "000000000000000000000000"}}`, `{"v": false}`, -1},
+		{"bools before dates", `{"v": true}`, `{"v": {"$date": "1970-01-01T00:00:00Z"}}`, -1},
+		{"int long equal", `{"v": 1}`, `{"v": {"$numberLong": "1"}}`, 0},
+		{"int double", `{"v": 1}`, `{"v": 1.5}`, -1},
+		{"strings", `{"v": "b"}`, `{"v": "a"}`, 1},
+		{"documents by field", `{"v": {"a": 1}}`, `{"v": {"a": 2}}`, -1},
+		{"documents by key", `{"v": {"a": 1}}`, `{"v": {"b": 0}}`, -1},
+		{"arrays", `{"v": [1, 2]}`, `{"v": [1]}`, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := extJSON(t, tt.a)[0].Value, extJSON(t, tt.b)[0].Value
+			if got := Compare(a, b); got != tt.want {
+				t.Errorf("Compare(%s, %s) = %d, want %d", tt.a, tt.b, got, tt.want)
+			}
+
+			if got := Compare(b, a); got != -tt.want {
+				t.Errorf("Compare(%s, %s) = %d, want %d", tt.b, tt.a, got, -tt.want)
+			}
+		})
+	}
+}
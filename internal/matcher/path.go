@@ -0,0 +1,117 @@
+package matcher
+
+import (
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Lookup returns the values found at the dotted path parts in v the way query filters see them:
+// arrays met on the way are traversed, so "a.b" on an array of documents yields b of every
+// element, and numeric parts also index into arrays. Missing values are not returned.
+func Lookup(v interface{}, parts []string) []interface{} {
+	if len(parts) == 0 {
+		return []interface{}{v}
+	}
+
+	switch cur := v.(type) {
+	case bson.D:
+		val, ok := Get(cur, parts[0])
+		if !ok {
+			return nil
+		}
+
+		return Lookup(val, parts[1:])
+	case bson.A:
+		var out []interface{}
+
+		if idx, err := strconv.Atoi(parts[0]); err == nil && idx >= 0 && idx < len(cur) {
+			out = append(out, Lookup(cur[idx], parts[1:])...)
+		}
+
+		for _, elem := range cur {
+			if doc, ok := elem.(bson.D); ok {
+				out = append(out, Lookup(doc, parts)...)
+			}
+		}
+
+		return out
+	}
+
+	return nil
+}
+
+// missing stands for the values a path lacks in some of the array elements it goes through,
+// which only match equality to null, e.g. {"a.b": null} matches {a: [{b: 1}, {c: 2}]}.
+type missing struct{}
+
+// lacks reports whether a document in an array met on the way to the dotted path parts in v
+// does not have the rest of the path.
+func lacks(v interface{}, parts []string) bool {
+	if len(parts) == 0 {
+		return false
+	}
+
+	switch cur := v.(type) {
+	case bson.D:
+		val, ok := Get(cur, parts[0])
+		return ok && lacks(val, parts[1:])
+	case bson.A:
+		if idx, err := strconv.Atoi(parts[0]); err == nil && idx >= 0 && idx < len(cur) {
+			if lacks(cur[idx], parts[1:]) {
+				return true
+			}
+		}
+
+		for _, elem := range cur {
+			if doc, ok := elem.(bson.D); ok && (len(Lookup(doc, parts)) == 0 || lacks(doc, parts)) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Resolve returns the value of the dotted path parts in v the way aggregation expressions
+// see it: a path through an array of documents yields the array of the values found in its
+// elements. ok is false when the value is missing.
+func Resolve(v interface{}, parts []string) (interface{}, bool) {
+	if len(parts) == 0 {
+		return v, true
+	}
+
+	switch cur := v.(type) {
+	case bson.D:
+		val, ok := Get(cur, parts[0])
+		if !ok {
+			return nil, false
+		}
+
+		return Resolve(val, parts[1:])
+	case bson.A:
+		out := make(bson.A, 0, len(cur))
+		for _, elem := range cur {
+			if doc, ok := elem.(bson.D); ok {
+				if val, ok := Resolve(doc, parts); ok {
+					out = append(out, val)
+				}
+			}
+		}
+
+		return out, true
+	}
+
+	return nil, false
+}
+
+// Get returns the value of the top-level field key of doc.
+func Get(doc bson.D, key string) (interface{}, bool) {
+	for _, elem := range doc {
+		if elem.Key == key {
+			return elem.Value, true
+		}
+	}
+
+	return nil, false
+}
@@ -0,0 +1,101 @@
+package memstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	mongo_options "go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/v1shn3vsk7/mongorm/internal/matcher"
+)
+
+// BulkWrite runs the insert, update and delete models in order. An ordered bulk write stops at
+// the first error; an unordered one reports the errors of all models.
+func (c *Collection) BulkWrite(ctx context.Context, models []mongo.WriteModel, opts ...*mongo_options.BulkWriteOptions) (*mongo.BulkWriteResult, error) {
+	if len(models) == 0 {
+		return nil, mongo.ErrEmptySlice
+	}
+
+	opt := mongo_options.MergeBulkWriteOptions(opts...)
+	ordered := opt.Ordered == nil || *opt.Ordered
+
+	res := &mongo.BulkWriteResult{UpsertedIDs: make(map[int64]interface{})}
+	var exception mongo.BulkWriteException
+
+	for i, model := range models {
+		err := c.write(ctx, res, int64(i), model)
+		if err == nil {
+			continue
+		}
+
+		var we mongo.WriteException
+		if !errors.As(err, &we) || len(we.WriteErrors) == 0 {
+			return res, err
+		}
+
+		writeErr := we.WriteErrors[0]
+		writeErr.Index = i
+		exception.WriteErrors = append(exception.WriteErrors, mongo.BulkWriteError{WriteError: writeErr, Request: model})
+
+		if ordered {
+			break
+		}
+	}
+
+	if len(exception.WriteErrors) > 0 {
+		return res, exception
+	}
+
+	return res, nil
+}
+
+// write runs the model at index i of a bulk write and adds its outcome to res.
+func (c *Collection) write(ctx context.Context, res *mongo.BulkWriteResult, i int64, model mongo.WriteModel) error {
+	var (
+		updated *mongo.UpdateResult
+		deleted *mongo.DeleteResult
+		err     error
+	)
+
+	switch m := model.(type) {
+	case *mongo.InsertOneModel:
+		if _, err = c.InsertOne(ctx, m.Document); err == nil {
+			res.InsertedCount++
+		}
+	case *mongo.UpdateOneModel:
+		updated, err = c.UpdateOne(ctx, m.Filter, m.Update, updateOptions(m.Upsert, m.ArrayFilters))
+	case *mongo.UpdateManyModel:
+		updated, err = c.UpdateMany(ctx, m.Filter, m.Update, updateOptions(m.Upsert, m.ArrayFilters))
+	case *mongo.DeleteOneModel:
+		deleted, err = c.DeleteOne(ctx, m.Filter)
+	case *mongo.DeleteManyModel:
+		deleted, err = c.DeleteMany(ctx, m.Filter)
+	default:
+		return fmt.Errorf("%w: bulk write model %T", matcher.ErrUnsupported, model)
+	}
+
+	if updated != nil {
+		res.MatchedCount += updated.MatchedCount
+		res.ModifiedCount += updated.ModifiedCount
+		res.UpsertedCount += updated.UpsertedCount
+		if updated.UpsertedID != nil {
+			res.UpsertedIDs[i] = updated.UpsertedID
+		}
+	}
+
+	if deleted != nil {
+		res.DeletedCount += deleted.DeletedCount
+	}
+
+	return err
+}
+
+func updateOptions(upsert *bool, arrayFilters *mongo_options.ArrayFilters) *mongo_options.UpdateOptions {
+	opts := mongo_options.Update()
+	opts.Upsert = upsert
+	opts.ArrayFilters = arrayFilters
+
+	return opts
+}
@@ -0,0 +1,443 @@
+package memstore
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mongo_options "go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/v1shn3vsk7/mongorm/internal/matcher"
+	"github.com/v1shn3vsk7/mongorm/internal/operators"
+)
+
+func (c *Collection) Find(ctx context.Context, filter interface{}, opts ...*mongo_options.FindOptions) (*mongo.Cursor, error) {
+	docs, err := c.find(ctx, filter, mongo_options.MergeFindOptions(opts...))
+	if err != nil {
+		return nil, err
+	}
+
+	return cursorOf(docs)
+}
+
+func (c *Collection) FindOne(ctx context.Context, filter interface{}, opts ...*mongo_options.FindOneOptions) *mongo.SingleResult {
+	opt := mongo_options.MergeFindOneOptions(opts...)
+
+	docs, err := c.find(ctx, filter, &mongo_options.FindOptions{
+		Projection: opt.Projection,
+		Skip:       opt.Skip,
+		Sort:       opt.Sort,
+		Limit:      intPtr(1),
+	})
+
+	return singleResult(docs, err)
+}
+
+func (c *Collection) CountDocuments(ctx context.Context, filter interface{}, opts ...*mongo_options.CountOptions) (int64, error) {
+	opt := mongo_options.MergeCountOptions(opts...)
+
+	docs, err := c.find(ctx, filter, &mongo_options.FindOptions{Skip: opt.Skip, Limit: opt.Limit})
+
+	return int64(len(docs)), err
+}
+
+func (c *Collection) Distinct(ctx context.Context, fieldName string, filter interface{}, _ ...*mongo_options.DistinctOptions) ([]interface{}, error) {
+	docs, err := c.find(ctx, filter, &mongo_options.FindOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]interface{}, 0)
+	add := func(v interface{}) {
+		for _, seen := range values {
+			if matcher.Equal(seen, v) {
+				return
+			}
+		}
+		values = append(values, v)
+	}
+
+	for _, doc := range docs {
+		for _, v := range matcher.Lookup(doc, strings.Split(fieldName, ".")) {
+			if arr, ok := v.(bson.A); ok {
+				for _, elem := range arr {
+					add(elem)
+				}
+				continue
+			}
+			add(v)
+		}
+	}
+
+	return values, nil
+}
+
+// Aggregate runs the pipeline stages that only filter, order and count documents:
+// $match, $sort, $skip, $limit, $project with inclusions or exclusions and $count,
+// and $lookup with localField and foreignField.
+func (c *Collection) Aggregate(ctx context.Context, pipeline interface{}, _ ...*mongo_options.AggregateOptions) (*mongo.Cursor, error) {
+	stages, ok := pipeline.(mongo.Pipeline)
+	if !ok {
+		if ds, isList := pipeline.([]bson.D); isList {
+			stages = ds
+		} else {
+			return nil, fmt.Errorf("%w: pipeline of type %T", matcher.ErrUnsupported, pipeline)
+		}
+	}
+
+	docs, err := c.find(ctx, nil, &mongo_options.FindOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, stage := range stages {
+		stage, err := normalize(stage)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(stage) != 1 {
+			return nil, fmt.Errorf("mongorm: pipeline stage must have exactly one field, got %d", len(stage))
+		}
+
+		if docs, err = c.applyStage(ctx, docs, stage[0]); err != nil {
+			return nil, err
+		}
+	}
+
+	return cursorOf(docs)
+}
+
+func (c *Collection) applyStage(ctx context.Context, docs []bson.D, stage bson.E) ([]bson.D, error) {
+	switch stage.Key {
+	case operators.MATCH:
+		filter, ok := stage.Value.(bson.D)
+		if !ok {
+			return nil, fmt.Errorf("mongorm: %s requires a document", stage.Key)
+		}
+
+		out := make([]bson.D, 0, len(docs))
+		for _, doc := range docs {
+			matched, err := matcher.Match(filter, doc)
+			if err != nil {
+				return nil, err
+			}
+			if matched {
+				out = append(out, doc)
+			}
+		}
+
+		return out, nil
+	case operators.SORT:
+		return docs, sortDocs(docs, stage.Value)
+	case operators.SKIP, operators.LIMIT:
+		n, ok := matcher.ToFloat(stage.Value)
+		if !ok || n < 0 {
+			return nil, fmt.Errorf("mongorm: %s requires a non-negative number", stage.Key)
+		}
+
+		if stage.Key == operators.SKIP {
+			return docs[min(int(n), len(docs)):], nil
+		}
+
+		return docs[:min(int(n), len(docs))], nil
+	case operators.PROJECT:
+		return projectDocs(docs, stage.Value)
+	case operators.COUNT:
+		field, ok := stage.Value.(string)
+		if !ok || field == "" {
+			return nil, fmt.Errorf("mongorm: %s requires a field name", stage.Key)
+		}
+
+		if len(docs) == 0 {
+			return nil, nil
+		}
+
+		return []bson.D{{{Key: field, Value: int32(len(docs))}}}, nil
+	case operators.LOOKUP:
+		return c.lookup(ctx, docs, stage.Value)
+	}
+
+	return nil, fmt.Errorf("%w: stage %s", matcher.ErrUnsupported, stage.Key)
+}
+
+// find returns copies of the documents matched by filter after applying the sort, skip, limit and projection of opt.
+func (c *Collection) find(ctx context.Context, filter interface{}, opt *mongo_options.FindOptions) ([]bson.D, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+
+	f, err := normalize(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	docs, _, err := c.filter(f)
+	c.mu.RUnlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if opt.Sort != nil {
+		if err := sortDocs(docs, opt.Sort); err != nil {
+			return nil, err
+		}
+	}
+
+	if opt.Skip != nil && *opt.Skip > 0 {
+		docs = docs[min(int(*opt.Skip), len(docs)):]
+	}
+
+	if opt.Limit != nil && *opt.Limit != 0 {
+		limit := *opt.Limit
+		if limit < 0 {
+			limit = -limit
+		}
+
+		docs = docs[:min(int(limit), len(docs))]
+	}
+
+	out := make([]bson.D, 0, len(docs))
+	for _, doc := range docs {
+		out = append(out, clone(doc))
+	}
+
+	if opt.Projection != nil {
+		return projectDocs(out, opt.Projection)
+	}
+
+	return out, nil
+}
+
+// sortDocs sorts docs by spec, a document of field: 1 or -1. As on a server, an array sorts
+// by its smallest element in ascending order and by its largest element in descending order.
+func sortDocs(docs []bson.D, spec interface{}) error {
+	keys, err := normalize(spec)
+	if err != nil {
+		return err
+	}
+
+	dirs := make([]int, 0, len(keys))
+	for _, key := range keys {
+		dir, ok := matcher.ToFloat(key.Value)
+		if !ok || (dir != 1 && dir != -1) {
+			return fmt.Errorf("%w: sort %q: %v", matcher.ErrUnsupported, key.Key, key.Value)
+		}
+		dirs = append(dirs, int(dir))
+	}
+
+	sortKey := func(doc bson.D, i int) interface{} {
+		var values []interface{}
+		for _, v := range matcher.Lookup(doc, strings.Split(keys[i].Key, ".")) {
+			if arr, ok := v.(bson.A); ok {
+				values = append(values, arr...)
+				continue
+			}
+			values = append(values, v)
+		}
+
+		if len(values) == 0 {
+			return nil
+		}
+
+		key := values[0]
+		for _, v := range values[1:] {
+			if matcher.Compare(v, key)*dirs[i] < 0 {
+				key = v
+			}
+		}
+
+		return key
+	}
+
+	sort.SliceStable(docs, func(a, b int) bool {
+		for i := range keys {
+			if c := matcher.Compare(sortKey(docs[a], i), sortKey(docs[b], i)); c != 0 {
+				return c*dirs[i] < 0
+			}
+		}
+
+		return false
+	})
+
+	return nil
+}
+
+// projectDocs applies a projection of inclusions or exclusions to docs.
+func projectDocs(docs []bson.D, projection interface{}) ([]bson.D, error) {
+	spec, err := normalize(projection)
+	if err != nil {
+		return nil, err
+	}
+
+	keepID, listID := true, false
+	var includes, excludes [][]string
+
+	for _, elem := range spec {
+		switch elem.Value.(type) {
+		case bool, int32, int64, float64:
+		default:
+			return nil, fmt.Errorf("%w: projection %q: %v", matcher.ErrUnsupported, elem.Key, elem.Value)
+		}
+
+		path := strings.Split(elem.Key, ".")
+		switch {
+		case elem.Key == "_id":
+			keepID, listID = matcher.Truthy(elem.Value), true
+		case matcher.Truthy(elem.Value):
+			includes = append(includes, path)
+		default:
+			excludes = append(excludes, path)
+		}
+	}
+
+	if len(includes) > 0 && len(excludes) > 0 {
+		return nil, fmt.Errorf("mongorm: projection cannot mix inclusion and exclusion")
+	}
+
+	// {_id: 1} alone is an inclusion of the _id only
+	inclusion := len(includes) > 0 || (listID && keepID && len(excludes) == 0)
+	if !inclusion && !keepID {
+		excludes = append(excludes, []string{"_id"})
+	}
+
+	out := make([]bson.D, 0, len(docs))
+	for _, doc := range docs {
+		id, hasID := matcher.Get(doc, "_id")
+
+		if inclusion {
+			doc = include(doc, includes)
+			if keepID && hasID {
+				doc = append(bson.D{{Key: "_id", Value: id}}, doc...)
+			}
+		} else {
+			doc = exclude(doc, excludes)
+		}
+
+		out = append(out, doc)
+	}
+
+	return out, nil
+}
+
+// include keeps the fields of doc found at paths, descending into documents and arrays of documents.
+func include(doc bson.D, paths [][]string) bson.D {
+	out := bson.D{}
+
+	for _, elem := range doc {
+		if elem.Key == "_id" {
+			continue
+		}
+
+		whole, rest := splitPaths(elem.Key, paths)
+		switch {
+		case whole:
+			out = append(out, elem)
+		case len(rest) > 0:
+			if v, ok := includeValue(elem.Value, rest); ok {
+				out = append(out, bson.E{Key: elem.Key, Value: v})
+			}
+		}
+	}
+
+	return out
+}
+
+func includeValue(v interface{}, paths [][]string) (interface{}, bool) {
+	switch val := v.(type) {
+	case bson.D:
+		return include(val, paths), true
+	case bson.A:
+		out := bson.A{}
+		for _, elem := range val {
+			if v, ok := includeValue(elem, paths); ok {
+				out = append(out, v)
+			}
+		}
+		return out, true
+	}
+
+	return nil, false
+}
+
+// exclude removes the fields of doc found at paths, descending into documents and arrays of documents.
+func exclude(doc bson.D, paths [][]string) bson.D {
+	out := bson.D{}
+
+	for _, elem := range doc {
+		whole, rest := splitPaths(elem.Key, paths)
+		switch {
+		case whole:
+			continue
+		case len(rest) > 0:
+			elem.Value = excludeValue(elem.Value, rest)
+		}
+
+		out = append(out, elem)
+	}
+
+	return out
+}
+
+func excludeValue(v interface{}, paths [][]string) interface{} {
+	switch val := v.(type) {
+	case bson.D:
+		return exclude(val, paths)
+	case bson.A:
+		out := make(bson.A, 0, len(val))
+		for _, elem := range val {
+			out = append(out, excludeValue(elem, paths))
+		}
+		return out
+	}
+
+	return v
+}
+
+// splitPaths reports whether one of paths is exactly key and returns the remainders of the paths below key.
+func splitPaths(key string, paths [][]string) (bool, [][]string) {
+	var rest [][]string
+
+	for _, path := range paths {
+		if path[0] != key {
+			continue
+		}
+
+		if len(path) == 1 {
+			return true, nil
+		}
+
+		rest = append(rest, path[1:])
+	}
+
+	return false, rest
+}
+
+func cursorOf(docs []bson.D) (*mongo.Cursor, error) {
+	items := make([]interface{}, 0, len(docs))
+	for _, doc := range docs {
+		items = append(items, doc)
+	}
+
+	return mongo.NewCursorFromDocuments(items, nil, nil)
+}
+
+func singleResult(docs []bson.D, err error) *mongo.SingleResult {
+	switch {
+	case err != nil:
+		return mongo.NewSingleResultFromDocument(bson.D{}, err, nil)
+	case len(docs) == 0:
+		return mongo.NewSingleResultFromDocument(bson.D{}, mongo.ErrNoDocuments, nil)
+	}
+
+	return mongo.NewSingleResultFromDocument(docs[0], nil, nil)
+}
+
+func intPtr(n int64) *int64 {
+	return &n
+}
@@ -0,0 +1,337 @@
+package memstore
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mongo_options "go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/v1shn3vsk7/mongorm/internal/matcher"
+)
+
+// duplicateKeyCode is the server error code of unique index violations, recognized by mongo.IsDuplicateKeyError.
+const duplicateKeyCode = 11000
+
+type index struct {
+	name    string
+	keys    bson.D
+	unique  bool
+	sparse  bool
+	partial bson.D
+
+	// doc describes the index the way listIndexes does.
+	doc bson.D
+}
+
+// idIndex returns the index every collection has on _id. It is unique even though, as on the
+// server, its description does not say so.
+func idIndex() index {
+	keys := bson.D{{Key: "_id", Value: int32(1)}}
+
+	return index{
+		name:   "_id_",
+		keys:   keys,
+		unique: true,
+		doc:    bson.D{{Key: "v", Value: int32(2)}, {Key: "key", Value: keys}, {Key: "name", Value: "_id_"}},
+	}
+}
+
+// CreateIndexes creates the indexes of models and returns their names. Only unique indexes
+// have an effect on an in-memory collection; the others are recorded and listed.
+func (c *Collection) CreateIndexes(ctx context.Context, models []mongo.IndexModel) ([]string, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	names := make([]string, 0, len(models))
+	for _, model := range models {
+		idx, err := indexOf(model)
+		if err != nil {
+			return nil, err
+		}
+
+		if existing, ok := c.index(idx.name); ok {
+			if !sameIndex(existing, idx) {
+				return nil, mongo.CommandError{
+					Code:    85,
+					Name:    "IndexOptionsConflict",
+					Message: fmt.Sprintf("an index named %s already exists with different options", idx.name),
+				}
+			}
+
+			names = append(names, idx.name)
+			continue
+		}
+
+		for i, doc := range c.docs {
+			if dup, ok := c.duplicate(idx, doc, c.docs[i+1:]); ok {
+				return nil, mongo.CommandError{
+					Code:    duplicateKeyCode,
+					Name:    "DuplicateKey",
+					Message: c.duplicateMessage(idx, dup),
+				}
+			}
+		}
+
+		c.indexes = append(c.indexes, idx)
+		names = append(names, idx.name)
+	}
+
+	return names, nil
+}
+
+func indexOf(model mongo.IndexModel) (index, error) {
+	keys, err := normalize(model.Keys)
+	if err != nil {
+		return index{}, err
+	}
+
+	if len(keys) == 0 {
+		return index{}, fmt.Errorf("mongorm: index keys cannot be empty")
+	}
+
+	idx := index{keys: keys}
+	var options bson.D
+
+	if opts := model.Options; opts != nil {
+		if opts.Name != nil {
+			idx.name = *opts.Name
+		}
+
+		idx.unique = opts.Unique != nil && *opts.Unique
+		idx.sparse = opts.Sparse != nil && *opts.Sparse
+
+		if opts.PartialFilterExpression != nil {
+			if idx.partial, err = normalize(opts.PartialFilterExpression); err != nil {
+				return index{}, err
+			}
+		}
+
+		if options, err = optionsDoc(opts); err != nil {
+			return index{}, err
+		}
+	}
+
+	if idx.name == "" {
+		parts := make([]string, 0, len(keys))
+		for _, key := range keys {
+			parts = append(parts, fmt.Sprintf("%s_%v", key.Key, key.Value))
+		}
+		idx.name = strings.Join(parts, "_")
+	}
+
+	idx.doc = append(bson.D{{Key: "v", Value: int32(2)}, {Key: "key", Value: keys}, {Key: "name", Value: idx.name}}, options...)
+
+	return idx, nil
+}
+
+// optionsDoc renders the index options the way the server reports them.
+func optionsDoc(opts *mongo_options.IndexOptions) (bson.D, error) {
+	doc := bson.D{}
+
+	if opts.Unique != nil {
+		doc = append(doc, bson.E{Key: "unique", Value: *opts.Unique})
+	}
+
+	if opts.Sparse != nil {
+		doc = append(doc, bson.E{Key: "sparse", Value: *opts.Sparse})
+	}
+
+	if opts.ExpireAfterSeconds != nil {
+		doc = append(doc, bson.E{Key: "expireAfterSeconds", Value: *opts.ExpireAfterSeconds})
+	}
+
+	if opts.DefaultLanguage != nil {
+		doc = append(doc, bson.E{Key: "default_language", Value: *opts.DefaultLanguage})
+	}
+
+	if opts.Hidden != nil {
+		doc = append(doc, bson.E{Key: "hidden", Value: *opts.Hidden})
+	}
+
+	var collation interface{}
+	if opts.Collation != nil {
+		collation = opts.Collation.ToDocument()
+	}
+
+	for _, opt := range []bson.E{
+		{Key: "partialFilterExpression", Value: opts.PartialFilterExpression},
+		{Key: "weights", Value: opts.Weights},
+		{Key: "collation", Value: collation},
+		{Key: "wildcardProjection", Value: opts.WildcardProjection},
+	} {
+		if opt.Value == nil {
+			continue
+		}
+
+		v, err := normalize(opt.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		doc = append(doc, bson.E{Key: opt.Key, Value: v})
+	}
+
+	return doc, nil
+}
+
+func sameIndex(a, b index) bool {
+	return matcher.Equal(a.doc, b.doc)
+}
+
+// ListIndexes returns the descriptions of the indexes of the collection in the format of listIndexes.
+func (c *Collection) ListIndexes(ctx context.Context) ([]bson.D, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	docs := make([]bson.D, 0, len(c.indexes))
+	for _, idx := range c.indexes {
+		docs = append(docs, clone(idx.doc))
+	}
+
+	return docs, nil
+}
+
+// DropIndex drops the index called name.
+func (c *Collection) DropIndex(ctx context.Context, name string) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+
+	if name == "_id_" {
+		return mongo.CommandError{Code: 72, Name: "InvalidOptions", Message: "cannot drop _id index"}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, idx := range c.indexes {
+		if idx.name == name {
+			c.indexes = append(c.indexes[:i:i], c.indexes[i+1:]...)
+			return nil
+		}
+	}
+
+	return mongo.CommandError{Code: 27, Name: "IndexNotFound", Message: fmt.Sprintf("index not found with name [%s]", name)}
+}
+
+// index returns the index called name. The caller must hold the lock.
+func (c *Collection) index(name string) (index, bool) {
+	for _, idx := range c.indexes {
+		if idx.name == name {
+			return idx, true
+		}
+	}
+
+	return index{}, false
+}
+
+// checkUnique returns a duplicate key error when doc violates a unique index against others.
+// The caller must hold the lock.
+func (c *Collection) checkUnique(doc bson.D, others []bson.D, position int) error {
+	for _, idx := range c.indexes {
+		if dup, ok := c.duplicate(idx, doc, others); ok {
+			return mongo.WriteException{
+				WriteErrors: mongo.WriteErrors{{
+					Index:   position,
+					Code:    duplicateKeyCode,
+					Message: c.duplicateMessage(idx, dup),
+				}},
+			}
+		}
+	}
+
+	return nil
+}
+
+// duplicate returns the entry of the unique index idx that doc shares with one of others.
+func (c *Collection) duplicate(idx index, doc bson.D, others []bson.D) (bson.A, bool) {
+	if !idx.unique {
+		return nil, false
+	}
+
+	entries, ok := indexEntries(idx, doc)
+	if !ok {
+		return nil, false
+	}
+
+	for _, other := range others {
+		otherEntries, ok := indexEntries(idx, other)
+		if !ok {
+			continue
+		}
+
+		for _, a := range entries {
+			for _, b := range otherEntries {
+				if matcher.Equal(a, b) {
+					return a, true
+				}
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// indexEntries returns the keys doc has in idx: one per combination of the elements of indexed
+// arrays, with null standing for missing fields. ok is false when the document is not indexed.
+func indexEntries(idx index, doc bson.D) ([]bson.A, bool) {
+	if idx.partial != nil {
+		if matched, err := matcher.Match(idx.partial, doc); err != nil || !matched {
+			return nil, false
+		}
+	}
+
+	entries := []bson.A{{}}
+	missing := 0
+
+	for _, key := range idx.keys {
+		var values []interface{}
+		for _, v := range matcher.Lookup(doc, strings.Split(key.Key, ".")) {
+			if arr, ok := v.(bson.A); ok && len(arr) > 0 {
+				values = append(values, arr...)
+				continue
+			}
+			values = append(values, v)
+		}
+
+		if len(values) == 0 {
+			missing++
+			values = []interface{}{nil}
+		}
+
+		next := make([]bson.A, 0, len(entries)*len(values))
+		for _, entry := range entries {
+			for _, v := range values {
+				next = append(next, append(append(bson.A{}, entry...), v))
+			}
+		}
+		entries = next
+	}
+
+	if idx.sparse && missing == len(idx.keys) {
+		return nil, false
+	}
+
+	return entries, true
+}
+
+func (c *Collection) duplicateMessage(idx index, entry bson.A) string {
+	keys := make([]string, 0, len(idx.keys))
+	for i, key := range idx.keys {
+		keys = append(keys, fmt.Sprintf("%s: %v", key.Key, entry[i]))
+	}
+
+	return fmt.Sprintf("E11000 duplicate key error collection: %s.%s index: %s dup key: { %s }",
+		c.db.name, c.name, idx.name, strings.Join(keys, ", "))
+}
@@ -0,0 +1,144 @@
+package memstore
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	mongo_options "go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/v1shn3vsk7/mongorm/internal/matcher"
+)
+
+// lookup runs a $lookup stage joining the documents of the collection from whose foreignField
+// equals the localField of every document, as on a server: array values match when one of their
+// elements does and missing fields match null. The stages of a pipeline given along run on the
+// joined documents; let is not supported.
+func (c *Collection) lookup(ctx context.Context, docs []bson.D, value interface{}) ([]bson.D, error) {
+	spec, ok := value.(bson.D)
+	if !ok {
+		return nil, fmt.Errorf("mongorm: $lookup requires a document")
+	}
+
+	var from, localField, foreignField, as string
+	var pipeline bson.A
+
+	for _, e := range spec {
+		switch e.Key {
+		case "from", "localField", "foreignField", "as":
+			s, ok := e.Value.(string)
+			if !ok || s == "" {
+				return nil, fmt.Errorf("mongorm: $lookup %s requires a string", e.Key)
+			}
+
+			switch e.Key {
+			case "from":
+				from = s
+			case "localField":
+				localField = s
+			case "foreignField":
+				foreignField = s
+			case "as":
+				as = s
+			}
+		case "pipeline":
+			if pipeline, ok = e.Value.(bson.A); !ok {
+				return nil, fmt.Errorf("mongorm: $lookup pipeline requires an array")
+			}
+		default:
+			return nil, fmt.Errorf("%w: $lookup %s", matcher.ErrUnsupported, e.Key)
+		}
+	}
+
+	if from == "" || localField == "" || foreignField == "" || as == "" {
+		return nil, fmt.Errorf("%w: $lookup without from, localField, foreignField and as", matcher.ErrUnsupported)
+	}
+
+	if strings.Contains(as, ".") {
+		return nil, fmt.Errorf("%w: $lookup into the embedded field %q", matcher.ErrUnsupported, as)
+	}
+
+	foreign, err := c.db.Collection(from).find(ctx, nil, &mongo_options.FindOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]bson.D, 0, len(docs))
+	for _, doc := range docs {
+		locals := lookupValues(doc, localField)
+
+		joined := make([]bson.D, 0)
+		for _, other := range foreign {
+			if anyEqual(locals, lookupValues(other, foreignField)) {
+				joined = append(joined, other)
+			}
+		}
+
+		for _, stage := range pipeline {
+			stage, err := normalize(stage)
+			if err != nil {
+				return nil, err
+			}
+
+			if len(stage) != 1 {
+				return nil, fmt.Errorf("mongorm: pipeline stage must have exactly one field, got %d", len(stage))
+			}
+
+			if joined, err = c.applyStage(ctx, joined, stage[0]); err != nil {
+				return nil, err
+			}
+		}
+
+		arr := make(bson.A, 0, len(joined))
+		for _, j := range joined {
+			arr = append(arr, j)
+		}
+
+		out = append(out, setField(doc, as, arr))
+	}
+
+	return out, nil
+}
+
+// lookupValues returns the values of path in doc with arrays expanded, or null when it is missing.
+func lookupValues(doc bson.D, path string) []interface{} {
+	values := make([]interface{}, 0, 1)
+	for _, v := range matcher.Lookup(doc, strings.Split(path, ".")) {
+		if arr, ok := v.(bson.A); ok {
+			values = append(values, arr...)
+			continue
+		}
+		values = append(values, v)
+	}
+
+	if len(values) == 0 {
+		values = append(values, nil)
+	}
+
+	return values
+}
+
+func anyEqual(a, b []interface{}) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if matcher.Equal(x, y) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// setField returns doc with the top-level field key set to value, replacing an existing one.
+func setField(doc bson.D, key string, value interface{}) bson.D {
+	for i, e := range doc {
+		if e.Key == key {
+			doc[i].Value = value
+			return doc
+		}
+	}
+
+	return append(doc, bson.E{Key: key, Value: value})
+}
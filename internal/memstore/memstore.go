@@ -0,0 +1,169 @@
+// Package memstore is an in-memory implementation of the collection methods mongorm needs.
+// Filters are evaluated by the matcher package, so queries, update operators, sorting and
+// unique indexes behave like on a server, but nothing is persisted and there are no sessions:
+// transactions run one at a time and roll back by restoring a snapshot.
+package memstore
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/v1shn3vsk7/mongorm/internal/matcher"
+)
+
+// Server holds the databases of an in-memory deployment.
+type Server struct {
+	mu        sync.Mutex
+	databases map[string]*Database
+
+	// txMu serializes transactions.
+	txMu sync.Mutex
+
+	// Now returns the time used by $currentDate.
+	Now func() time.Time
+}
+
+// Database holds the collections of an in-memory database.
+type Database struct {
+	server      *Server
+	name        string
+	mu          sync.Mutex
+	collections map[string]*Collection
+}
+
+// Collection is an in-memory collection. Its methods have the signatures of *mongo.Collection.
+type Collection struct {
+	db      *Database
+	name    string
+	mu      sync.RWMutex
+	docs    []bson.D
+	indexes []index
+}
+
+func NewServer() *Server {
+	return &Server{
+		databases: make(map[string]*Database),
+		Now:       time.Now,
+	}
+}
+
+// Database returns the database called name, creating it on first use.
+func (s *Server) Database(name string) *Database {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	db, ok := s.databases[name]
+	if !ok {
+		db = &Database{server: s, name: name, collections: make(map[string]*Collection)}
+		s.databases[name] = db
+	}
+
+	return db
+}
+
+func (db *Database) Name() string {
+	return db.name
+}
+
+// Collection returns the collection called name, creating it on first use.
+func (db *Database) Collection(name string) *Collection {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	c, ok := db.collections[name]
+	if !ok {
+		c = &Collection{db: db, name: name, indexes: []index{idIndex()}}
+		db.collections[name] = c
+	}
+
+	return c
+}
+
+// CollectionNames returns the names of the collections of the database.
+func (db *Database) CollectionNames() []string {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	names := make([]string, 0, len(db.collections))
+	for name := range db.collections {
+		names = append(names, name)
+	}
+
+	return names
+}
+
+func (c *Collection) Name() string {
+	return c.name
+}
+
+// normalize converts a document or filter passed to the collection into a bson.D; nil is an empty document.
+func normalize(v interface{}) (bson.D, error) {
+	if v == nil {
+		return bson.D{}, nil
+	}
+
+	return matcher.Normalize(v)
+}
+
+// clone returns a deep copy of doc.
+func clone(doc bson.D) bson.D {
+	out, err := matcher.Normalize(doc)
+	if err != nil {
+		// doc was produced by Normalize, so it always marshals back
+		panic(fmt.Sprintf("memstore: clone document: %v", err))
+	}
+
+	return out
+}
+
+// withID returns doc with its _id as the first field, generating an ObjectID when missing.
+func withID(doc bson.D) bson.D {
+	id, ok := matcher.Get(doc, "_id")
+	if !ok {
+		id = primitive.NewObjectID()
+	}
+
+	out := make(bson.D, 0, len(doc)+1)
+	out = append(out, bson.E{Key: "_id", Value: id})
+	for _, elem := range doc {
+		if elem.Key != "_id" {
+			out = append(out, elem)
+		}
+	}
+
+	return out
+}
+
+// filter returns the documents of the collection matched by filter, in natural order,
+// together with their positions. The caller must hold the lock.
+func (c *Collection) filter(filter bson.D) ([]bson.D, []int, error) {
+	var docs []bson.D
+	var positions []int
+
+	for i, doc := range c.docs {
+		ok, err := matcher.Match(filter, doc)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if ok {
+			docs = append(docs, doc)
+			positions = append(positions, i)
+		}
+	}
+
+	return docs, positions, nil
+}
+
+func checkContext(ctx context.Context) error {
+	if ctx == nil {
+		return nil
+	}
+
+	return ctx.Err()
+}
@@ -0,0 +1,181 @@
+package memstore
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mongo_options "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func testCollection(t *testing.T, docs ...string) *Collection {
+	t.Helper()
+
+	c := NewServer().Database("db").Collection("c")
+	for _, doc := range docs {
+		if _, err := c.InsertOne(context.Background(), extJSON(t, doc)); err != nil {
+			t.Fatalf("err insert %s: %v", doc, err)
+		}
+	}
+
+	return c
+}
+
+func ids(t *testing.T, cursor *mongo.Cursor) []int32 {
+	t.Helper()
+
+	var docs []bson.M
+	if err := cursor.All(context.Background(), &docs); err != nil {
+		t.Fatalf("err decode: %v", err)
+	}
+
+	out := make([]int32, 0, len(docs))
+	for _, doc := range docs {
+		out = append(out, doc["_id"].(int32))
+	}
+
+	return out
+}
+
+func equalIDs(a, b []int32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestFind(t *testing.T) {
+	c := testCollection(t,
+		`{"_id": 1, "a": 1, "tags": ["x", "y"]}`,
+		`{"_id": 2, "a": null, "tags": []}`,
+		`{"_id": 3, "tags": ["y"]}`,
+		`{"_id": 4, "a": "1", "tags": "x"}`,
+		`{"_id": 5, "a": [0, 3], "tags": [{"k": "x"}]}`,
+	)
+
+	tests := []struct {
+		name   string
+		filter string
+		sort   string
+		want   []int32
+	}{
+		{"all", `{}`, `{"_id": 1}`, []int32{1, 2, 3, 4, 5}},
+		{"null or missing", `{"a": null}`, `{"_id": 1}`, []int32{2, 3}},
+		{"exists", `{"a": {"$exists": true}}`, `{"_id": 1}`, []int32{1, 2, 4, 5}},
+		{"ne on arrays and missing", `{"tags": {"$ne": "x"}}`, `{"_id": 1}`, []int32{2, 3, 5}},
+		{"nin", `{"tags": {"$nin": ["x", "y"]}}`, `{"_id": 1}`, []int32{2, 5}},
+		{"numbers only", `{"a": {"$gte": 1}}`, `{"_id": 1}`, []int32{1, 5}},
+		{"strings only", `{"a": {"$gte": ""}}`, `{"_id": 1}`, []int32{4}},
+		{"array path", `{"tags.k": "x"}`, `{"_id": 1}`, []int32{5}},
+		{"size", `{"tags": {"$size": 0}}`, `{"_id": 1}`, []int32{2}},
+		{"sort null and missing first", `{}`, `{"a": 1, "_id": 1}`, []int32{2, 3, 5, 1, 4}},
+		{"sort descending by array max", `{}`, `{"a": -1, "_id": 1}`, []int32{4, 5, 1, 2, 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cursor, err := c.Find(context.Background(), extJSON(t, tt.filter), mongo_options.Find().SetSort(extJSON(t, tt.sort)))
+			if err != nil {
+				t.Fatalf("Find(%s): %v", tt.filter, err)
+			}
+
+			if got := ids(t, cursor); !equalIDs(got, tt.want) {
+				t.Errorf("Find(%s) sorted by %s = %v, want %v", tt.filter, tt.sort, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  string
+		update  string
+		upsert  bool
+		want    string
+		wantErr bool
+	}{
+		{"update", `{"_id": 1}`, `{"$inc": {"n": 1}}`, false, `{"_id": 1, "n": 2, "a": {"b": 1}}`, false},
+		{"no match", `{"_id": 9}`, `{"$inc": {"n": 1}}`, false, `{"_id": 1, "n": 1, "a": {"b": 1}}`, false},
+		{"upsert seeds equality", `{"_id": 2, "k": {"$eq": "x"}, "n": {"$gt": 1}}`, `{"$set": {"m": 1}}`, true, `{"_id": 2, "k": "x", "m": 1}`, false},
+		{"upsert and", `{"$and": [{"_id": 2}, {"a.b": 3}]}`, `{"$setOnInsert": {"c": 1}}`, true, `{"_id": 2, "a": {"b": 3}, "c": 1}`, false},
+		{"conflict leaves document", `{"_id": 1}`, `{"$set": {"a": {}}, "$unset": {"a.b": ""}}`, false, `{"_id": 1, "n": 1, "a": {"b": 1}}`, true},
+		{"conflict on upsert", `{"_id": 2}`, `{"$set": {"c": 1}, "$setOnInsert": {"c.d": 2}}`, true, `{"_id": 2}`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			c := testCollection(t, `{"_id": 1, "n": 1, "a": {"b": 1}}`)
+
+			opts := mongo_options.Update().SetUpsert(tt.upsert)
+			_, err := c.UpdateOne(ctx, extJSON(t, tt.filter), extJSON(t, tt.update), opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UpdateOne(%s, %s): error %v, want error %v", tt.filter, tt.update, err, tt.wantErr)
+			}
+
+			want := extJSON(t, tt.want)
+
+			got := bson.D{}
+			if err := c.FindOne(ctx, bson.D{{Key: "_id", Value: want[0].Value}}).Decode(&got); err != nil {
+				if tt.wantErr && err == mongo.ErrNoDocuments {
+					return
+				}
+				t.Fatalf("FindOne(%v): %v", want[0].Value, err)
+			}
+
+			if !bytes.Equal(marshal(t, got), marshal(t, want)) {
+				t.Errorf("UpdateOne(%s, %s) stored %v, want %v", tt.filter, tt.update, got, want)
+			}
+		})
+	}
+}
+
+func TestUniqueIndex(t *testing.T) {
+	ctx := context.Background()
+	c := testCollection(t, `{"_id": 1, "email": "a"}`, `{"_id": 2}`)
+
+	_, err := c.CreateIndexes(ctx, []mongo.IndexModel{{
+		Keys:    bson.D{{Key: "email", Value: 1}},
+		Options: mongo_options.Index().SetUnique(true),
+	}})
+	if err != nil {
+		t.Fatalf("CreateIndexes: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		doc  string
+		dup  bool
+	}{
+		{"duplicate", `{"_id": 3, "email": "a"}`, true},
+		{"duplicate missing as null", `{"_id": 3}`, true},
+		{"duplicate null", `{"_id": 3, "email": null}`, true},
+		{"other value", `{"_id": 3, "email": "b"}`, false},
+		{"duplicate id", `{"_id": 1, "email": "c"}`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := c.InsertOne(ctx, extJSON(t, tt.doc))
+			if mongo.IsDuplicateKeyError(err) != tt.dup {
+				t.Fatalf("InsertOne(%s): error %v, want duplicate %v", tt.doc, err, tt.dup)
+			}
+
+			if err == nil {
+				if _, err := c.DeleteOne(ctx, bson.D{{Key: "_id", Value: int32(3)}}); err != nil {
+					t.Fatalf("DeleteOne: %v", err)
+				}
+			}
+		})
+	}
+}
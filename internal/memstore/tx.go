@@ -0,0 +1,77 @@
+package memstore
+
+import "go.mongodb.org/mongo-driver/bson"
+
+// Transaction runs fn as a transaction: transactions run one at a time and, when fn fails, the
+// databases are restored to their state before fn, which also reverts writes made outside of the
+// transaction meanwhile.
+func (s *Server) Transaction(fn func() error) error {
+	s.txMu.Lock()
+	defer s.txMu.Unlock()
+
+	snap := s.snapshot()
+
+	if err := fn(); err != nil {
+		s.restore(snap)
+		return err
+	}
+
+	return nil
+}
+
+type collectionState struct {
+	docs    []bson.D
+	indexes []index
+}
+
+// snapshot copies the documents and indexes of every collection.
+func (s *Server) snapshot() map[*Collection]collectionState {
+	snap := make(map[*Collection]collectionState)
+
+	for _, c := range s.collections() {
+		c.mu.RLock()
+		state := collectionState{
+			docs:    make([]bson.D, 0, len(c.docs)),
+			indexes: append([]index{}, c.indexes...),
+		}
+		for _, doc := range c.docs {
+			state.docs = append(state.docs, clone(doc))
+		}
+		c.mu.RUnlock()
+
+		snap[c] = state
+	}
+
+	return snap
+}
+
+// restore resets the collections to snap. Collections created after the snapshot are emptied
+// rather than removed, since callers may still hold them.
+func (s *Server) restore(snap map[*Collection]collectionState) {
+	for _, c := range s.collections() {
+		state, ok := snap[c]
+		if !ok {
+			state = collectionState{indexes: []index{idIndex()}}
+		}
+
+		c.mu.Lock()
+		c.docs, c.indexes = state.docs, state.indexes
+		c.mu.Unlock()
+	}
+}
+
+func (s *Server) collections() []*Collection {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var collections []*Collection
+	for _, db := range s.databases {
+		db.mu.Lock()
+		for _, c := range db.collections {
+			collections = append(collections, c)
+		}
+		db.mu.Unlock()
+	}
+
+	return collections
+}
@@ -0,0 +1,535 @@
+package memstore
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/v1shn3vsk7/mongorm/internal/matcher"
+	"github.com/v1shn3vsk7/mongorm/internal/operators"
+)
+
+// applyUpdate returns a copy of doc with the update operators of update applied. insert is true
+// when the document is created by an upsert, which is the only case $setOnInsert applies.
+func applyUpdate(doc, update bson.D, insert bool, now time.Time) (bson.D, error) {
+	if len(update) == 0 {
+		return nil, fmt.Errorf("mongorm: update document cannot be empty")
+	}
+
+	out := clone(doc)
+	paths := make([]string, 0)
+
+	for _, op := range update {
+		if !strings.HasPrefix(op.Key, "$") {
+			return nil, fmt.Errorf("%w: replacement documents", matcher.ErrUnsupported)
+		}
+
+		fields, ok := op.Value.(bson.D)
+		if !ok {
+			return nil, fmt.Errorf("mongorm: %s requires a document", op.Key)
+		}
+
+		for _, field := range fields {
+			parts := strings.Split(field.Key, ".")
+			for _, part := range parts {
+				if strings.HasPrefix(part, "$") {
+					return nil, fmt.Errorf("%w: positional path %q", matcher.ErrUnsupported, field.Key)
+				}
+			}
+
+			modified := []string{field.Key}
+			if to, ok := field.Value.(string); ok && op.Key == operators.RENAME {
+				modified = append(modified, to)
+			}
+
+			for _, path := range modified {
+				if other, ok := conflict(paths, path); ok {
+					return nil, fmt.Errorf("mongorm: updating the path '%s' would create a conflict at '%s'", path, other)
+				}
+				paths = append(paths, path)
+			}
+
+			var err error
+			if out, err = applyOp(out, op.Key, parts, field.Value, insert, now); err != nil {
+				return nil, fmt.Errorf("%w (field %q)", err, field.Key)
+			}
+		}
+	}
+
+	if !matcher.Equal(idOf(doc), idOf(out)) && len(doc) > 0 {
+		return nil, fmt.Errorf("mongorm: performing an update on the path '_id' would modify the immutable field '_id'")
+	}
+
+	return out, nil
+}
+
+// conflict returns the shorter of path and the first of paths that is equal to it, a parent or a child:
+// an update modifies every path once.
+func conflict(paths []string, path string) (string, bool) {
+	for _, other := range paths {
+		switch {
+		case other == path || strings.HasPrefix(path, other+"."):
+			return other, true
+		case strings.HasPrefix(other, path+"."):
+			return path, true
+		}
+	}
+
+	return "", false
+}
+
+func applyOp(doc bson.D, op string, parts []string, arg interface{}, insert bool, now time.Time) (bson.D, error) {
+	cur, exists := get(doc, parts)
+
+	switch op {
+	case operators.SET:
+		return setDoc(doc, parts, arg)
+	case operators.SET_ON_INSERT:
+		if !insert {
+			return doc, nil
+		}
+		return setDoc(doc, parts, arg)
+	case operators.UNSET:
+		return unset(doc, parts).(bson.D), nil
+	case operators.INC, operators.MUL:
+		if _, ok := matcher.ToFloat(arg); !ok {
+			return nil, fmt.Errorf("mongorm: %s requires a number, got %v", op, arg)
+		}
+
+		if !exists {
+			if op == operators.MUL {
+				arg, _ = arithmetic(operators.MULTIPLY, arg, int32(0))
+			}
+			return setDoc(doc, parts, arg)
+		}
+
+		arithOp := operators.ADD
+		if op == operators.MUL {
+			arithOp = operators.MULTIPLY
+		}
+
+		v, err := arithmetic(arithOp, cur, arg)
+		if err != nil {
+			return nil, err
+		}
+
+		return setDoc(doc, parts, v)
+	case operators.MIN, operators.MAX:
+		c := matcher.Compare(arg, cur)
+		if exists && ((op == operators.MIN && c >= 0) || (op == operators.MAX && c <= 0)) {
+			return doc, nil
+		}
+		return setDoc(doc, parts, arg)
+	case operators.RENAME:
+		to, ok := arg.(string)
+		if !ok || to == "" {
+			return nil, fmt.Errorf("mongorm: %s requires a field name, got %v", op, arg)
+		}
+
+		if !exists {
+			return doc, nil
+		}
+
+		return setDoc(unset(doc, parts).(bson.D), strings.Split(to, "."), cur)
+	case operators.CURRENT_DATE:
+		var v interface{} = primitive.NewDateTimeFromTime(now)
+
+		if spec, ok := arg.(bson.D); ok {
+			switch t, _ := matcher.Get(spec, operators.TYPE); t {
+			case "date":
+			case "timestamp":
+				v = primitive.Timestamp{T: uint32(now.Unix()), I: 1}
+			default:
+				return nil, fmt.Errorf("mongorm: %s requires $type date or timestamp, got %v", op, t)
+			}
+		} else if arg != true {
+			return nil, fmt.Errorf("mongorm: %s requires true or a $type document, got %v", op, arg)
+		}
+
+		return setDoc(doc, parts, v)
+	case operators.PUSH, operators.ADD_TO_SET, operators.PULL, operators.PULL_ALL, operators.POP:
+		arr := bson.A{}
+		if exists {
+			var ok bool
+			if arr, ok = cur.(bson.A); !ok {
+				return nil, fmt.Errorf("mongorm: %s requires an array, got %v", op, cur)
+			}
+		} else if op != operators.PUSH && op != operators.ADD_TO_SET {
+			return doc, nil
+		}
+
+		arr, err := applyArrayOp(append(bson.A{}, arr...), op, arg)
+		if err != nil {
+			return nil, err
+		}
+
+		return setDoc(doc, parts, arr)
+	}
+
+	return nil, fmt.Errorf("%w: %s", matcher.ErrUnsupported, op)
+}
+
+func applyArrayOp(arr bson.A, op string, arg interface{}) (bson.A, error) {
+	switch op {
+	case operators.PUSH:
+		mods, ok := modifiers(arg)
+		if !ok {
+			return append(arr, arg), nil
+		}
+		return push(arr, mods)
+	case operators.ADD_TO_SET:
+		values := bson.A{arg}
+		if mods, ok := modifiers(arg); ok {
+			each, _ := matcher.Get(mods, operators.EACH)
+			if values, ok = each.(bson.A); !ok || len(mods) != 1 {
+				return nil, fmt.Errorf("mongorm: %s accepts only %s with an array", op, operators.EACH)
+			}
+		}
+
+		for _, v := range values {
+			if !contains(arr, v) {
+				arr = append(arr, v)
+			}
+		}
+
+		return arr, nil
+	case operators.PULL:
+		out := bson.A{}
+		for _, elem := range arr {
+			matched := matcher.Equal(elem, arg)
+			if cond, ok := arg.(bson.D); ok {
+				var err error
+				if matched, err = matcher.MatchElement(elem, cond); err != nil {
+					return nil, err
+				}
+			}
+
+			if !matched {
+				out = append(out, elem)
+			}
+		}
+
+		return out, nil
+	case operators.PULL_ALL:
+		values, ok := arg.(bson.A)
+		if !ok {
+			return nil, fmt.Errorf("mongorm: %s requires an array, got %v", op, arg)
+		}
+
+		out := bson.A{}
+		for _, elem := range arr {
+			if !contains(values, elem) {
+				out = append(out, elem)
+			}
+		}
+
+		return out, nil
+	case operators.POP:
+		n, ok := matcher.ToFloat(arg)
+		if !ok || (n != 1 && n != -1) {
+			return nil, fmt.Errorf("mongorm: %s requires 1 or -1, got %v", op, arg)
+		}
+
+		switch {
+		case len(arr) == 0:
+			return arr, nil
+		case n == 1:
+			return arr[:len(arr)-1], nil
+		}
+
+		return arr[1:], nil
+	}
+
+	return nil, fmt.Errorf("%w: %s", matcher.ErrUnsupported, op)
+}
+
+// modifiers returns arg when it is a document of $push modifiers such as {$each: [...], $slice: 3}.
+func modifiers(arg interface{}) (bson.D, bool) {
+	doc, ok := arg.(bson.D)
+	if !ok {
+		return nil, false
+	}
+
+	if _, ok := matcher.Get(doc, operators.EACH); !ok {
+		return nil, false
+	}
+
+	return doc, true
+}
+
+// push applies {$each, $position, $sort, $slice} in the order the server does.
+func push(arr bson.A, mods bson.D) (bson.A, error) {
+	each, _ := matcher.Get(mods, operators.EACH)
+	values, ok := each.(bson.A)
+	if !ok {
+		return nil, fmt.Errorf("mongorm: %s requires an array, got %v", operators.EACH, each)
+	}
+
+	pos := len(arr)
+	if p, ok := matcher.Get(mods, operators.POSITION); ok {
+		n, ok := matcher.ToFloat(p)
+		if !ok {
+			return nil, fmt.Errorf("mongorm: %s requires a number, got %v", operators.POSITION, p)
+		}
+
+		pos = int(n)
+		if pos < 0 {
+			pos = max(len(arr)+pos, 0)
+		}
+		pos = min(pos, len(arr))
+	}
+
+	out := make(bson.A, 0, len(arr)+len(values))
+	out = append(out, arr[:pos]...)
+	out = append(out, values...)
+	out = append(out, arr[pos:]...)
+
+	if spec, ok := matcher.Get(mods, operators.SORT); ok {
+		if err := sortArray(out, spec); err != nil {
+			return nil, err
+		}
+	}
+
+	if s, ok := matcher.Get(mods, operators.SLICE); ok {
+		n, ok := matcher.ToFloat(s)
+		if !ok {
+			return nil, fmt.Errorf("mongorm: %s requires a number, got %v", operators.SLICE, s)
+		}
+
+		switch size := int(n); {
+		case size >= 0:
+			out = out[:min(size, len(out))]
+		default:
+			out = out[max(len(out)+size, 0):]
+		}
+	}
+
+	return out, nil
+}
+
+// sortArray sorts the elements of arr by 1 or -1, or by a document of element fields.
+func sortArray(arr bson.A, spec interface{}) error {
+	if dir, ok := matcher.ToFloat(spec); ok {
+		sort.SliceStable(arr, func(a, b int) bool {
+			return matcher.Compare(arr[a], arr[b])*int(dir) < 0
+		})
+		return nil
+	}
+
+	docs := make([]bson.D, 0, len(arr))
+	for _, elem := range arr {
+		doc, ok := elem.(bson.D)
+		if !ok {
+			return fmt.Errorf("mongorm: %s by fields requires documents, got %v", operators.SORT, elem)
+		}
+		docs = append(docs, doc)
+	}
+
+	if err := sortDocs(docs, spec); err != nil {
+		return err
+	}
+
+	for i, doc := range docs {
+		arr[i] = doc
+	}
+
+	return nil
+}
+
+func contains(arr bson.A, v interface{}) bool {
+	for _, elem := range arr {
+		if matcher.Equal(elem, v) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// arithmetic adds or multiplies two numbers, keeping int32 while the result fits like the server does.
+func arithmetic(op string, a, b interface{}) (interface{}, error) {
+	fa, okA := matcher.ToFloat(a)
+	fb, okB := matcher.ToFloat(b)
+	if !okA || !okB {
+		return nil, fmt.Errorf("mongorm: cannot apply %s to %v and %v", op, a, b)
+	}
+
+	_, decA := a.(primitive.Decimal128)
+	_, decB := b.(primitive.Decimal128)
+	if decA || decB {
+		return nil, fmt.Errorf("%w: decimal arithmetic", matcher.ErrUnsupported)
+	}
+
+	ia, intA := integer(a)
+	ib, intB := integer(b)
+
+	if !intA || !intB {
+		if op == operators.ADD {
+			return fa + fb, nil
+		}
+		return fa * fb, nil
+	}
+
+	var r int64
+	if op == operators.ADD {
+		r = ia + ib
+		if (r > ia) != (ib > 0) {
+			return nil, fmt.Errorf("mongorm: integer overflow")
+		}
+	} else {
+		r = ia * ib
+		if ia != 0 && (r/ia != ib || (ia == -1 && ib == math.MinInt64)) {
+			return nil, fmt.Errorf("mongorm: integer overflow")
+		}
+	}
+
+	_, longA := a.(int64)
+	_, longB := b.(int64)
+	if !longA && !longB && r >= math.MinInt32 && r <= math.MaxInt32 {
+		return int32(r), nil
+	}
+
+	return r, nil
+}
+
+func integer(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	}
+
+	return 0, false
+}
+
+func idOf(doc bson.D) interface{} {
+	id, _ := matcher.Get(doc, "_id")
+
+	return id
+}
+
+// get returns the value at the exact path parts of doc, where numeric parts index arrays.
+func get(v interface{}, parts []string) (interface{}, bool) {
+	if len(parts) == 0 {
+		return v, true
+	}
+
+	switch cur := v.(type) {
+	case bson.D:
+		val, ok := matcher.Get(cur, parts[0])
+		if !ok {
+			return nil, false
+		}
+		return get(val, parts[1:])
+	case bson.A:
+		idx, err := strconv.Atoi(parts[0])
+		if err != nil || idx < 0 || idx >= len(cur) {
+			return nil, false
+		}
+		return get(cur[idx], parts[1:])
+	}
+
+	return nil, false
+}
+
+func setDoc(doc bson.D, parts []string, value interface{}) (bson.D, error) {
+	v, err := set(doc, parts, value)
+	if err != nil {
+		return nil, err
+	}
+
+	return v.(bson.D), nil
+}
+
+// set returns v with value stored at the path parts, creating missing documents on the way.
+func set(v interface{}, parts []string, value interface{}) (interface{}, error) {
+	if len(parts) == 0 {
+		return value, nil
+	}
+
+	switch cur := v.(type) {
+	case bson.D:
+		for i, elem := range cur {
+			if elem.Key == parts[0] {
+				child, err := set(elem.Value, parts[1:], value)
+				if err != nil {
+					return nil, err
+				}
+
+				cur[i].Value = child
+				return cur, nil
+			}
+		}
+
+		child, err := set(bson.D{}, parts[1:], value)
+		if err != nil {
+			return nil, err
+		}
+
+		return append(cur, bson.E{Key: parts[0], Value: child}), nil
+	case bson.A:
+		idx, err := strconv.Atoi(parts[0])
+		if err != nil || idx < 0 {
+			return nil, fmt.Errorf("mongorm: cannot create field %q in an array", parts[0])
+		}
+
+		var elem interface{} = bson.D{}
+		if idx < len(cur) {
+			elem = cur[idx]
+		}
+
+		for len(cur) <= idx {
+			cur = append(cur, nil)
+		}
+
+		child, err := set(elem, parts[1:], value)
+		if err != nil {
+			return nil, err
+		}
+
+		cur[idx] = child
+		return cur, nil
+	}
+
+	return nil, fmt.Errorf("mongorm: cannot create field %q in element %v", parts[0], v)
+}
+
+// unset returns v without the value at the path parts. Array elements are set to null instead.
+func unset(v interface{}, parts []string) interface{} {
+	switch cur := v.(type) {
+	case bson.D:
+		for i, elem := range cur {
+			if elem.Key != parts[0] {
+				continue
+			}
+
+			if len(parts) == 1 {
+				return append(cur[:i:i], cur[i+1:]...)
+			}
+
+			cur[i].Value = unset(elem.Value, parts[1:])
+			return cur
+		}
+	case bson.A:
+		idx, err := strconv.Atoi(parts[0])
+		if err != nil || idx < 0 || idx >= len(cur) {
+			return cur
+		}
+
+		if len(parts) == 1 {
+			cur[idx] = nil
+		} else {
+			cur[idx] = unset(cur[idx], parts[1:])
+		}
+		return cur
+	}
+
+	return v
+}
@@ -0,0 +1,110 @@
+package memstore
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func extJSON(t *testing.T, s string) bson.D {
+	t.Helper()
+
+	doc := bson.D{}
+	if err := bson.UnmarshalExtJSON([]byte(s), false, &doc); err != nil {
+		t.Fatalf("err parse %s: %v", s, err)
+	}
+
+	return doc
+}
+
+func marshal(t *testing.T, doc bson.D) []byte {
+	t.Helper()
+
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		t.Fatalf("err marshal %v: %v", doc, err)
+	}
+
+	return data
+}
+
+func TestApplyUpdate(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		doc     string
+		update  string
+		insert  bool
+		want    string
+		wantErr bool
+	}{
+		{"set", `{"a": 1}`, `{"$set": {"a": 2, "b": 3}}`, false, `{"a": 2, "b": 3}`, false},
+		{"set embedded", `{"a": {"b": 1}}`, `{"$set": {"a.c": 2}}`, false, `{"a": {"b": 1, "c": 2}}`, false},
+		{"set creates parents", `{}`, `{"$set": {"a.b.c": 1}}`, false, `{"a": {"b": {"c": 1}}}`, false},
+		{"set array index", `{"a": [1, 2]}`, `{"$set": {"a.1": 5}}`, false, `{"a": [1, 5]}`, false},
+		{"set array index pads", `{"a": [1]}`, `{"$set": {"a.2": 5}}`, false, `{"a": [1, null, 5]}`, false},
+		{"set through scalar", `{"a": 1}`, `{"$set": {"a.b": 2}}`, false, ``, true},
+		{"unset", `{"a": 1, "b": 2}`, `{"$unset": {"a": ""}}`, false, `{"b": 2}`, false},
+		{"unset missing", `{"b": 2}`, `{"$unset": {"a.c": ""}}`, false, `{"b": 2}`, false},
+		{"set on insert skipped", `{"a": 1}`, `{"$setOnInsert": {"b": 1}}`, false, `{"a": 1}`, false},
+		{"set on insert", `{"a": 1}`, `{"$setOnInsert": {"b": 1}}`, true, `{"a": 1, "b": 1}`, false},
+		{"inc", `{"a": 1}`, `{"$inc": {"a": 2, "b": 1}}`, false, `{"a": 3, "b": 1}`, false},
+		{"inc keeps long", `{"a": {"$numberLong": "1"}}`, `{"$inc": {"a": 1}}`, false, `{"a": {"$numberLong": "2"}}`, false},
+		{"inc double", `{"a": 1}`, `{"$inc": {"a": 0.5}}`, false, `{"a": 1.5}`, false},
+		{"inc string", `{"a": "x"}`, `{"$inc": {"a": 1}}`, false, ``, true},
+		{"mul missing", `{}`, `{"$mul": {"a": 3}}`, false, `{"a": 0}`, false},
+		{"min", `{"a": 5}`, `{"$min": {"a": 3}}`, false, `{"a": 3}`, false},
+		{"max keeps", `{"a": 5}`, `{"$max": {"a": 3}}`, false, `{"a": 5}`, false},
+		{"rename", `{"a": 1}`, `{"$rename": {"a": "b.c"}}`, false, `{"b": {"c": 1}}`, false},
+		{"rename missing", `{"x": 1}`, `{"$rename": {"a": "b"}}`, false, `{"x": 1}`, false},
+		{"current date", `{}`, `{"$currentDate": {"a": true}}`, false, `{"a": {"$date": "2024-01-02T03:04:05Z"}}`, false},
+		{"push", `{"a": [1]}`, `{"$push": {"a": 2}}`, false, `{"a": [1, 2]}`, false},
+		{"push each sort slice", `{"a": [3, 1]}`, `{"$push": {"a": {"$each": [2, 5], "$sort": 1, "$slice": 3}}}`, false, `{"a": [1, 2, 3]}`, false},
+		{"push position", `{"a": [1, 2]}`, `{"$push": {"a": {"$each": [9], "$position": -1}}}`, false, `{"a": [1, 9, 2]}`, false},
+		{"push scalar", `{"a": 1}`, `{"$push": {"a": 2}}`, false, ``, true},
+		{"add to set", `{"a": [1, 2]}`, `{"$addToSet": {"a": {"$each": [2, 3]}}}`, false, `{"a": [1, 2, 3]}`, false},
+		{"pull condition", `{"a": [1, 5, 7]}`, `{"$pull": {"a": {"$gte": 5}}}`, false, `{"a": [1]}`, false},
+		{"pull documents", `{"a": [{"b": 1}, {"b": 2}]}`, `{"$pull": {"a": {"b": 1}}}`, false, `{"a": [{"b": 2}]}`, false},
+		{"pull all", `{"a": [1, 2, 1, 3]}`, `{"$pullAll": {"a": [1, 3]}}`, false, `{"a": [2]}`, false},
+		{"pop first", `{"a": [1, 2]}`, `{"$pop": {"a": -1}}`, false, `{"a": [2]}`, false},
+		{"id immutable", `{"_id": 1}`, `{"$set": {"_id": 2}}`, false, ``, true},
+		{"replacement", `{"a": 1}`, `{"a": 2}`, false, ``, true},
+		{"empty", `{"a": 1}`, `{}`, false, ``, true},
+
+		// every path is modified once
+		{"conflict same path", `{"a": 1}`, `{"$set": {"a": 2}, "$inc": {"a": 1}}`, false, ``, true},
+		{"conflict parent", `{"a": {"b": 1}}`, `{"$set": {"a": {}}, "$inc": {"a.b": 1}}`, false, ``, true},
+		{"conflict child", `{"a": {"b": 1}}`, `{"$unset": {"a.b": ""}, "$set": {"a": 1}}`, false, ``, true},
+		{"conflict set on insert", `{}`, `{"$set": {"a": 1}, "$setOnInsert": {"a": 2}}`, true, ``, true},
+		{"conflict rename target", `{"a": 1}`, `{"$rename": {"a": "b"}, "$set": {"b": 2}}`, false, ``, true},
+		{"conflict rename source", `{"a": 1}`, `{"$set": {"a": 2}, "$rename": {"a": "b"}}`, false, ``, true},
+		{"conflict rename itself", `{"a": 1}`, `{"$rename": {"a": "a"}}`, false, ``, true},
+		{"sibling paths", `{"a": {"b": 1}}`, `{"$set": {"a.c": 1}, "$inc": {"a.b": 1}}`, false, `{"a": {"b": 2, "c": 1}}`, false},
+		{"common prefix", `{"ab": 1}`, `{"$set": {"a": 1}, "$inc": {"ab": 1}}`, false, `{"ab": 2, "a": 1}`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := applyUpdate(extJSON(t, tt.doc), extJSON(t, tt.update), tt.insert, now)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("applyUpdate(%s, %s) = %v, want error", tt.doc, tt.update, got)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("applyUpdate(%s, %s): %v", tt.doc, tt.update, err)
+			}
+
+			// the encodings also compare the types of numbers and the order of fields
+			want := extJSON(t, tt.want)
+			if !bytes.Equal(marshal(t, got), marshal(t, want)) {
+				t.Errorf("applyUpdate(%s, %s) = %v, want %v", tt.doc, tt.update, got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,311 @@
+package memstore
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mongo_options "go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/v1shn3vsk7/mongorm/internal/matcher"
+	"github.com/v1shn3vsk7/mongorm/internal/operators"
+)
+
+func (c *Collection) InsertOne(ctx context.Context, document interface{}, _ ...*mongo_options.InsertOneOptions) (*mongo.InsertOneResult, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+
+	doc, err := normalize(document)
+	if err != nil {
+		return nil, err
+	}
+	doc = withID(doc)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.checkUnique(doc, c.docs, 0); err != nil {
+		return nil, err
+	}
+
+	c.docs = append(c.docs, doc)
+
+	return &mongo.InsertOneResult{InsertedID: idOf(doc)}, nil
+}
+
+// InsertMany inserts documents in order and stops at the first error, like an ordered insert.
+func (c *Collection) InsertMany(ctx context.Context, documents []interface{}, _ ...*mongo_options.InsertManyOptions) (*mongo.InsertManyResult, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+
+	if len(documents) == 0 {
+		return nil, mongo.ErrEmptySlice
+	}
+
+	docs := make([]bson.D, 0, len(documents))
+	for _, document := range documents {
+		doc, err := normalize(document)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, withID(doc))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	res := &mongo.InsertManyResult{InsertedIDs: make([]interface{}, 0, len(docs))}
+	for i, doc := range docs {
+		if err := c.checkUnique(doc, c.docs, i); err != nil {
+			we := err.(mongo.WriteException).WriteErrors[0]
+			return res, mongo.BulkWriteException{WriteErrors: []mongo.BulkWriteError{{WriteError: we}}}
+		}
+
+		c.docs = append(c.docs, doc)
+		res.InsertedIDs = append(res.InsertedIDs, idOf(doc))
+	}
+
+	return res, nil
+}
+
+func (c *Collection) DeleteOne(ctx context.Context, filter interface{}, _ ...*mongo_options.DeleteOptions) (*mongo.DeleteResult, error) {
+	return c.delete(ctx, filter, 1)
+}
+
+func (c *Collection) DeleteMany(ctx context.Context, filter interface{}, _ ...*mongo_options.DeleteOptions) (*mongo.DeleteResult, error) {
+	return c.delete(ctx, filter, 0)
+}
+
+// delete removes the documents matched by filter, at most limit of them unless limit is 0.
+func (c *Collection) delete(ctx context.Context, filter interface{}, limit int) (*mongo.DeleteResult, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+
+	f, err := normalize(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, positions, err := c.filter(f)
+	if err != nil {
+		return nil, err
+	}
+
+	if limit > 0 && len(positions) > limit {
+		positions = positions[:limit]
+	}
+
+	deleted := make(map[int]bool, len(positions))
+	for _, pos := range positions {
+		deleted[pos] = true
+	}
+
+	docs := make([]bson.D, 0, len(c.docs)-len(positions))
+	for i, doc := range c.docs {
+		if !deleted[i] {
+			docs = append(docs, doc)
+		}
+	}
+	c.docs = docs
+
+	return &mongo.DeleteResult{DeletedCount: int64(len(positions))}, nil
+}
+
+func (c *Collection) UpdateOne(ctx context.Context, filter, update interface{}, opts ...*mongo_options.UpdateOptions) (*mongo.UpdateResult, error) {
+	opt := mongo_options.MergeUpdateOptions(opts...)
+	res, _, _, err := c.update(ctx, filter, update, 1, opt.Upsert != nil && *opt.Upsert, opt.ArrayFilters, nil)
+
+	return res, err
+}
+
+func (c *Collection) UpdateMany(ctx context.Context, filter, update interface{}, opts ...*mongo_options.UpdateOptions) (*mongo.UpdateResult, error) {
+	opt := mongo_options.MergeUpdateOptions(opts...)
+	res, _, _, err := c.update(ctx, filter, update, 0, opt.Upsert != nil && *opt.Upsert, opt.ArrayFilters, nil)
+
+	return res, err
+}
+
+func (c *Collection) FindOneAndUpdate(ctx context.Context, filter, update interface{}, opts ...*mongo_options.FindOneAndUpdateOptions) *mongo.SingleResult {
+	opt := mongo_options.MergeFindOneAndUpdateOptions(opts...)
+
+	_, before, after, err := c.update(ctx, filter, update, 1, opt.Upsert != nil && *opt.Upsert, opt.ArrayFilters, opt.Sort)
+	if err != nil {
+		return singleResult(nil, err)
+	}
+
+	doc := before
+	if opt.ReturnDocument != nil && *opt.ReturnDocument == mongo_options.After {
+		doc = after
+	}
+
+	if doc == nil {
+		return singleResult(nil, nil)
+	}
+
+	docs := []bson.D{doc}
+	if opt.Projection != nil {
+		if docs, err = projectDocs(docs, opt.Projection); err != nil {
+			return singleResult(nil, err)
+		}
+	}
+
+	return singleResult(docs, nil)
+}
+
+// update applies update to the documents matched by filter, at most limit of them unless limit is 0,
+// and returns the first updated document before and after the update.
+func (c *Collection) update(ctx context.Context, filter, update interface{}, limit int, upsert bool,
+	arrayFilters *mongo_options.ArrayFilters, sortSpec interface{}) (*mongo.UpdateResult, bson.D, bson.D, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, nil, nil, err
+	}
+
+	if arrayFilters != nil && len(arrayFilters.Filters) > 0 {
+		return nil, nil, nil, fmt.Errorf("%w: array filters", matcher.ErrUnsupported)
+	}
+
+	f, err := normalize(filter)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	u, err := normalize(update)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	now := c.db.server.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	docs, positions, err := c.filter(f)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if sortSpec != nil && len(docs) > 1 {
+		if err := sortDocs(docs, sortSpec); err != nil {
+			return nil, nil, nil, err
+		}
+
+		for i, doc := range docs {
+			positions[i] = c.position(doc)
+		}
+	}
+
+	res := &mongo.UpdateResult{}
+
+	if len(docs) == 0 {
+		if !upsert {
+			return res, nil, nil, nil
+		}
+
+		doc, err := applyUpdate(upsertSeed(f), u, true, now)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		doc = withID(doc)
+
+		if err := c.checkUnique(doc, c.docs, 0); err != nil {
+			return nil, nil, nil, err
+		}
+
+		c.docs = append(c.docs, doc)
+		res.UpsertedCount, res.UpsertedID = 1, idOf(doc)
+
+		return res, nil, clone(doc), nil
+	}
+
+	if limit > 0 && len(docs) > limit {
+		docs, positions = docs[:limit], positions[:limit]
+	}
+
+	var before, after bson.D
+
+	for i, doc := range docs {
+		updated, err := applyUpdate(doc, u, false, now)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		pos := positions[i]
+		others := append(append([]bson.D{}, c.docs[:pos]...), c.docs[pos+1:]...)
+		if err := c.checkUnique(updated, others, 0); err != nil {
+			return nil, nil, nil, err
+		}
+
+		res.MatchedCount++
+		if !matcher.Equal(doc, updated) {
+			res.ModifiedCount++
+		}
+
+		if i == 0 {
+			before, after = clone(doc), clone(updated)
+		}
+
+		c.docs[pos] = updated
+	}
+
+	return res, before, after, nil
+}
+
+// position returns the index of the stored document with the _id of doc. The caller must hold the lock.
+func (c *Collection) position(doc bson.D) int {
+	id := idOf(doc)
+	for i, stored := range c.docs {
+		if matcher.Equal(idOf(stored), id) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// upsertSeed returns the document an upsert starts from: the fields the filter sets with equality conditions.
+func upsertSeed(filter bson.D) bson.D {
+	doc := bson.D{}
+
+	var seed func(filter bson.D)
+	seed = func(filter bson.D) {
+		for _, elem := range filter {
+			if elem.Key == operators.AND {
+				list, _ := elem.Value.(bson.A)
+				for _, item := range list {
+					if sub, ok := item.(bson.D); ok {
+						seed(sub)
+					}
+				}
+				continue
+			}
+
+			if strings.HasPrefix(elem.Key, "$") {
+				continue
+			}
+
+			value := elem.Value
+			if ops, ok := value.(bson.D); ok && len(ops) > 0 && strings.HasPrefix(ops[0].Key, "$") {
+				eq, ok := matcher.Get(ops, operators.EQ)
+				if !ok {
+					continue
+				}
+				value = eq
+			}
+
+			if next, err := setDoc(doc, strings.Split(elem.Key, "."), value); err == nil {
+				doc = next
+			}
+		}
+	}
+	seed(filter)
+
+	return doc
+}
@@ -0,0 +1,68 @@
+package operators
+
+// aggregation expression operators, the ones shared with queries and updates are declared in operators.go
+const (
+	EXPR    = "$expr"
+	LITERAL = "$literal"
+
+	ADD      = "$add"
+	SUBTRACT = "$subtract"
+	MULTIPLY = "$multiply"
+	DIVIDE   = "$divide"
+	ABS      = "$abs"
+	CEIL     = "$ceil"
+	FLOOR    = "$floor"
+	ROUND    = "$round"
+	TRUNC    = "$trunc"
+	POW      = "$pow"
+	SQRT     = "$sqrt"
+	EXP      = "$exp"
+	LN       = "$ln"
+	LOG10    = "$log10"
+
+	CMP = "$cmp"
+
+	CONCAT      = "$concat"
+	TO_LOWER    = "$toLower"
+	TO_UPPER    = "$toUpper"
+	SUBSTR_CP   = "$substrCP"
+	STR_LEN_CP  = "$strLenCP"
+	TRIM        = "$trim"
+	SPLIT       = "$split"
+	REGEX_MATCH = "$regexMatch"
+	REPLACE_ALL = "$replaceAll"
+	TO_STRING   = "$toString"
+
+	DATE_TO_STRING   = "$dateToString"
+	DATE_FROM_STRING = "$dateFromString"
+	DATE_ADD         = "$dateAdd"
+	DATE_DIFF        = "$dateDiff"
+	DATE_TRUNC       = "$dateTrunc"
+	YEAR             = "$year"
+	MONTH            = "$month"
+	DAY_OF_MONTH     = "$dayOfMonth"
+	DAY_OF_WEEK      = "$dayOfWeek"
+	HOUR             = "$hour"
+	MINUTE           = "$minute"
+	SECOND           = "$second"
+
+	ARRAY_ELEM_AT = "$arrayElemAt"
+	CONCAT_ARRAYS = "$concatArrays"
+	FILTER        = "$filter"
+	MAP           = "$map"
+	REDUCE        = "$reduce"
+	IS_ARRAY      = "$isArray"
+	REVERSE_ARRAY = "$reverseArray"
+
+	SET_UNION         = "$setUnion"
+	SET_INTERSECTION  = "$setIntersection"
+	SET_DIFFERENCE    = "$setDifference"
+	SET_EQUALS        = "$setEquals"
+	SET_IS_SUBSET     = "$setIsSubset"
+	ANY_ELEMENT_TRUE  = "$anyElementTrue"
+	ALL_ELEMENTS_TRUE = "$allElementsTrue"
+
+	COND    = "$cond"
+	IF_NULL = "$ifNull"
+	SWITCH  = "$switch"
+)
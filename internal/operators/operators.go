@@ -8,6 +8,71 @@ const (
 	LT  = "$lt"
 	LTE = "$lte"
 	IN  = "$in"
+	NIN = "$nin"
 	AND = "$and"
 	OR  = "$or"
+	NOR = "$nor"
+	NOT = "$not"
+
+	EXISTS = "$exists"
+	TYPE   = "$type"
+
+	MOD     = "$mod"
+	REGEX   = "$regex"
+	OPTIONS = "$options"
+
+	ALL        = "$all"
+	SIZE       = "$size"
+	ELEM_MATCH = "$elemMatch"
+
+	BITS_ALL_SET   = "$bitsAllSet"
+	BITS_ANY_SET   = "$bitsAnySet"
+	BITS_ALL_CLEAR = "$bitsAllClear"
+	BITS_ANY_CLEAR = "$bitsAnyClear"
+
+	SET           = "$set"
+	SET_ON_INSERT = "$setOnInsert"
+	UNSET         = "$unset"
+	INC           = "$inc"
+	MUL           = "$mul"
+	MIN           = "$min"
+	MAX           = "$max"
+	RENAME        = "$rename"
+	CURRENT_DATE  = "$currentDate"
+	PUSH          = "$push"
+	ADD_TO_SET    = "$addToSet"
+	PULL          = "$pull"
+	PULL_ALL      = "$pullAll"
+	POP           = "$pop"
+	EACH          = "$each"
+	SLICE         = "$slice"
+	SORT          = "$sort"
+	POSITION      = "$position"
+
+	MATCH        = "$match"
+	PROJECT      = "$project"
+	GROUP        = "$group"
+	LIMIT        = "$limit"
+	SKIP         = "$skip"
+	UNWIND       = "$unwind"
+	LOOKUP       = "$lookup"
+	ADD_FIELDS   = "$addFields"
+	COUNT        = "$count"
+	FACET        = "$facet"
+	BUCKET       = "$bucket"
+	REPLACE_ROOT = "$replaceRoot"
+	OUT          = "$out"
+	MERGE        = "$merge"
+
+	SUM          = "$sum"
+	AVG          = "$avg"
+	FIRST        = "$first"
+	LAST         = "$last"
+	STD_DEV_POP  = "$stdDevPop"
+	STD_DEV_SAMP = "$stdDevSamp"
+
+	GEO_WITHIN     = "$geoWithin"
+	GEO_INTERSECTS = "$geoIntersects"
+	NEAR           = "$near"
+	NEAR_SPHERE    = "$nearSphere"
 )
@@ -0,0 +1,27 @@
+package mongorm
+
+import (
+	"github.com/v1shn3vsk7/mongorm/internal/matcher"
+)
+
+// Matches reports whether doc, a struct, map, bson.D or bson.Raw, matches the filter rendered by Bson.
+// The filter is evaluated locally following the comparison and type ordering rules of MongoDB,
+// with binary string comparison. ErrUnsupported is returned for operators that need a server,
+// such as $text, $where and geo queries.
+func (q *Query) Matches(doc interface{}) (bool, error) {
+	if q.err != nil {
+		return false, q.err
+	}
+
+	filter, err := matcher.Normalize(q.Bson())
+	if err != nil {
+		return false, err
+	}
+
+	normalized, err := matcher.Normalize(doc)
+	if err != nil {
+		return false, err
+	}
+
+	return matcher.Match(filter, normalized)
+}
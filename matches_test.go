@@ -0,0 +1,68 @@
+package mongorm
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestQueryMatches(t *testing.T) {
+	type address struct {
+		City string `bson:"city"`
+	}
+	type user struct {
+		ID      primitive.ObjectID `bson:"_id"`
+		Name    string             `bson:"name"`
+		Age     int                `bson:"age"`
+		Tags    []string           `bson:"tags"`
+		Address *address           `bson:"address,omitempty"`
+	}
+
+	oid := primitive.NewObjectID()
+	u := user{ID: oid, Name: "ann", Age: 30, Tags: []string{"a", "b"}, Address: &address{City: "Oslo"}}
+
+	tests := []struct {
+		name  string
+		query *Query
+		doc   interface{}
+		want  bool
+	}{
+		{"struct", NewQuery().Where("age", GTE, 18).Where("name", EQ, "ann"), u, true},
+		{"struct pointer", NewQuery().Where("age", LT, 18), &u, false},
+		{"object ids", NewQuery().Where("_id", IN, []primitive.ObjectID{primitive.NewObjectID(), oid}), u, true},
+		{"array element", NewQuery().Where("tags", EQ, "b"), u, true},
+		{"embedded path", NewQuery().Where("address.city", REGEX, regexp.MustCompile("^O")), u, true},
+		{"missing embedded", NewQuery().Where("address.city", EQ, nil), user{}, true},
+		{"or", NewQuery().Where("age", LT, 18).Or().Where("tags", SIZE, 2), u, true},
+		{"number types", NewQuery().Where("n", EQ, 1.0), bson.M{"n": int64(1)}, true},
+		{"type bracket", NewQuery().Where("n", GT, 0), bson.D{{Key: "n", Value: "1"}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.query.Matches(tt.doc)
+			if err != nil {
+				t.Fatalf("Matches: %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("Matches(%v) of %s = %v, want %v", tt.doc, renderJSON(t, tt.query.Bson()), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryMatchesErrors(t *testing.T) {
+	doc := bson.M{"a": 1}
+
+	if _, err := NewQuery().Where("a", SIZE, -1).Matches(doc); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("Matches of an invalid query: error %v, want ErrInvalidValue", err)
+	}
+
+	if _, err := NewQuery().Where("a", NEAR, bson.M{"$geometry": bson.M{"type": "Point", "coordinates": bson.A{0, 0}}}).Matches(doc); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Matches of $near: error %v, want ErrUnsupported", err)
+	}
+}
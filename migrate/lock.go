@@ -0,0 +1,80 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/v1shn3vsk7/mongorm"
+)
+
+// lockID is the _id of the lock document in the migrations collection.
+const lockID = "lock"
+
+// lock takes the lock document, either free or expired, by upserting it. When another instance
+// holds it the filter does not match and the upsert fails on the duplicate _id.
+func (m *Migrator) lock(ctx context.Context) error {
+	now := m.db.Now()
+
+	_, err := m.records.Query().
+		Where("_id", mongorm.EQ, lockID).
+		Group(func(q *mongorm.Query) *mongorm.Query {
+			return q.Where("owner", mongorm.EQ, m.owner).Or().Where("expires_at", mongorm.LT, now)
+		}).
+		Upsert(ctx, m.lockUpdate(now))
+
+	if mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("%w: %s", ErrLocked, m.holder(ctx))
+	}
+
+	return err
+}
+
+// refreshLock extends the lock before a migration runs, failing when it was taken over meanwhile.
+func (m *Migrator) refreshLock(ctx context.Context) error {
+	res, err := m.records.Query().
+		Where("_id", mongorm.EQ, lockID).
+		Where("owner", mongorm.EQ, m.owner).
+		UpdateOne(ctx, m.lockUpdate(m.db.Now()))
+	if err != nil {
+		return err
+	}
+
+	if res.MatchedCount == 0 {
+		return fmt.Errorf("%w: the lock of %s expired and was taken over", ErrLocked, m.owner)
+	}
+
+	return nil
+}
+
+func (m *Migrator) unlock(ctx context.Context) error {
+	_, err := m.records.Query().
+		Where("_id", mongorm.EQ, lockID).
+		Where("owner", mongorm.EQ, m.owner).
+		DeleteOne(ctx)
+
+	return err
+}
+
+func (m *Migrator) lockUpdate(now time.Time) *mongorm.Update {
+	return mongorm.NewUpdate().
+		Set("owner", m.owner).
+		Set("locked_at", now).
+		Set("expires_at", now.Add(m.lockTTL))
+}
+
+// holder describes the instance holding the lock for error messages.
+func (m *Migrator) holder(ctx context.Context) string {
+	var lock struct {
+		Owner     string    `bson:"owner"`
+		ExpiresAt time.Time `bson:"expires_at"`
+	}
+
+	if err := m.records.Query().Where("_id", mongorm.EQ, lockID).One(ctx, &lock); err != nil {
+		return "unknown holder"
+	}
+
+	return fmt.Sprintf("held by %s until %s", lock.Owner, lock.ExpiresAt.Format(time.RFC3339))
+}
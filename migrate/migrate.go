@@ -0,0 +1,335 @@
+// Package migrate applies versioned schema and data migrations written in Go.
+//
+// Migrations are registered with Register, usually from init functions, and applied in version
+// order by a Migrator. Every applied migration is recorded in a collection together with a
+// checksum, and a lock document in the same collection makes sure that only one instance migrates
+// at a time. The default checksum covers the version and description only, so a migration that is
+// renumbered or redescribed after it ran is detected; edits of its functions are only detected
+// when it declares a Checksum of its content.
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/v1shn3vsk7/mongorm"
+	"github.com/v1shn3vsk7/mongorm/options"
+)
+
+// Latest is the target of Migrate that applies every pending migration.
+const Latest int64 = math.MaxInt64
+
+var (
+	// ErrInvalidMigration is returned for migrations without a positive version or an Up function, and for duplicate versions.
+	ErrInvalidMigration = errors.New("mongorm: invalid migration")
+
+	// ErrLocked is returned when another instance holds the migration lock.
+	ErrLocked = errors.New("mongorm: migrations are locked by another instance")
+
+	// ErrChecksumMismatch is returned when the checksum of an applied migration differs from the registered one.
+	ErrChecksumMismatch = errors.New("mongorm: migration checksum mismatch")
+
+	// ErrIrreversible is returned when a migration without a Down function has to be rolled back.
+	ErrIrreversible = errors.New("mongorm: migration cannot be rolled back")
+)
+
+// Migration is a versioned change of the database.
+type Migration struct {
+	// Version orders the migrations, e.g. 1, 2, 3 or a timestamp such as 20240131120000.
+	Version int64
+
+	Description string
+
+	Up func(ctx context.Context, db *mongorm.Database) error
+
+	// Down reverts Up. Migrations without Down cannot be rolled back.
+	Down func(ctx context.Context, db *mongorm.Database) error
+
+	// Checksum identifies the content of the migration. The default is derived from Version and
+	// Description only, since Go functions cannot be hashed; set it, e.g. to a hash of the script the
+	// migration runs, to detect edits of Up and Down.
+	Checksum string
+}
+
+// Status is the state of a migration.
+type Status struct {
+	Version     int64
+	Description string
+	Applied     bool
+	AppliedAt   time.Time
+
+	// Missing is true for applied migrations that are not registered anymore.
+	Missing bool
+}
+
+var (
+	registeredMu sync.Mutex
+	registered   = make(map[int64]Migration)
+)
+
+// Register adds migrations to the ones returned by Registered. It panics if a version is registered twice.
+func Register(migrations ...Migration) {
+	registeredMu.Lock()
+	defer registeredMu.Unlock()
+
+	for _, m := range migrations {
+		if _, ok := registered[m.Version]; ok {
+			panic(fmt.Sprintf("migrate: Register called twice for version %d", m.Version))
+		}
+
+		registered[m.Version] = m
+	}
+}
+
+// Registered returns the migrations added with Register in version order.
+func Registered() []Migration {
+	registeredMu.Lock()
+	defer registeredMu.Unlock()
+
+	migrations := make([]Migration, 0, len(registered))
+	for _, m := range registered {
+		migrations = append(migrations, m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations
+}
+
+// Migrator applies and rolls back migrations on a database.
+type Migrator struct {
+	db         *mongorm.Database
+	migrations []Migration
+	records    *mongorm.TypedCollection[record]
+	lockTTL    time.Duration
+	owner      string
+}
+
+// record is the document of an applied migration. Its _id is the version.
+type record struct {
+	ID          int64     `bson:"_id"`
+	Version     int64     `bson:"version"`
+	Description string    `bson:"description"`
+	Checksum    string    `bson:"checksum"`
+	AppliedAt   time.Time `bson:"applied_at"`
+}
+
+// New returns a Migrator of migrations on db.
+func New(db *mongorm.Database, migrations []Migration, opts ...*options.MigrateOptions) (*Migrator, error) {
+	opt := options.MergeMigrateOptions(opts...)
+
+	m := &Migrator{
+		db:         db,
+		migrations: append([]Migration{}, migrations...),
+		lockTTL:    10 * time.Minute,
+	}
+
+	collection := "_migrations"
+	if opt.Collection != nil {
+		collection = *opt.Collection
+	}
+	m.records = mongorm.CollectionOf[record](db, collection)
+
+	if opt.LockTTL != nil {
+		m.lockTTL = *opt.LockTTL
+	}
+
+	if opt.Owner != nil {
+		m.owner = *opt.Owner
+	} else {
+		host, _ := os.Hostname()
+		m.owner = fmt.Sprintf("%s:%d", host, os.Getpid())
+	}
+
+	sort.Slice(m.migrations, func(i, j int) bool { return m.migrations[i].Version < m.migrations[j].Version })
+
+	for i, mig := range m.migrations {
+		switch {
+		case mig.Version <= 0:
+			return nil, fmt.Errorf("%w: version must be positive, got %d", ErrInvalidMigration, mig.Version)
+		case mig.Up == nil:
+			return nil, fmt.Errorf("%w: version %d has no Up function", ErrInvalidMigration, mig.Version)
+		case i > 0 && m.migrations[i-1].Version == mig.Version:
+			return nil, fmt.Errorf("%w: duplicate version %d", ErrInvalidMigration, mig.Version)
+		}
+	}
+
+	return m, nil
+}
+
+// Migrate applies the pending migrations up to and including the target version, Latest for all of them,
+// in version order, and returns the applied versions. It stops at the first failing migration.
+func (m *Migrator) Migrate(ctx context.Context, target int64) ([]int64, error) {
+	applied := make([]int64, 0)
+
+	err := m.locked(ctx, func(records map[int64]record) error {
+		for _, mig := range m.migrations {
+			if mig.Version > target {
+				break
+			}
+
+			if _, ok := records[mig.Version]; ok {
+				continue
+			}
+
+			if err := m.refreshLock(ctx); err != nil {
+				return err
+			}
+
+			if err := mig.Up(ctx, m.db); err != nil {
+				return fmt.Errorf("mongorm: migration %d up: %w", mig.Version, err)
+			}
+
+			_, err := m.records.InsertOne(ctx, record{
+				ID:          mig.Version,
+				Version:     mig.Version,
+				Description: mig.Description,
+				Checksum:    mig.checksum(),
+				AppliedAt:   m.db.Now(),
+			})
+			if err != nil {
+				return err
+			}
+
+			applied = append(applied, mig.Version)
+		}
+
+		return nil
+	})
+
+	return applied, err
+}
+
+// Rollback reverts the applied migrations with a version above target in reverse version order
+// and returns the reverted versions. Rollback(ctx, 0) reverts every migration.
+func (m *Migrator) Rollback(ctx context.Context, target int64) ([]int64, error) {
+	reverted := make([]int64, 0)
+
+	err := m.locked(ctx, func(records map[int64]record) error {
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			mig := m.migrations[i]
+			if mig.Version <= target {
+				break
+			}
+
+			if _, ok := records[mig.Version]; !ok {
+				continue
+			}
+
+			if mig.Down == nil {
+				return fmt.Errorf("%w: version %d has no Down function", ErrIrreversible, mig.Version)
+			}
+
+			if err := m.refreshLock(ctx); err != nil {
+				return err
+			}
+
+			if err := mig.Down(ctx, m.db); err != nil {
+				return fmt.Errorf("mongorm: migration %d down: %w", mig.Version, err)
+			}
+
+			if _, err := m.records.Query().Where("_id", mongorm.EQ, mig.Version).DeleteOne(ctx); err != nil {
+				return err
+			}
+
+			reverted = append(reverted, mig.Version)
+		}
+
+		return nil
+	})
+
+	return reverted, err
+}
+
+// Status returns the state of the registered migrations and of the applied ones that are not registered, in version order.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	records, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		rec, ok := records[mig.Version]
+		statuses = append(statuses, Status{
+			Version:     mig.Version,
+			Description: mig.Description,
+			Applied:     ok,
+			AppliedAt:   rec.AppliedAt,
+		})
+		delete(records, mig.Version)
+	}
+
+	for _, rec := range records {
+		statuses = append(statuses, Status{
+			Version:     rec.Version,
+			Description: rec.Description,
+			Applied:     true,
+			AppliedAt:   rec.AppliedAt,
+			Missing:     true,
+		})
+	}
+
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+
+	return statuses, nil
+}
+
+// locked runs fn while holding the lock, with the applied migrations after checking their checksums.
+func (m *Migrator) locked(ctx context.Context, fn func(records map[int64]record) error) (err error) {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+
+	defer func() {
+		if unlockErr := m.unlock(ctx); err == nil {
+			err = unlockErr
+		}
+	}()
+
+	records, err := m.applied(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, mig := range m.migrations {
+		if rec, ok := records[mig.Version]; ok && rec.Checksum != mig.checksum() {
+			return fmt.Errorf("%w: version %d was applied with checksum %s, registered %s",
+				ErrChecksumMismatch, mig.Version, rec.Checksum, mig.checksum())
+		}
+	}
+
+	return fn(records)
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[int64]record, error) {
+	recs, err := m.records.All(ctx, m.records.Query().Where("version", mongorm.EXISTS, true))
+	if err != nil {
+		return nil, err
+	}
+
+	records := make(map[int64]record, len(recs))
+	for _, rec := range recs {
+		records[rec.Version] = rec
+	}
+
+	return records, nil
+}
+
+func (mig Migration) checksum() string {
+	if mig.Checksum != "" {
+		return mig.Checksum
+	}
+
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d\x00%s", mig.Version, mig.Description)))
+
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,242 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/v1shn3vsk7/mongorm"
+	"github.com/v1shn3vsk7/mongorm/options"
+)
+
+// clock is a settable clock for the in-memory client.
+type clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *clock) Add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+func testDB(t *testing.T) (*mongorm.Database, *clock) {
+	t.Helper()
+
+	clk := &clock{now: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}
+
+	client, err := mongorm.NewMemoryClient(options.Client().SetClock(clk.Now))
+	if err != nil {
+		t.Fatalf("NewMemoryClient: %v", err)
+	}
+
+	return client.Database("test"), clk
+}
+
+// insert returns a migration inserting a document with _id version into the collection "log".
+func insert(version int64) Migration {
+	return Migration{
+		Version:     version,
+		Description: "insert",
+		Up: func(ctx context.Context, db *mongorm.Database) error {
+			_, err := db.Collection("log").InsertOne(ctx, bson.D{{Key: "_id", Value: version}})
+			return err
+		},
+		Down: func(ctx context.Context, db *mongorm.Database) error {
+			_, err := db.Collection("log").DeleteOne(ctx, bson.D{{Key: "_id", Value: version}})
+			return err
+		},
+	}
+}
+
+func logged(t *testing.T, db *mongorm.Database) int64 {
+	t.Helper()
+
+	n, err := db.Collection("log").CountDocuments(context.Background(), bson.D{})
+	if err != nil {
+		t.Fatalf("CountDocuments: %v", err)
+	}
+
+	return n
+}
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+	db, clk := testDB(t)
+
+	m, err := New(db, []Migration{insert(3), insert(1), insert(2)})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	applied, err := m.Migrate(ctx, 2)
+	if err != nil || !reflect.DeepEqual(applied, []int64{1, 2}) {
+		t.Fatalf("Migrate(2) = %v, %v, want [1 2]", applied, err)
+	}
+
+	clk.Add(time.Hour)
+
+	applied, err = m.Migrate(ctx, Latest)
+	if err != nil || !reflect.DeepEqual(applied, []int64{3}) {
+		t.Fatalf("Migrate(Latest) = %v, %v, want [3]", applied, err)
+	}
+
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+
+	// migrations are stamped with the clock of the client
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	want := []time.Time{start, start, start.Add(time.Hour)}
+	for i, s := range statuses {
+		if !s.Applied || !s.AppliedAt.Equal(want[i]) {
+			t.Errorf("status of %d = %+v, want applied at %v", s.Version, s, want[i])
+		}
+	}
+
+	if n := logged(t, db); n != 3 {
+		t.Errorf("logged %d documents, want 3", n)
+	}
+
+	reverted, err := m.Rollback(ctx, 1)
+	if err != nil || !reflect.DeepEqual(reverted, []int64{3, 2}) {
+		t.Fatalf("Rollback(1) = %v, %v, want [3 2]", reverted, err)
+	}
+
+	if n := logged(t, db); n != 1 {
+		t.Errorf("logged %d documents after the rollback, want 1", n)
+	}
+
+	// the lock was released
+	if n, _ := db.Collection("_migrations").CountDocuments(ctx, bson.D{{Key: "_id", Value: lockID}}); n != 0 {
+		t.Errorf("lock documents = %d, want 0", n)
+	}
+}
+
+func TestMigrateErrors(t *testing.T) {
+	ctx := context.Background()
+	db, _ := testDB(t)
+
+	m, err := New(db, []Migration{insert(1)})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if _, err := m.Migrate(ctx, Latest); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+
+	renamed := insert(1)
+	renamed.Description = "renamed"
+	m, _ = New(db, []Migration{renamed})
+	if _, err := m.Migrate(ctx, Latest); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Migrate after a change of description: error %v, want ErrChecksumMismatch", err)
+	}
+
+	edited := insert(1)
+	edited.Checksum = "v2"
+	m, _ = New(db, []Migration{edited})
+	if _, err := m.Migrate(ctx, Latest); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Migrate after a change of checksum: error %v, want ErrChecksumMismatch", err)
+	}
+
+	irreversible := insert(2)
+	irreversible.Down = nil
+	m, _ = New(db, []Migration{insert(1), irreversible})
+	if _, err := m.Migrate(ctx, Latest); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	if _, err := m.Rollback(ctx, 0); !errors.Is(err, ErrIrreversible) {
+		t.Errorf("Rollback: error %v, want ErrIrreversible", err)
+	}
+
+	failing := Migration{Version: 3, Up: func(context.Context, *mongorm.Database) error { return errors.New("boom") }}
+	m, _ = New(db, []Migration{insert(1), irreversible, failing, insert(4)})
+	if applied, err := m.Migrate(ctx, Latest); err == nil || len(applied) != 0 {
+		t.Errorf("Migrate with a failing migration = %v, %v, want an error before version 4", applied, err)
+	}
+	if n := logged(t, db); n != 2 {
+		t.Errorf("logged %d documents, want 2", n)
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	db, _ := testDB(t)
+
+	tests := []struct {
+		name       string
+		migrations []Migration
+	}{
+		{"zero version", []Migration{{Up: insert(1).Up}}},
+		{"no up", []Migration{{Version: 1}}},
+		{"duplicate version", []Migration{insert(1), insert(1)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(db, tt.migrations); !errors.Is(err, ErrInvalidMigration) {
+				t.Errorf("New: error %v, want ErrInvalidMigration", err)
+			}
+		})
+	}
+}
+
+func TestLock(t *testing.T) {
+	ctx := context.Background()
+	db, clk := testDB(t)
+
+	opts := options.Migrate().SetLockTTL(time.Minute)
+
+	holder, _ := New(db, []Migration{insert(1)}, opts, options.Migrate().SetOwner("holder"))
+	other, _ := New(db, []Migration{insert(1)}, opts, options.Migrate().SetOwner("other"))
+
+	if err := holder.lock(ctx); err != nil {
+		t.Fatalf("lock: %v", err)
+	}
+
+	// the holder may take its own lock again
+	if err := holder.lock(ctx); err != nil {
+		t.Fatalf("lock again: %v", err)
+	}
+
+	if _, err := other.Migrate(ctx, Latest); !errors.Is(err, ErrLocked) {
+		t.Fatalf("Migrate while locked: error %v, want ErrLocked", err)
+	}
+
+	if n := logged(t, db); n != 0 {
+		t.Errorf("logged %d documents while locked, want 0", n)
+	}
+
+	// an expired lock is taken over and its former holder cannot extend it
+	clk.Add(2 * time.Minute)
+
+	if err := other.lock(ctx); err != nil {
+		t.Fatalf("lock after expiry: %v", err)
+	}
+
+	if err := holder.refreshLock(ctx); !errors.Is(err, ErrLocked) {
+		t.Errorf("refreshLock after a takeover: error %v, want ErrLocked", err)
+	}
+
+	if err := other.unlock(ctx); err != nil {
+		t.Fatalf("unlock: %v", err)
+	}
+
+	if applied, err := holder.Migrate(ctx, Latest); err != nil || len(applied) != 1 {
+		t.Errorf("Migrate after unlock = %v, %v, want [1]", applied, err)
+	}
+}
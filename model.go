@@ -0,0 +1,602 @@
+package mongorm
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Model describes a Go struct registered with Client.Register: the collection it is stored in,
+// its primary key, the bson paths of its fields and the indexes declared in its tags.
+//
+// Fields are declared with the mongorm struct tag, a comma separated list of options:
+//
+//	pk           the primary key; implied for the field stored as "_id"
+//	index        an ascending index on the field; index:<name> adds the field to the compound index <name>
+//	unique       a unique index on the field; unique:<name> makes the compound index <name> unique
+//	sparse       the index of the field only holds documents that have the field
+//	desc         the field is indexed in descending order
+//	ttl:<d>      a TTL index removing documents once the date in the field is older than the duration d, e.g. ttl:720h
+//	text         the field is part of the text index of the collection
+//	2dsphere     a geospatial index on the field
+//	required     the validator of the collection requires the field
+//	enum:<a|b>   the validator only accepts the values separated by |, e.g. enum:draft|published
+//	min:<n>      the minimum of a number or the minimum length of a string or array
+//	max:<n>      the maximum of a number or the maximum length of a string or array
+//	createdAt    the time.Time, *time.Time or primitive.DateTime field is set to the current time
+//	             when documents are inserted, unless it is already set
+//	updatedAt    the time.Time, *time.Time or primitive.DateTime field is set to the current time
+//	             when documents are inserted or updated
+//	deletedAt    the documents are soft deleted: deletes set the time.Time, *time.Time or
+//	             primitive.DateTime field, which must be a pointer or omitempty, to the current time
+//	             and queries skip the documents where it is set, neither null nor missing, see
+//	             Query.WithDeleted
+//	version      the int, int32 or int64 field is incremented by every update; queries only check it
+//	             when given the loaded version with Query.Version, units of work always do
+//	pattern:<re> the validator only accepts strings matching the regular expression re; since
+//	             the expression may contain commas, pattern has to be the last option
+//
+// Further indexes are declared by an Indexes() []IndexSpec method of the struct.
+//
+// Relations are fields tagged bson:"-" that hold documents of another registered model, loaded by
+// Query.Preload. Their option names the field holding the reference by Go name or bson path:
+//
+//	belongsTo:<key>  a struct or struct pointer; key is the field of the model holding the _id of the related document
+//	hasOne:<key>     a struct or struct pointer; key is the field of the related model holding the _id of the model
+//	hasMany:<key>    a slice of structs or struct pointers; key is the field of the related model holding the _id of the model
+//	manyToMany:<key> a slice of structs or struct pointers; key is the array field of the model holding the _ids of the related documents
+//
+// The collection name is returned by a CollectionName() string method of the struct or,
+// without one, is the plural snake case of the type name, e.g. "user_profiles" for UserProfile.
+type Model struct {
+	Type       reflect.Type
+	Collection string
+	PK         *Field
+	Fields     []*Field
+	Indexes    []IndexSpec
+
+	// CreatedAt, UpdatedAt and DeletedAt are the fields tagged createdAt, updatedAt and deletedAt, see Client.Now.
+	CreatedAt *Field
+	UpdatedAt *Field
+	DeletedAt *Field
+
+	// Version is the field tagged version.
+	Version *Field
+
+	// Relations are the relation fields of the model, see Query.Preload.
+	Relations []*Relation
+
+	// Schema is the $jsonSchema validator of the collection, see Database.EnsureCollection.
+	Schema bson.D
+
+	byPath     map[string]*Field
+	byName     map[string]*Field
+	byRelation map[string]*Relation
+}
+
+// Field is a field of a model. Fields of nested structs are listed with dotted names and paths.
+type Field struct {
+	// Name is the Go name of the field, e.g. "Address.City".
+	Name string
+
+	// Path is the bson path of the field, e.g. "address.city".
+	Path string
+
+	Type      reflect.Type
+	PK        bool
+	OmitEmpty bool
+
+	index []int
+	tags  map[string]string
+}
+
+// modelTags lists the options of the mongorm struct tag and whether they take a value.
+var modelTags = map[string]bool{
+	"pk":       false,
+	"index":    true,
+	"unique":   true,
+	"sparse":   false,
+	"desc":     false,
+	"ttl":      true,
+	"text":     false,
+	"2dsphere": false,
+	"required": false,
+	"enum":     true,
+	"min":      true,
+	"max":      true,
+	"pattern":  true,
+
+	"createdAt": false,
+	"updatedAt": false,
+	"deletedAt": false,
+	"version":   false,
+
+	"belongsTo":  true,
+	"hasOne":     true,
+	"hasMany":    true,
+	"manyToMany": true,
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Field returns the field with the given bson path or Go name.
+func (m *Model) Field(name string) (*Field, bool) {
+	if f, ok := m.byPath[name]; ok {
+		return f, true
+	}
+
+	f, ok := m.byName[name]
+
+	return f, ok
+}
+
+// Register parses the struct tags of models, given as struct values or pointers, and records them
+// so that collections of these types know their metadata. Registering a type again has no effect.
+func (c *Client) Register(models ...interface{}) error {
+	for _, model := range models {
+		t := reflect.TypeOf(model)
+		for t != nil && t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+
+		if t == nil || t.Kind() != reflect.Struct {
+			return fmt.Errorf("%w: %T is not a struct", ErrInvalidModel, model)
+		}
+
+		if _, ok := c.models.lookup(t); ok {
+			continue
+		}
+
+		m, err := newModel(t)
+		if err != nil {
+			return err
+		}
+
+		c.models.add(m)
+	}
+
+	return nil
+}
+
+// Model returns the registered model of v, a struct value or pointer. A nil client has no models.
+func (c *Client) Model(v interface{}) (*Model, error) {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if c == nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnknownModel, t)
+	}
+
+	m, ok := c.models.lookup(t)
+	if !ok {
+		return nil, fmt.Errorf("%w: %v", ErrUnknownModel, t)
+	}
+
+	return m, nil
+}
+
+// registry holds the models registered with a client.
+type registry struct {
+	mu     sync.RWMutex
+	models map[reflect.Type]*Model
+}
+
+func newRegistry() *registry {
+	return &registry{models: make(map[reflect.Type]*Model)}
+}
+
+func (r *registry) lookup(t reflect.Type) (*Model, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	m, ok := r.models[t]
+
+	return m, ok
+}
+
+// all returns the registered models ordered by collection and type name.
+func (r *registry) all() []*Model {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	models := make([]*Model, 0, len(r.models))
+	for _, m := range r.models {
+		models = append(models, m)
+	}
+
+	sort.Slice(models, func(i, j int) bool {
+		if models[i].Collection != models[j].Collection {
+			return models[i].Collection < models[j].Collection
+		}
+		return models[i].Type.String() < models[j].Type.String()
+	})
+
+	return models
+}
+
+// collection returns the registered model of the collection called name, unless several have it.
+func (r *registry) collection(name string) (*Model, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var found *Model
+	for _, m := range r.models {
+		if m.Collection != name {
+			continue
+		}
+
+		if found != nil {
+			return nil, false
+		}
+		found = m
+	}
+
+	return found, found != nil
+}
+
+func (r *registry) add(m *Model) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.models[m.Type]; !ok {
+		r.models[m.Type] = m
+	}
+}
+
+func newModel(t reflect.Type) (*Model, error) {
+	m := &Model{
+		Type:       t,
+		Collection: collectionName(t),
+		byPath:     make(map[string]*Field),
+		byName:     make(map[string]*Field),
+		byRelation: make(map[string]*Relation),
+	}
+
+	if err := m.parseFields(t, nil, "", ""); err != nil {
+		return nil, err
+	}
+
+	for _, f := range m.Fields {
+		if _, isPK := f.tags["pk"]; isPK && f.Path != "_id" {
+			return nil, fmt.Errorf("%w: %v: primary key %s must be stored as _id, got %q", ErrInvalidModel, t, f.Name, f.Path)
+		}
+
+		if f.Path == "_id" {
+			f.PK = true
+			m.PK = f
+		}
+
+		if _, ok := f.tags["version"]; ok {
+			if !versionKinds[f.Type.Kind()] {
+				return nil, fmt.Errorf("%w: %v: version field %s must be an int, int32 or int64, got %v", ErrInvalidModel, t, f.Name, f.Type)
+			}
+
+			if m.Version != nil {
+				return nil, fmt.Errorf("%w: %v: version is declared by both %s and %s", ErrInvalidModel, t, m.Version.Name, f.Name)
+			}
+			m.Version = f
+		}
+
+		for key, dst := range map[string]**Field{"createdAt": &m.CreatedAt, "updatedAt": &m.UpdatedAt, "deletedAt": &m.DeletedAt} {
+			if _, ok := f.tags[key]; !ok {
+				continue
+			}
+
+			if !timestampTypes[f.Type] {
+				return nil, fmt.Errorf("%w: %v: %s field %s must be a time.Time, *time.Time or primitive.DateTime, got %v", ErrInvalidModel, t, key, f.Name, f.Type)
+			}
+
+			if key == "deletedAt" && f.Type.Kind() != reflect.Pointer && !f.OmitEmpty {
+				return nil, fmt.Errorf("%w: %v: deletedAt field %s must be a pointer or omitempty", ErrInvalidModel, t, f.Name)
+			}
+
+			if *dst != nil {
+				return nil, fmt.Errorf("%w: %v: %s is declared by both %s and %s", ErrInvalidModel, t, key, (*dst).Name, f.Name)
+			}
+			*dst = f
+		}
+	}
+
+	for _, rel := range m.Relations {
+		if err := m.resolveRelation(rel); err != nil {
+			return nil, err
+		}
+	}
+
+	indexes, err := modelIndexes(t, m.Fields)
+	if err != nil {
+		return nil, err
+	}
+	m.Indexes = indexes
+
+	if m.Schema, err = m.objectSchema(map[reflect.Type]bool{t: true}); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// parseFields adds the exported fields of t, found at index below the Go name prefix and bson path prefix.
+func (m *Model) parseFields(t reflect.Type, index []int, name, path string) error {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		key, omitEmpty, inline, skip := bsonTag(sf)
+		if skip {
+			if err := m.parseRelation(sf, append(append([]int{}, index...), i), name); err != nil {
+				return err
+			}
+			continue
+		}
+
+		fieldIndex := append(append([]int{}, index...), i)
+
+		if inline {
+			if sf.Type.Kind() == reflect.Struct {
+				if err := m.parseFields(sf.Type, fieldIndex, name, path); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		tags, err := parseModelTag(sf.Tag.Get("mongorm"))
+		if err != nil {
+			return fmt.Errorf("%w: %v.%s: %v", ErrInvalidModel, m.Type, sf.Name, err)
+		}
+
+		if kind, ok := relationOf(tags); ok {
+			return fmt.Errorf("%w: %v.%s: %s field must be tagged bson:\"-\"", ErrInvalidModel, m.Type, sf.Name, kind)
+		}
+
+		f := &Field{
+			Name:      join(name, sf.Name),
+			Path:      join(path, key),
+			Type:      sf.Type,
+			OmitEmpty: omitEmpty,
+			index:     fieldIndex,
+			tags:      tags,
+		}
+
+		if _, ok := m.byPath[f.Path]; ok {
+			return fmt.Errorf("%w: %v: duplicate bson path %q", ErrInvalidModel, m.Type, f.Path)
+		}
+
+		m.Fields = append(m.Fields, f)
+		m.byPath[f.Path] = f
+		m.byName[f.Name] = f
+
+		if nested(sf.Type) {
+			if err := m.parseFields(sf.Type, fieldIndex, f.Name, f.Path); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// bsonTag reads the bson struct tag the way the driver does: without a name the key is the lowercased field name.
+func bsonTag(sf reflect.StructField) (key string, omitEmpty, inline, skip bool) {
+	tag, ok := sf.Tag.Lookup("bson")
+	if !ok && !strings.Contains(string(sf.Tag), ":") && len(sf.Tag) > 0 {
+		tag = string(sf.Tag)
+	}
+
+	if tag == "-" {
+		return "", false, false, true
+	}
+
+	parts := strings.Split(tag, ",")
+	key = parts[0]
+	for _, opt := range parts[1:] {
+		switch opt {
+		case "omitempty":
+			omitEmpty = true
+		case "inline":
+			inline = true
+		}
+	}
+
+	if key == "" {
+		key = strings.ToLower(sf.Name)
+	}
+
+	return key, omitEmpty, inline, false
+}
+
+// parseModelTag parses the options of a mongorm struct tag.
+func parseModelTag(tag string) (map[string]string, error) {
+	tags := make(map[string]string)
+	if tag == "" {
+		return tags, nil
+	}
+
+	opts := strings.Split(tag, ",")
+	for i, opt := range opts {
+		key, value, hasValue := strings.Cut(strings.TrimSpace(opt), ":")
+
+		// the expression of pattern may contain commas, so it takes the rest of the tag
+		if key == "pattern" {
+			value = strings.Join(append([]string{value}, opts[i+1:]...), ",")
+		}
+
+		takesValue, ok := modelTags[key]
+		switch {
+		case !ok:
+			return nil, fmt.Errorf("unknown mongorm tag option %q", key)
+		case hasValue && !takesValue:
+			return nil, fmt.Errorf("mongorm tag option %q does not take a value", key)
+		case hasValue && value == "":
+			return nil, fmt.Errorf("mongorm tag option %q has an empty value", key)
+		}
+
+		tags[key] = value
+
+		if key == "pattern" {
+			break
+		}
+	}
+
+	return tags, nil
+}
+
+// modelIndexes builds the indexes declared by the tags of fields. Fields sharing an index name
+// form a compound index whose keys follow the order of the fields, and the text fields of the
+// model form its text index.
+func modelIndexes(t reflect.Type, fields []*Field) ([]IndexSpec, error) {
+	var specs []IndexSpec
+	named := make(map[string]int)
+
+	for _, f := range fields {
+		index, hasIndex := f.tags["index"]
+		unique, isUnique := f.tags["unique"]
+		ttl, hasTTL := f.tags["ttl"]
+		_, isText := f.tags["text"]
+		_, isGeo := f.tags["2dsphere"]
+		if !hasIndex && !isUnique && !hasTTL && !isText && !isGeo {
+			continue
+		}
+
+		var key interface{} = 1
+		switch _, desc := f.tags["desc"]; {
+		case isText:
+			key = "text"
+		case isGeo:
+			key = "2dsphere"
+		case desc:
+			key = -1
+		}
+		_, sparse := f.tags["sparse"]
+
+		spec := IndexSpec{
+			Keys:   bson.D{{Key: f.Path, Value: key}},
+			Unique: isUnique,
+			Sparse: sparse,
+		}
+
+		if hasTTL {
+			d, err := time.ParseDuration(ttl)
+			if err != nil || ttl == "" {
+				return nil, fmt.Errorf("%w: %v.%s: ttl requires a duration, e.g. ttl:24h", ErrInvalidModel, t, f.Name)
+			}
+			spec.ExpireAfter = &d
+		}
+
+		name := index
+		if name == "" {
+			name = unique
+		}
+
+		if name == "" && !isText {
+			specs = append(specs, spec)
+			continue
+		}
+
+		// unnamed text fields share the text index, since a collection can only have one
+		i, ok := named[name]
+		if !ok {
+			named[name] = len(specs)
+			specs = append(specs, IndexSpec{Name: name, ExpireAfter: spec.ExpireAfter})
+			i = len(specs) - 1
+		}
+
+		specs[i].Keys = append(specs[i].Keys, spec.Keys...)
+		specs[i].Unique = specs[i].Unique || isUnique
+		specs[i].Sparse = specs[i].Sparse || sparse
+	}
+
+	if indexer, ok := reflect.New(t).Interface().(interface{ Indexes() []IndexSpec }); ok {
+		specs = append(specs, indexer.Indexes()...)
+	}
+
+	return specs, nil
+}
+
+// nested reports whether the fields of a struct of type t are stored as a subdocument whose paths are listed.
+func nested(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct || t == timeType {
+		return false
+	}
+
+	if strings.HasPrefix(t.PkgPath(), "go.mongodb.org/mongo-driver/") {
+		return false
+	}
+
+	return !marshals(t)
+}
+
+// marshals reports whether values of type t encode themselves.
+func marshals(t reflect.Type) bool {
+	for _, marshaler := range []reflect.Type{
+		reflect.TypeOf((*bson.Marshaler)(nil)).Elem(),
+		reflect.TypeOf((*bson.ValueMarshaler)(nil)).Elem(),
+	} {
+		if t.Implements(marshaler) || reflect.PointerTo(t).Implements(marshaler) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// collectionName returns the name returned by the CollectionName method of t or the plural snake case of its name.
+func collectionName(t reflect.Type) string {
+	if namer, ok := reflect.New(t).Interface().(interface{ CollectionName() string }); ok {
+		return namer.CollectionName()
+	}
+
+	return plural(snakeCase(t.Name()))
+}
+
+func snakeCase(s string) string {
+	runes := []rune(s)
+
+	var sb strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			// a new word starts at an upper case letter that follows a lower case one or,
+			// within an acronym such as "HTTPServer", precedes a lower case one
+			if i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1]))) {
+				sb.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		sb.WriteRune(r)
+	}
+
+	return sb.String()
+}
+
+func plural(s string) string {
+	switch {
+	case s == "":
+		return s
+	case strings.HasSuffix(s, "y") && len(s) > 1 && !strings.ContainsAny(s[len(s)-2:len(s)-1], "aeiou"):
+		return s[:len(s)-1] + "ies"
+	case strings.HasSuffix(s, "s"), strings.HasSuffix(s, "x"), strings.HasSuffix(s, "z"),
+		strings.HasSuffix(s, "ch"), strings.HasSuffix(s, "sh"):
+		return s + "es"
+	}
+
+	return s + "s"
+}
+
+func join(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+
+	return prefix + "." + name
+}
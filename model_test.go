@@ -0,0 +1,155 @@
+package mongorm
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type userProfile struct {
+	ID      primitive.ObjectID `bson:"_id" mongorm:"pk"`
+	Email   string             `bson:"email" mongorm:"unique"`
+	First   string             `bson:"first" mongorm:"index:name"`
+	Last    string             `bson:"last" mongorm:"index:name,desc"`
+	Address struct {
+		City string `bson:"city" mongorm:"index"`
+	} `bson:"address"`
+	Audit `bson:",inline"`
+}
+
+type Audit struct {
+	CreatedBy string `bson:"created_by"`
+}
+
+type namedModel struct {
+	ID int `bson:"_id"`
+}
+
+func (namedModel) CollectionName() string {
+	return "named"
+}
+
+func TestRegister(t *testing.T) {
+	client, err := NewMemoryClient()
+	if err != nil {
+		t.Fatalf("NewMemoryClient: %v", err)
+	}
+
+	if err := client.Register(&userProfile{}, namedModel{}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	m, err := client.Model(userProfile{})
+	if err != nil {
+		t.Fatalf("Model: %v", err)
+	}
+
+	if m.Collection != "user_profiles" {
+		t.Errorf("Collection = %q, want user_profiles", m.Collection)
+	}
+
+	if m.PK == nil || m.PK.Name != "ID" {
+		t.Errorf("PK = %+v, want ID", m.PK)
+	}
+
+	for name, path := range map[string]string{"Address.City": "address.city", "CreatedBy": "created_by", "Email": "email"} {
+		f, ok := m.Field(name)
+		if !ok || f.Path != path {
+			t.Errorf("Field(%q) = %+v, want path %q", name, f, path)
+		}
+
+		if f, ok := m.Field(path); !ok || f.Name != name {
+			t.Errorf("Field(%q) = %+v, want name %q", path, f, name)
+		}
+	}
+
+	indexes := make([]string, 0, len(m.Indexes))
+	for _, spec := range m.Indexes {
+		indexes = append(indexes, fmt.Sprintf("%s %s unique=%v", spec.Name, renderJSON(t, spec.Keys), spec.Unique))
+	}
+
+	want := []string{
+		` {"email":1} unique=true`,
+		`name {"first":1,"last":-1} unique=false`,
+		` {"address.city":1} unique=false`,
+	}
+	if !reflect.DeepEqual(indexes, want) {
+		t.Errorf("Indexes = %q, want %q", indexes, want)
+	}
+
+	if m, err := client.Model(&namedModel{}); err != nil || m.Collection != "named" {
+		t.Errorf("Model(namedModel) = %+v, %v, want collection named", m, err)
+	}
+
+	// registering again keeps the model
+	if err := client.Register(userProfile{}); err != nil {
+		t.Fatalf("Register again: %v", err)
+	}
+	if again, _ := client.Model(userProfile{}); again != m {
+		t.Errorf("Model after registering again = %p, want %p", again, m)
+	}
+
+	db := client.Database("test")
+	if c := db.Collection("user_profiles"); c.Model() != m {
+		t.Errorf("Collection(user_profiles).Model() = %v, want the registered model", c.Model())
+	}
+
+	if c := db.Collection("other"); c.Model() != nil {
+		t.Errorf("Collection(other).Model() = %v, want nil", c.Model())
+	}
+
+	if c, err := CollectionFor[*userProfile](db); err != nil || c.Name() != "user_profiles" || c.Model() != m {
+		t.Errorf("CollectionFor = %v, %v, want user_profiles with the registered model", c, err)
+	}
+
+	if _, err := client.Model(time.Time{}); !errors.Is(err, ErrUnknownModel) {
+		t.Errorf("Model of an unregistered type: error %v, want ErrUnknownModel", err)
+	}
+}
+
+func TestRegisterErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		model interface{}
+	}{
+		{"not a struct", 1},
+		{"nil", nil},
+		{"unknown option", struct {
+			A string `mongorm:"indexed"`
+		}{}},
+		{"pk not stored as _id", struct {
+			ID string `bson:"id" mongorm:"pk"`
+		}{}},
+		{"duplicate path", struct {
+			A string `bson:"x"`
+			B string `bson:"x"`
+		}{}},
+		{"deletedAt without pointer or omitempty", struct {
+			Deleted time.Time `bson:"deleted" mongorm:"deletedAt"`
+		}{}},
+		{"version of a string", struct {
+			V string `bson:"v" mongorm:"version"`
+		}{}},
+		{"two createdAt fields", struct {
+			A time.Time `bson:"a" mongorm:"createdAt"`
+			B time.Time `bson:"b" mongorm:"createdAt"`
+		}{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewMemoryClient()
+			if err != nil {
+				t.Fatalf("NewMemoryClient: %v", err)
+			}
+
+			if err := client.Register(tt.model); !errors.Is(err, ErrInvalidModel) {
+				t.Errorf("Register(%T): error %v, want ErrInvalidModel", tt.model, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package mongorm
+
+import (
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	mongo_options "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Select limits the returned documents to fields. It cannot be combined with Exclude,
+// except for excluding "_id". The fields referencing preloaded relations are returned too.
+func (q *Query) Select(fields ...string) *Query {
+	return q.project(1, fields)
+}
+
+// Exclude removes fields from the returned documents, but the ones referencing preloaded relations.
+func (q *Query) Exclude(fields ...string) *Query {
+	return q.project(0, fields)
+}
+
+func (q *Query) project(flag int32, fields []string) *Query {
+	nq := q.Clone()
+	nq.projection = withElems(q.projection, len(fields))
+
+	for _, field := range fields {
+		nq.projection = append(nq.projection, bson.E{Key: field, Value: flag})
+	}
+
+	included, excluded := false, false
+	for _, e := range nq.projection {
+		switch {
+		case e.Value.(int32) == 1:
+			included = true
+		case e.Key != "_id":
+			excluded = true
+		}
+	}
+
+	if included && excluded {
+		nq.setErr(fmt.Errorf("%w: projection cannot both select and exclude fields", ErrInvalidValue))
+	}
+
+	return nq
+}
+
+// Sort orders the result by field. Multiple calls add secondary sort keys in call order.
+func (q *Query) Sort(field string, dir SortDirection) *Query {
+	nq := q.Clone()
+	nq.sort = withElems(q.sort, 1)
+	nq.sort = append(nq.sort, bson.E{Key: field, Value: int32(dir)})
+
+	if dir != ASC && dir != DESC {
+		nq.setErr(fmt.Errorf("%w: sort direction of %q must be ASC or DESC, got %d", ErrInvalidValue, field, dir))
+	}
+
+	return nq
+}
+
+// Limit caps the number of returned documents. Zero means no limit.
+func (q *Query) Limit(n int64) *Query {
+	nq := q.Clone()
+	nq.limit = n
+
+	if n < 0 {
+		nq.setErr(fmt.Errorf("%w: limit must not be negative, got %d", ErrInvalidValue, n))
+	}
+
+	return nq
+}
+
+// Skip skips the first n matched documents.
+func (q *Query) Skip(n int64) *Query {
+	nq := q.Clone()
+	nq.skip = n
+
+	if n < 0 {
+		nq.setErr(fmt.Errorf("%w: skip must not be negative, got %d", ErrInvalidValue, n))
+	}
+
+	return nq
+}
+
+// Hint forces the server to use index, given either by name or by its key document.
+func (q *Query) Hint(index interface{}) *Query {
+	nq := q.Clone()
+	nq.hint = index
+
+	if _, ok := index.(string); !ok && !isDocument(index) {
+		nq.setErr(fmt.Errorf("%w: hint requires an index name or key document, got %T", ErrInvalidValue, index))
+	}
+
+	return nq
+}
+
+// Collation sets the language-specific rules used to compare strings.
+func (q *Query) Collation(collation *mongo_options.Collation) *Query {
+	nq := q.Clone()
+	nq.collation = collation
+
+	return nq
+}
+
+func (q *Query) findOptions() *mongo_options.FindOptions {
+	opts := mongo_options.Find()
+	if projection := q.preloadProjection(); projection != nil {
+		opts.SetProjection(projection)
+	}
+	if q.sort != nil {
+		opts.SetSort(q.sort)
+	}
+	if q.limit > 0 {
+		opts.SetLimit(q.limit)
+	}
+	if q.skip > 0 {
+		opts.SetSkip(q.skip)
+	}
+	if q.hint != nil {
+		opts.SetHint(q.hint)
+	}
+	if q.collation != nil {
+		opts.SetCollation(q.collation)
+	}
+
+	return opts
+}
+
+func (q *Query) findOneOptions() *mongo_options.FindOneOptions {
+	opts := mongo_options.FindOne()
+	if projection := q.preloadProjection(); projection != nil {
+		opts.SetProjection(projection)
+	}
+	if q.sort != nil {
+		opts.SetSort(q.sort)
+	}
+	if q.skip > 0 {
+		opts.SetSkip(q.skip)
+	}
+	if q.hint != nil {
+		opts.SetHint(q.hint)
+	}
+	if q.collation != nil {
+		opts.SetCollation(q.collation)
+	}
+
+	return opts
+}
+
+func (q *Query) countOptions() *mongo_options.CountOptions {
+	opts := mongo_options.Count()
+	if q.limit > 0 {
+		opts.SetLimit(q.limit)
+	}
+	if q.skip > 0 {
+		opts.SetSkip(q.skip)
+	}
+	if q.hint != nil {
+		opts.SetHint(q.hint)
+	}
+	if q.collation != nil {
+		opts.SetCollation(q.collation)
+	}
+
+	return opts
+}
+
+func (q *Query) deleteOptions() *mongo_options.DeleteOptions {
+	opts := mongo_options.Delete()
+	if q.hint != nil {
+		opts.SetHint(q.hint)
+	}
+	if q.collation != nil {
+		opts.SetCollation(q.collation)
+	}
+
+	return opts
+}
+
+func (q *Query) distinctOptions() *mongo_options.DistinctOptions {
+	opts := mongo_options.Distinct()
+	if q.collation != nil {
+		opts.SetCollation(q.collation)
+	}
+
+	return opts
+}
+
+// withElems returns a copy of d with room for n more elements, so that
+// appending to it never changes a document shared with another query.
+func withElems(d bson.D, n int) bson.D {
+	return append(make(bson.D, 0, len(d)+n), d...)
+}
+
+func (q *Query) updateOptions(u *Update) *mongo_options.UpdateOptions {
+	opts := mongo_options.Update()
+	if q.hint != nil {
+		opts.SetHint(q.hint)
+	}
+	if q.collation != nil {
+		opts.SetCollation(q.collation)
+	}
+	if u.arrayFilters != nil {
+		opts.SetArrayFilters(mongo_options.ArrayFilters{Filters: u.arrayFilters})
+	}
+
+	return opts
+}
+
+func (q *Query) findOneAndUpdateOptions(u *Update) *mongo_options.FindOneAndUpdateOptions {
+	opts := mongo_options.FindOneAndUpdate().SetReturnDocument(mongo_options.After)
+	if projection := q.preloadProjection(); projection != nil {
+		opts.SetProjection(projection)
+	}
+	if q.sort != nil {
+		opts.SetSort(q.sort)
+	}
+	if q.hint != nil {
+		opts.SetHint(q.hint)
+	}
+	if q.collation != nil {
+		opts.SetCollation(q.collation)
+	}
+	if u.arrayFilters != nil {
+		opts.SetArrayFilters(mongo_options.ArrayFilters{Filters: u.arrayFilters})
+	}
+
+	return opts
+}
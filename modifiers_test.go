@@ -0,0 +1,66 @@
+package mongorm
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestQueryModifiers(t *testing.T) {
+	c := testCollection(t,
+		`{"_id": 1, "kind": "a", "n": 3, "x": 1}`,
+		`{"_id": 2, "kind": "b", "n": 1, "x": 1}`,
+		`{"_id": 3, "kind": "a", "n": 2, "x": 1}`,
+		`{"_id": 4, "kind": "b", "n": 4, "x": 1}`,
+	)
+
+	tests := []struct {
+		name  string
+		query *Query
+		want  []bson.M
+	}{
+		{"sort", c.Query().Sort("n", ASC).Select("_id"), []bson.M{{"_id": int32(2)}, {"_id": int32(3)}, {"_id": int32(1)}, {"_id": int32(4)}}},
+		{"secondary sort", c.Query().Sort("kind", DESC).Sort("n", ASC).Select("_id"), []bson.M{{"_id": int32(2)}, {"_id": int32(4)}, {"_id": int32(3)}, {"_id": int32(1)}}},
+		{"skip and limit", c.Query().Sort("_id", ASC).Skip(1).Limit(2).Select("_id"), []bson.M{{"_id": int32(2)}, {"_id": int32(3)}}},
+		{"select", c.Query().Where("_id", EQ, 1).Select("kind"), []bson.M{{"_id": int32(1), "kind": "a"}}},
+		{"select without id", c.Query().Where("_id", EQ, 1).Select("kind").Exclude("_id"), []bson.M{{"kind": "a"}}},
+		{"exclude", c.Query().Where("_id", EQ, 1).Exclude("kind", "x"), []bson.M{{"_id": int32(1), "n": int32(3)}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []bson.M
+			if err := tt.query.All(context.Background(), &got); err != nil {
+				t.Fatalf("All: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("All = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryModifierErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		query *Query
+	}{
+		{"select and exclude", NewQuery().Select("a").Exclude("b")},
+		{"sort direction", NewQuery().Sort("a", 2)},
+		{"negative limit", NewQuery().Limit(-1)},
+		{"negative skip", NewQuery().Skip(-1)},
+		{"hint", NewQuery().Hint(1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.query.Err(); !errors.Is(err, ErrInvalidValue) {
+				t.Errorf("Err() = %v, want ErrInvalidValue", err)
+			}
+		})
+	}
+}
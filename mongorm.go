@@ -2,22 +2,39 @@ package mongorm
 
 import (
 	"context"
+	"crypto/rand"
 	"fmt"
+	"time"
 
 	"go.mongodb.org/mongo-driver/mongo"
 	mongo_options "go.mongodb.org/mongo-driver/mongo/options"
 
+	"github.com/v1shn3vsk7/mongorm/internal/memstore"
 	"github.com/v1shn3vsk7/mongorm/options"
 )
 
+// Client is a connection to a server or, when created with NewMemoryClient, an in-memory
+// deployment, in which case the embedded *mongo.Client is nil.
 type Client struct {
 	*mongo.Client
+
+	cursorSecret []byte
+	clock        func() time.Time
+	memory       *memstore.Server
+	models       *registry
 }
 
 func New(ctx context.Context, opts ...*options.ClientOptions) (*Client, error) {
+	c, err := newClient(opts)
+	if err != nil {
+		return nil, err
+	}
+
 	mongoOpts := make([]*mongo_options.ClientOptions, 0, len(opts))
 	for _, opt := range opts {
-		mongoOpts = append(mongoOpts, opt.MongoOptions())
+		if opt != nil {
+			mongoOpts = append(mongoOpts, opt.MongoOptions())
+		}
 	}
 
 	client, err := mongo.Connect(ctx, mongoOpts...)
@@ -25,18 +42,70 @@ func New(ctx context.Context, opts ...*options.ClientOptions) (*Client, error) {
 		return nil, fmt.Errorf("mongorm: err connect to mongo client: %v", err)
 	}
 
-	return &Client{
-		client,
-	}, nil
+	c.Client = client
+
+	return c, nil
+}
+
+// NewMemoryClient returns a client whose databases live in memory, for tests of code built on mongorm.
+// Queries, updates, deletes, counts, sorting, pagination and unique indexes behave like on a server;
+// aggregation supports $match, $sort, $skip, $limit, $project, $count and $lookup with localField
+// and foreignField, and transactions run one at a time. Operations the in-memory store cannot
+// evaluate return ErrUnsupported. Only the cursor secret and the clock of opts are used.
+func NewMemoryClient(opts ...*options.ClientOptions) (*Client, error) {
+	c, err := newClient(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	c.memory = memstore.NewServer()
+	c.memory.Now = c.clock
+
+	return c, nil
+}
+
+func newClient(opts []*options.ClientOptions) (*Client, error) {
+	c := &Client{models: newRegistry(), clock: time.Now}
+
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+
+		if secret := opt.CursorSecret(); secret != nil {
+			c.cursorSecret = secret
+		}
+
+		if clock := opt.Clock(); clock != nil {
+			c.clock = clock
+		}
+	}
+
+	if c.cursorSecret == nil {
+		c.cursorSecret = make([]byte, 32)
+		if _, err := rand.Read(c.cursorSecret); err != nil {
+			return nil, fmt.Errorf("mongorm: err generate cursor secret: %v", err)
+		}
+	}
+
+	return c, nil
 }
 
 func (c *Client) Database(name string, opts ...*options.DatabaseOptions) *Database {
+	if c.memory != nil {
+		return &Database{
+			client: c,
+			memory: c.memory.Database(name),
+		}
+	}
+
 	mongoOpts := make([]*mongo_options.DatabaseOptions, 0, len(opts))
 	for _, opt := range opts {
 		mongoOpts = append(mongoOpts, opt.ToMongoOptions())
 	}
 
 	return &Database{
-		c.Client.Database(name, mongoOpts...),
+		Database: c.Client.Database(name, mongoOpts...),
+		client:   c,
 	}
 }
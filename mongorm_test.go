@@ -0,0 +1,59 @@
+package mongorm
+
+import (
+	"context"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/v1shn3vsk7/mongorm/options"
+)
+
+// newTestDB returns a database of a new in-memory client.
+func newTestDB(t *testing.T, opts ...*options.ClientOptions) *Database {
+	t.Helper()
+
+	client, err := NewMemoryClient(opts...)
+	if err != nil {
+		t.Fatalf("NewMemoryClient: %v", err)
+	}
+
+	return client.Database("test")
+}
+
+// testCollection returns an in-memory collection holding docs, given in extended JSON.
+func testCollection(t *testing.T, docs ...string) *Collection {
+	t.Helper()
+
+	c := newTestDB(t).Collection("c")
+	for _, doc := range docs {
+		if _, err := c.InsertOne(context.Background(), extJSON(t, doc)); err != nil {
+			t.Fatalf("err insert %s: %v", doc, err)
+		}
+	}
+
+	return c
+}
+
+func extJSON(t *testing.T, s string) bson.D {
+	t.Helper()
+
+	doc := bson.D{}
+	if err := bson.UnmarshalExtJSON([]byte(s), false, &doc); err != nil {
+		t.Fatalf("err parse %s: %v", s, err)
+	}
+
+	return doc
+}
+
+// renderJSON returns doc in relaxed extended JSON.
+func renderJSON(t *testing.T, doc interface{}) string {
+	t.Helper()
+
+	data, err := bson.MarshalExtJSON(doc, false, false)
+	if err != nil {
+		t.Fatalf("err marshal %v: %v", doc, err)
+	}
+
+	return string(data)
+}
@@ -28,6 +28,8 @@ import (
 type ClientOptions struct {
 	opts          *mongooptions.ClientOptions
 	externalTools *tools.ExternalTools
+	cursorSecret  []byte
+	clock         func() time.Time
 }
 
 // Client creates a new ClientOptions instance.
@@ -746,6 +748,33 @@ func (c *ClientOptions) SetCaching(ctx context.Context, key any, TTL, cleanup ti
 	return c
 }
 
+// SetCursorSecret specifies the key used to sign the pagination cursors returned by Query.Paginate. Cursors signed with
+// one key are rejected by clients configured with another. The default is a random key generated for every Client, so
+// cursors only stay valid while the process that issued them is running.
+func (c *ClientOptions) SetCursorSecret(secret []byte) *ClientOptions {
+	c.cursorSecret = secret
+
+	return c
+}
+
+// CursorSecret returns the key set by SetCursorSecret.
+func (c *ClientOptions) CursorSecret() []byte {
+	return c.cursorSecret
+}
+
+// SetClock specifies the function returning the current time set in the createdAt, updatedAt and deletedAt fields of models
+// and, on in-memory clients, by $currentDate, so that tests can use a deterministic clock. The default is time.Now.
+func (c *ClientOptions) SetClock(clock func() time.Time) *ClientOptions {
+	c.clock = clock
+
+	return c
+}
+
+// Clock returns the function set by SetClock.
+func (c *ClientOptions) Clock() func() time.Time {
+	return c.clock
+}
+
 func (c *ClientOptions) MongoOptions() *mongooptions.ClientOptions {
 	return c.opts
 }
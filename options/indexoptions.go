@@ -0,0 +1,51 @@
+package options
+
+// SyncIndexesOptions configures Database.SyncIndexes and Collection.SyncIndexes.
+type SyncIndexesOptions struct {
+	// DryRun only returns the planned changes without creating or dropping indexes. The default is false.
+	DryRun *bool
+
+	// DropUndeclared drops the indexes that exist on the server but are not declared. The _id index is never
+	// dropped. The default is false, in which case undeclared indexes are only reported.
+	DropUndeclared *bool
+}
+
+// SyncIndexes creates a new SyncIndexesOptions instance.
+func SyncIndexes() *SyncIndexesOptions {
+	return &SyncIndexesOptions{}
+}
+
+// SetDryRun sets the value for the DryRun field.
+func (s *SyncIndexesOptions) SetDryRun(b bool) *SyncIndexesOptions {
+	s.DryRun = &b
+
+	return s
+}
+
+// SetDropUndeclared sets the value for the DropUndeclared field.
+func (s *SyncIndexesOptions) SetDropUndeclared(b bool) *SyncIndexesOptions {
+	s.DropUndeclared = &b
+
+	return s
+}
+
+// MergeSyncIndexesOptions combines the given SyncIndexesOptions instances into a single SyncIndexesOptions
+// in a last-one-wins fashion.
+func MergeSyncIndexesOptions(opts ...*SyncIndexesOptions) *SyncIndexesOptions {
+	s := SyncIndexes()
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+
+		if opt.DryRun != nil {
+			s.DryRun = opt.DryRun
+		}
+
+		if opt.DropUndeclared != nil {
+			s.DropUndeclared = opt.DropUndeclared
+		}
+	}
+
+	return s
+}
@@ -0,0 +1,67 @@
+package options
+
+import "time"
+
+// MigrateOptions configures a migrate.Migrator.
+type MigrateOptions struct {
+	// Collection records the applied migrations and holds the lock document. The default is "_migrations".
+	Collection *string
+
+	// LockTTL is how long the lock is held without being refreshed before another instance may take it over,
+	// e.g. after a crash. The lock is refreshed before every migration. The default is 10 minutes.
+	LockTTL *time.Duration
+
+	// Owner identifies the instance holding the lock. The default is the host name followed by the process id.
+	Owner *string
+}
+
+// Migrate creates a new MigrateOptions instance.
+func Migrate() *MigrateOptions {
+	return &MigrateOptions{}
+}
+
+// SetCollection sets the value for the Collection field.
+func (m *MigrateOptions) SetCollection(name string) *MigrateOptions {
+	m.Collection = &name
+
+	return m
+}
+
+// SetLockTTL sets the value for the LockTTL field.
+func (m *MigrateOptions) SetLockTTL(d time.Duration) *MigrateOptions {
+	m.LockTTL = &d
+
+	return m
+}
+
+// SetOwner sets the value for the Owner field.
+func (m *MigrateOptions) SetOwner(owner string) *MigrateOptions {
+	m.Owner = &owner
+
+	return m
+}
+
+// MergeMigrateOptions combines the given MigrateOptions instances into a single MigrateOptions
+// in a last-one-wins fashion.
+func MergeMigrateOptions(opts ...*MigrateOptions) *MigrateOptions {
+	m := Migrate()
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+
+		if opt.Collection != nil {
+			m.Collection = opt.Collection
+		}
+
+		if opt.LockTTL != nil {
+			m.LockTTL = opt.LockTTL
+		}
+
+		if opt.Owner != nil {
+			m.Owner = opt.Owner
+		}
+	}
+
+	return m
+}
@@ -0,0 +1,121 @@
+package options
+
+import (
+	"time"
+
+	mongo_options "go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+// TxOptions configures a transaction run by Client.Tx.
+type TxOptions struct {
+	// MaxAttempts is the number of times the transaction is run when it fails with a TransientTransactionError,
+	// and the number of times its commit is tried when the result is unknown. The default is 3.
+	MaxAttempts *int
+
+	// ReadConcern, WriteConcern and ReadPreference of the transaction. The defaults are the ones of the client.
+	ReadConcern    *readconcern.ReadConcern
+	WriteConcern   *writeconcern.WriteConcern
+	ReadPreference *readpref.ReadPref
+
+	// MaxCommitTime limits the time the commit may take on the server.
+	MaxCommitTime *time.Duration
+}
+
+// Tx creates a new TxOptions instance.
+func Tx() *TxOptions {
+	return &TxOptions{}
+}
+
+// SetMaxAttempts sets the value for the MaxAttempts field.
+func (t *TxOptions) SetMaxAttempts(n int) *TxOptions {
+	t.MaxAttempts = &n
+
+	return t
+}
+
+// SetReadConcern sets the value for the ReadConcern field.
+func (t *TxOptions) SetReadConcern(rc *readconcern.ReadConcern) *TxOptions {
+	t.ReadConcern = rc
+
+	return t
+}
+
+// SetWriteConcern sets the value for the WriteConcern field.
+func (t *TxOptions) SetWriteConcern(wc *writeconcern.WriteConcern) *TxOptions {
+	t.WriteConcern = wc
+
+	return t
+}
+
+// SetReadPreference sets the value for the ReadPreference field.
+func (t *TxOptions) SetReadPreference(rp *readpref.ReadPref) *TxOptions {
+	t.ReadPreference = rp
+
+	return t
+}
+
+// SetMaxCommitTime sets the value for the MaxCommitTime field.
+func (t *TxOptions) SetMaxCommitTime(d time.Duration) *TxOptions {
+	t.MaxCommitTime = &d
+
+	return t
+}
+
+// ToMongoOptions returns the transaction options of the driver.
+func (t *TxOptions) ToMongoOptions() *mongo_options.TransactionOptions {
+	opts := mongo_options.Transaction()
+
+	if t.ReadConcern != nil {
+		opts.SetReadConcern(t.ReadConcern)
+	}
+
+	if t.WriteConcern != nil {
+		opts.SetWriteConcern(t.WriteConcern)
+	}
+
+	if t.ReadPreference != nil {
+		opts.SetReadPreference(t.ReadPreference)
+	}
+
+	if t.MaxCommitTime != nil {
+		opts.SetMaxCommitTime(t.MaxCommitTime)
+	}
+
+	return opts
+}
+
+// MergeTxOptions combines the given TxOptions instances into a single TxOptions
+// in a last-one-wins fashion.
+func MergeTxOptions(opts ...*TxOptions) *TxOptions {
+	t := Tx()
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+
+		if opt.MaxAttempts != nil {
+			t.MaxAttempts = opt.MaxAttempts
+		}
+
+		if opt.ReadConcern != nil {
+			t.ReadConcern = opt.ReadConcern
+		}
+
+		if opt.WriteConcern != nil {
+			t.WriteConcern = opt.WriteConcern
+		}
+
+		if opt.ReadPreference != nil {
+			t.ReadPreference = opt.ReadPreference
+		}
+
+		if opt.MaxCommitTime != nil {
+			t.MaxCommitTime = opt.MaxCommitTime
+		}
+	}
+
+	return t
+}
@@ -0,0 +1,354 @@
+package mongorm
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/v1shn3vsk7/mongorm/internal/operators"
+)
+
+const (
+	cursorAfter  = "after"
+	cursorBefore = "before"
+)
+
+// PageRequest selects a page of a keyset-paginated query. At most one of After and Before
+// may be set; when both are empty the first page is returned.
+type PageRequest struct {
+	// After is the Next cursor of the previous page.
+	After string
+	// Before is the Prev cursor of the following page.
+	Before string
+	// Size is the maximal number of documents on the page.
+	Size int64
+}
+
+// PageInfo describes the position of a page. Next and Prev are empty when there is no such page.
+type PageInfo struct {
+	Next    string
+	Prev    string
+	HasNext bool
+	HasPrev bool
+}
+
+type pageCursor struct {
+	Sort   string          `bson:"s"`
+	Mode   string          `bson:"m"`
+	Filter []byte          `bson:"f"`
+	Values []bson.RawValue `bson:"v"`
+}
+
+// fallbackSecret signs the cursors of collections that do not belong to a client.
+var fallbackSecret struct {
+	once   sync.Once
+	secret []byte
+	err    error
+}
+
+// Paginate decodes one page of the documents matched by the query into dst, which must be a pointer to a slice.
+//
+// Pages are ordered by the Sort keys of the query followed by "_id", which is appended when missing so that the
+// order is stable. The keys should never be null or missing and must not be excluded by the projection. Instead of
+// skipping documents the page is selected with a range filter on the keys of the last seen document, carried by
+// the signed, opaque cursors of PageInfo, which are only accepted by queries with the same filter and sort.
+// Skip and Limit of the query are ignored.
+func (q *Query) Paginate(ctx context.Context, req PageRequest, dst interface{}) (*PageInfo, error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+
+	if q.collection == nil || q.collection.storage() == nil {
+		return nil, ErrNoCollection
+	}
+
+	if req.Size <= 0 {
+		return nil, fmt.Errorf("%w: page size must be positive, got %d", ErrInvalidValue, req.Size)
+	}
+
+	if req.After != "" && req.Before != "" {
+		return nil, fmt.Errorf("%w: page request cannot have both After and Before", ErrInvalidValue)
+	}
+
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Slice {
+		return nil, fmt.Errorf("%w: page destination must be a pointer to a slice, got %T", ErrInvalidValue, dst)
+	}
+
+	keys := q.pageKeys()
+	spec := sortSpec(keys)
+
+	secret, err := q.collection.cursorSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	mode, token := cursorAfter, req.After
+	if req.Before != "" {
+		mode, token = cursorBefore, req.Before
+	}
+
+	filter := q.scope(q.Bson())
+
+	// cursors are bound to the filter of the query they were issued for
+	hash, err := filterHash(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if token != "" {
+		cur, err := decodeCursor(secret, token)
+		if err != nil {
+			return nil, err
+		}
+
+		if cur.Mode != mode || cur.Sort != spec || !hmac.Equal(cur.Filter, hash) || len(cur.Values) != len(keys) {
+			return nil, fmt.Errorf("%w: cursor does not belong to this query", ErrInvalidCursor)
+		}
+
+		filter = andFilter(filter, keysetFilter(keys, cur.Values, mode == cursorBefore))
+	}
+
+	// the previous page is read in reverse order starting from its last document
+	sort := keys
+	if mode == cursorBefore {
+		sort = reverseSort(keys)
+	}
+
+	opts := q.findOptions().SetSort(sort).SetLimit(req.Size + 1).SetSkip(0)
+
+	cursor, err := q.collection.storage().Find(ctx, filter, opts)
+	if err != nil {
+		return nil, q.mapErr("paginate", err)
+	}
+	defer cursor.Close(ctx)
+
+	elemType := rv.Elem().Type().Elem()
+	items := reflect.MakeSlice(rv.Elem().Type(), 0, int(req.Size)+1)
+	raws := make([]bson.Raw, 0, req.Size+1)
+
+	for cursor.Next(ctx) {
+		item := reflect.New(elemType)
+		if err := cursor.Decode(item.Interface()); err != nil {
+			return nil, q.mapErr("paginate", err)
+		}
+
+		items = reflect.Append(items, item.Elem())
+		raws = append(raws, append(bson.Raw(nil), cursor.Current...))
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, q.mapErr("paginate", err)
+	}
+
+	more := int64(len(raws)) > req.Size
+	if more {
+		items = items.Slice(0, int(req.Size))
+		raws = raws[:req.Size]
+	}
+
+	if mode == cursorBefore {
+		reverse(items, raws)
+	}
+
+	rv.Elem().Set(items)
+
+	if err := q.found(ctx, dst, false); err != nil {
+		return nil, err
+	}
+
+	info := &PageInfo{}
+	if mode == cursorBefore {
+		info.HasPrev, info.HasNext = more, true
+	} else {
+		info.HasPrev, info.HasNext = token != "", more
+	}
+
+	if len(raws) == 0 {
+		return info, nil
+	}
+
+	if info.HasNext {
+		if info.Next, err = encodeCursor(secret, spec, cursorAfter, hash, keys, raws[len(raws)-1]); err != nil {
+			return nil, err
+		}
+	}
+
+	if info.HasPrev {
+		if info.Prev, err = encodeCursor(secret, spec, cursorBefore, hash, keys, raws[0]); err != nil {
+			return nil, err
+		}
+	}
+
+	return info, nil
+}
+
+// pageKeys returns the sort keys of the query with "_id" as the final tie-breaker.
+func (q *Query) pageKeys() bson.D {
+	for _, key := range q.sort {
+		if key.Key == "_id" {
+			return q.sort
+		}
+	}
+
+	keys := withElems(q.sort, 1)
+
+	return append(keys, bson.E{Key: "_id", Value: int32(ASC)})
+}
+
+func sortSpec(keys bson.D) string {
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, key.Key+":"+strconv.Itoa(int(key.Value.(int32))))
+	}
+
+	return strings.Join(parts, ",")
+}
+
+func reverseSort(keys bson.D) bson.D {
+	reversed := make(bson.D, 0, len(keys))
+	for _, key := range keys {
+		reversed = append(reversed, bson.E{Key: key.Key, Value: -key.Value.(int32)})
+	}
+
+	return reversed
+}
+
+// keysetFilter matches the documents that come after values in the order of keys, or before them if backward is set:
+// (k1 > v1) OR (k1 = v1 AND k2 > v2) OR ...
+func keysetFilter(keys bson.D, values []bson.RawValue, backward bool) bson.D {
+	branches := make(bson.A, 0, len(keys))
+
+	for i, key := range keys {
+		branch := make(bson.D, 0, i+1)
+		for j := 0; j < i; j++ {
+			branch = append(branch, bson.E{Key: keys[j].Key, Value: values[j]})
+		}
+
+		op := operators.GT
+		if (key.Value.(int32) == int32(DESC)) != backward {
+			op = operators.LT
+		}
+
+		branch = append(branch, bson.E{Key: key.Key, Value: bson.D{{Key: op, Value: values[i]}}})
+		branches = append(branches, branch)
+	}
+
+	return bson.D{{Key: operators.OR, Value: branches}}
+}
+
+func andFilter(filter, other bson.D) bson.D {
+	if len(filter) == 0 {
+		return other
+	}
+
+	return bson.D{{Key: operators.AND, Value: bson.A{filter, other}}}
+}
+
+func reverse(items reflect.Value, raws []bson.Raw) {
+	swap := reflect.Swapper(items.Interface())
+	for i, j := 0, len(raws)-1; i < j; i, j = i+1, j-1 {
+		swap(i, j)
+		raws[i], raws[j] = raws[j], raws[i]
+	}
+}
+
+// encodeCursor returns the base64 encoded cursor document followed by its HMAC-SHA256 signature.
+func encodeCursor(secret []byte, spec, mode string, hash []byte, keys bson.D, doc bson.Raw) (string, error) {
+	cur := pageCursor{
+		Sort:   spec,
+		Mode:   mode,
+		Filter: hash,
+		Values: make([]bson.RawValue, 0, len(keys)),
+	}
+
+	for _, key := range keys {
+		val, err := doc.LookupErr(strings.Split(key.Key, ".")...)
+		if err != nil {
+			return "", fmt.Errorf("mongorm: err read sort key %q of page document: %w", key.Key, err)
+		}
+
+		cur.Values = append(cur.Values, val)
+	}
+
+	payload, err := bson.Marshal(cur)
+	if err != nil {
+		return "", fmt.Errorf("mongorm: err encode cursor: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(payload) + "." +
+		base64.RawURLEncoding.EncodeToString(sign(secret, payload)), nil
+}
+
+func decodeCursor(secret []byte, token string) (*pageCursor, error) {
+	encPayload, encSig, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encPayload)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(encSig)
+	if err != nil || !hmac.Equal(sig, sign(secret, payload)) {
+		return nil, ErrInvalidCursor
+	}
+
+	cur := &pageCursor{}
+	if err := bson.Unmarshal(payload, cur); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return cur, nil
+}
+
+// filterHash returns the SHA-256 hash of the encoded filter.
+func filterHash(filter bson.D) ([]byte, error) {
+	if filter == nil {
+		filter = bson.D{}
+	}
+
+	data, err := bson.Marshal(filter)
+	if err != nil {
+		return nil, fmt.Errorf("mongorm: err encode page filter: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+
+	return sum[:], nil
+}
+
+// cursorSecret returns the secret signing the pagination cursors of the collection: the cursor secret
+// of its client or, for collections that do not belong to one, a random secret of the process.
+func (c *Collection) cursorSecret() ([]byte, error) {
+	if c.db != nil && c.db.client != nil {
+		return c.db.client.cursorSecret, nil
+	}
+
+	fallbackSecret.once.Do(func() {
+		fallbackSecret.secret = make([]byte, 32)
+		if _, err := rand.Read(fallbackSecret.secret); err != nil {
+			fallbackSecret.err = fmt.Errorf("mongorm: err generate cursor secret: %v", err)
+		}
+	})
+
+	return fallbackSecret.secret, fallbackSecret.err
+}
+
+func sign(secret, payload []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+
+	return mac.Sum(nil)
+}
@@ -0,0 +1,145 @@
+package mongorm
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/v1shn3vsk7/mongorm/options"
+)
+
+func pageIDs(t *testing.T, q *Query, req PageRequest) ([]int32, *PageInfo) {
+	t.Helper()
+
+	var docs []bson.M
+	info, err := q.Paginate(context.Background(), req, &docs)
+	if err != nil {
+		t.Fatalf("Paginate(%+v): %v", req, err)
+	}
+
+	ids := make([]int32, 0, len(docs))
+	for _, doc := range docs {
+		ids = append(ids, doc["_id"].(int32))
+	}
+
+	return ids, info
+}
+
+func TestPaginate(t *testing.T) {
+	c := testCollection(t,
+		`{"_id": 1, "n": 5}`,
+		`{"_id": 2, "n": 4}`,
+		`{"_id": 3, "n": 4}`,
+		`{"_id": 4, "n": 2}`,
+		`{"_id": 5, "n": 1}`,
+	)
+	q := c.Query().Sort("n", DESC)
+
+	ids, first := pageIDs(t, q, PageRequest{Size: 2})
+	if !reflect.DeepEqual(ids, []int32{1, 2}) || first.HasPrev || !first.HasNext || first.Prev != "" {
+		t.Fatalf("first page = %v %+v, want [1 2] with a next page only", ids, first)
+	}
+
+	ids, second := pageIDs(t, q, PageRequest{After: first.Next, Size: 2})
+	if !reflect.DeepEqual(ids, []int32{3, 4}) || !second.HasPrev || !second.HasNext {
+		t.Fatalf("second page = %v %+v, want [3 4] with both pages", ids, second)
+	}
+
+	ids, last := pageIDs(t, q, PageRequest{After: second.Next, Size: 2})
+	if !reflect.DeepEqual(ids, []int32{5}) || !last.HasPrev || last.HasNext || last.Next != "" {
+		t.Fatalf("last page = %v %+v, want [5] with a previous page only", ids, last)
+	}
+
+	ids, back := pageIDs(t, q, PageRequest{Before: last.Prev, Size: 2})
+	if !reflect.DeepEqual(ids, []int32{3, 4}) || !back.HasPrev || !back.HasNext {
+		t.Fatalf("page before the last = %v %+v, want [3 4] with both pages", ids, back)
+	}
+
+	ids, front := pageIDs(t, q, PageRequest{Before: back.Prev, Size: 2})
+	if !reflect.DeepEqual(ids, []int32{1, 2}) || front.HasPrev || !front.HasNext {
+		t.Fatalf("first page read backward = %v %+v, want [1 2] with a next page only", ids, front)
+	}
+}
+
+func TestPaginateCursors(t *testing.T) {
+	docs := []string{`{"_id": 1, "n": 1}`, `{"_id": 2, "n": 1}`, `{"_id": 3, "n": 2}`}
+	c := testCollection(t, docs...)
+
+	_, info := pageIDs(t, c.Query().Where("n", EQ, 1), PageRequest{Size: 1})
+	next := info.Next
+
+	tampered := []byte(next)
+	tampered[len(tampered)/2] ^= 1
+
+	other := newTestDB(t, options.Client().SetCursorSecret([]byte("other"))).Collection("c")
+	for _, doc := range docs {
+		if _, err := other.InsertOne(context.Background(), extJSON(t, doc)); err != nil {
+			t.Fatalf("err insert %s: %v", doc, err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		query *Query
+		req   PageRequest
+	}{
+		{"tampered", c.Query().Where("n", EQ, 1), PageRequest{After: string(tampered), Size: 1}},
+		{"garbage", c.Query().Where("n", EQ, 1), PageRequest{After: "x", Size: 1}},
+		{"other filter", c.Query().Where("n", EQ, 2), PageRequest{After: next, Size: 1}},
+		{"other sort", c.Query().Where("n", EQ, 1).Sort("n", ASC), PageRequest{After: next, Size: 1}},
+		{"other direction", c.Query().Where("n", EQ, 1), PageRequest{Before: next, Size: 1}},
+		{"other secret", other.Query().Where("n", EQ, 1), PageRequest{After: next, Size: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var docs []bson.M
+			if _, err := tt.query.Paginate(context.Background(), tt.req, &docs); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("Paginate: error %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+
+	// the cursor still reads the next page of its own query
+	ids, _ := pageIDs(t, c.Query().Where("n", EQ, 1), PageRequest{After: next, Size: 1})
+	if !reflect.DeepEqual(ids, []int32{2}) {
+		t.Errorf("next page = %v, want [2]", ids)
+	}
+}
+
+func TestPaginateRequestErrors(t *testing.T) {
+	c := testCollection(t)
+
+	tests := []struct {
+		name string
+		req  PageRequest
+		dst  interface{}
+	}{
+		{"zero size", PageRequest{}, &[]bson.M{}},
+		{"after and before", PageRequest{After: "a", Before: "b", Size: 1}, &[]bson.M{}},
+		{"not a slice", PageRequest{Size: 1}, &bson.M{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := c.Query().Paginate(context.Background(), tt.req, tt.dst); !errors.Is(err, ErrInvalidValue) {
+				t.Errorf("Paginate: error %v, want ErrInvalidValue", err)
+			}
+		})
+	}
+}
+
+func TestPaginateBareCollection(t *testing.T) {
+	// collections built without a database sign their cursors with a secret of the process
+	c := &Collection{store: testCollection(t, `{"_id": 1}`, `{"_id": 2}`).store}
+
+	_, info := pageIDs(t, c.Query(), PageRequest{Size: 1})
+
+	ids, _ := pageIDs(t, c.Query(), PageRequest{After: info.Next, Size: 1})
+	if !reflect.DeepEqual(ids, []int32{2}) {
+		t.Errorf("next page = %v, want [2]", ids)
+	}
+}
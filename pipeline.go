@@ -0,0 +1,418 @@
+package mongorm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/v1shn3vsk7/mongorm/expr"
+	"github.com/v1shn3vsk7/mongorm/internal/operators"
+)
+
+// Pipeline is an immutable builder of aggregation pipelines. Like Query, every method
+// returns a new Pipeline and leaves the receiver untouched.
+type Pipeline struct {
+	collection *Collection
+	stages     []bson.D
+	err        error
+}
+
+// Accumulator computes a field of the documents produced by Group and Bucket.
+type Accumulator struct {
+	Field    string
+	Operator string
+	Expr     interface{}
+}
+
+// UnwindOptions are the optional parameters of the $unwind stage.
+type UnwindOptions struct {
+	// IncludeArrayIndex is the name of the field that receives the index of the element.
+	IncludeArrayIndex string
+	// PreserveNullAndEmptyArrays keeps documents whose array is null, missing or empty.
+	PreserveNullAndEmptyArrays bool
+}
+
+// MergeOptions are the optional parameters of the $merge stage.
+type MergeOptions struct {
+	// On are the fields that identify a document in the output collection.
+	On []string
+	// WhenMatched is one of "replace", "keepExisting", "merge", "fail" or a pipeline.
+	WhenMatched interface{}
+	// WhenNotMatched is one of "insert", "discard" or "fail".
+	WhenNotMatched string
+}
+
+func (c *Collection) Pipeline() *Pipeline {
+	return &Pipeline{
+		collection: c,
+	}
+}
+
+// NewPipeline returns a pipeline that is not bound to a collection, e.g. a sub-pipeline of Facet or LookupPipeline.
+func NewPipeline() *Pipeline {
+	return &Pipeline{}
+}
+
+// Match keeps the documents matched by q. In pipelines of a collection whose model has a deletedAt
+// field, the documents are restricted to the deleted scope of q like queries, see Query.WithDeleted.
+func (p *Pipeline) Match(q *Query) *Pipeline {
+	if q == nil {
+		np := p.with(operators.MATCH, bson.D{})
+		np.setErr(fmt.Errorf("%w: %s requires a non-nil *Query", ErrInvalidValue, operators.MATCH))
+
+		return np
+	}
+
+	np := p.with(operators.MATCH, scopeIn(p.collection, q.deleted, q.Bson()))
+	np.setErr(q.Err())
+
+	return np
+}
+
+// Project reshapes the documents according to spec, a document of fields and inclusion flags or expressions.
+func (p *Pipeline) Project(spec interface{}) *Pipeline {
+	return p.withDocument(operators.PROJECT, spec)
+}
+
+// Group groups the documents by id and computes the fields of every group with accs.
+func (p *Pipeline) Group(id interface{}, accs ...Accumulator) *Pipeline {
+	group := bson.D{{Key: "_id", Value: id}}
+	group = append(group, accumulators(accs)...)
+
+	return p.with(operators.GROUP, group)
+}
+
+// Sort orders the documents by field. Consecutive calls add secondary keys to the same stage.
+func (p *Pipeline) Sort(field string, dir SortDirection) *Pipeline {
+	key := bson.E{Key: field, Value: int32(dir)}
+
+	np := p.with(operators.SORT, bson.D{key})
+
+	if last := len(p.stages) - 1; last >= 0 && len(p.stages[last]) == 1 && p.stages[last][0].Key == operators.SORT {
+		if keys, ok := p.stages[last][0].Value.(bson.D); ok {
+			np = p.clone()
+			np.stages[last] = bson.D{{Key: operators.SORT, Value: append(withElems(keys, 1), key)}}
+		}
+	}
+
+	if dir != ASC && dir != DESC {
+		np.setErr(fmt.Errorf("%w: sort direction of %q must be ASC or DESC, got %d", ErrInvalidValue, field, dir))
+	}
+
+	return np
+}
+
+// Limit passes only the first n documents.
+func (p *Pipeline) Limit(n int64) *Pipeline {
+	np := p.with(operators.LIMIT, n)
+	if n <= 0 {
+		np.setErr(fmt.Errorf("%w: %s must be positive, got %d", ErrInvalidValue, operators.LIMIT, n))
+	}
+
+	return np
+}
+
+// Skip skips the first n documents.
+func (p *Pipeline) Skip(n int64) *Pipeline {
+	np := p.with(operators.SKIP, n)
+	if n < 0 {
+		np.setErr(fmt.Errorf("%w: %s must not be negative, got %d", ErrInvalidValue, operators.SKIP, n))
+	}
+
+	return np
+}
+
+// Unwind outputs a document for every element of the array at path. opts may be nil.
+func (p *Pipeline) Unwind(path string, opts *UnwindOptions) *Pipeline {
+	unwind := bson.D{{Key: "path", Value: "$" + path}}
+
+	if opts != nil {
+		if opts.IncludeArrayIndex != "" {
+			unwind = append(unwind, bson.E{Key: "includeArrayIndex", Value: opts.IncludeArrayIndex})
+		}
+		if opts.PreserveNullAndEmptyArrays {
+			unwind = append(unwind, bson.E{Key: "preserveNullAndEmptyArrays", Value: true})
+		}
+	}
+
+	return p.with(operators.UNWIND, unwind)
+}
+
+// Lookup adds to every document the array as of the documents of from whose foreignField equals localField.
+func (p *Pipeline) Lookup(from, localField, foreignField, as string) *Pipeline {
+	return p.with(operators.LOOKUP, bson.D{
+		{Key: "from", Value: from},
+		{Key: "localField", Value: localField},
+		{Key: "foreignField", Value: foreignField},
+		{Key: "as", Value: as},
+	})
+}
+
+// LookupPipeline adds to every document the array as of the documents of from produced by pipeline.
+// let binds fields of the input document to variables used by pipeline and may be nil.
+func (p *Pipeline) LookupPipeline(from string, let bson.D, pipeline *Pipeline, as string) *Pipeline {
+	if pipeline == nil {
+		pipeline = &Pipeline{err: fmt.Errorf("%w: %s requires a non-nil pipeline", ErrInvalidPipeline, operators.LOOKUP)}
+	}
+
+	lookup := bson.D{{Key: "from", Value: from}}
+	if let != nil {
+		lookup = append(lookup, bson.E{Key: "let", Value: let})
+	}
+	lookup = append(lookup, bson.E{Key: "pipeline", Value: pipeline.Bson()}, bson.E{Key: "as", Value: as})
+
+	np := p.with(operators.LOOKUP, lookup)
+	np.setErr(pipeline.Err())
+
+	return np
+}
+
+// AddFields adds the fields of spec, a document of field names and expressions, to the documents.
+func (p *Pipeline) AddFields(spec interface{}) *Pipeline {
+	return p.withDocument(operators.ADD_FIELDS, spec)
+}
+
+// Count replaces the documents with a single document holding their number in field.
+func (p *Pipeline) Count(field string) *Pipeline {
+	return p.with(operators.COUNT, field)
+}
+
+// Facet runs every sub-pipeline of facets on the same input and outputs their results as arrays under their names.
+func (p *Pipeline) Facet(facets map[string]*Pipeline) *Pipeline {
+	names := make([]string, 0, len(facets))
+	for name := range facets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	facet := make(bson.D, 0, len(facets))
+	errs := make([]error, 0, len(facets))
+
+	for _, name := range names {
+		sub := facets[name]
+		if sub == nil {
+			sub = &Pipeline{err: fmt.Errorf("%w: %s %q requires a non-nil pipeline", ErrInvalidPipeline, operators.FACET, name)}
+		}
+
+		facet = append(facet, bson.E{Key: name, Value: sub.Bson()})
+		errs = append(errs, sub.Err())
+	}
+
+	np := p.with(operators.FACET, facet)
+	for _, err := range errs {
+		np.setErr(err)
+	}
+
+	return np
+}
+
+// Bucket groups the documents by groupBy into the buckets delimited by boundaries, which must be a slice.
+// Documents outside of the boundaries go to the bucket def unless it is nil.
+func (p *Pipeline) Bucket(groupBy, boundaries, def interface{}, accs ...Accumulator) *Pipeline {
+	bucket := bson.D{
+		{Key: "groupBy", Value: groupBy},
+		{Key: "boundaries", Value: boundaries},
+	}
+	if def != nil {
+		bucket = append(bucket, bson.E{Key: "default", Value: def})
+	}
+	if len(accs) > 0 {
+		bucket = append(bucket, bson.E{Key: "output", Value: accumulators(accs)})
+	}
+
+	np := p.with(operators.BUCKET, bucket)
+	if !isList(boundaries) {
+		np.setErr(fmt.Errorf("%w: %s boundaries must be a slice, got %T", ErrInvalidValue, operators.BUCKET, boundaries))
+	}
+
+	return np
+}
+
+// ReplaceRoot replaces every document with newRoot, an expression that resolves to a document.
+func (p *Pipeline) ReplaceRoot(newRoot interface{}) *Pipeline {
+	return p.with(operators.REPLACE_ROOT, bson.D{{Key: "newRoot", Value: newRoot}})
+}
+
+// Out writes the documents to the collection coll, replacing its content. It must be the last stage.
+func (p *Pipeline) Out(coll string) *Pipeline {
+	return p.with(operators.OUT, coll)
+}
+
+// Merge writes the documents into the collection into. It must be the last stage. opts may be nil.
+func (p *Pipeline) Merge(into string, opts *MergeOptions) *Pipeline {
+	merge := bson.D{{Key: "into", Value: into}}
+
+	if opts != nil {
+		if len(opts.On) > 0 {
+			merge = append(merge, bson.E{Key: "on", Value: opts.On})
+		}
+		if opts.WhenMatched != nil {
+			if sub, ok := opts.WhenMatched.(*Pipeline); ok {
+				merge = append(merge, bson.E{Key: "whenMatched", Value: sub.Bson()})
+			} else {
+				merge = append(merge, bson.E{Key: "whenMatched", Value: opts.WhenMatched})
+			}
+		}
+		if opts.WhenNotMatched != "" {
+			merge = append(merge, bson.E{Key: "whenNotMatched", Value: opts.WhenNotMatched})
+		}
+	}
+
+	return p.with(operators.MERGE, merge)
+}
+
+// Stage appends a raw stage, e.g. one without a dedicated method.
+func (p *Pipeline) Stage(stage bson.D) *Pipeline {
+	np := p.clone()
+	np.stages = append(np.stages, stage)
+	np.setErr(expr.Validate(stage))
+
+	if len(stage) != 1 {
+		np.setErr(fmt.Errorf("%w: a stage must have exactly one field, got %d", ErrInvalidPipeline, len(stage)))
+	}
+
+	return np
+}
+
+// Err returns the first error found while building the pipeline.
+func (p *Pipeline) Err() error {
+	if p.err != nil {
+		return p.err
+	}
+
+	for i, stage := range p.stages {
+		switch stage[0].Key {
+		case operators.OUT, operators.MERGE:
+			if i != len(p.stages)-1 {
+				return fmt.Errorf("%w: %s must be the last stage", ErrInvalidPipeline, stage[0].Key)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Bson renders the pipeline, so it can also be passed to the driver directly.
+func (p *Pipeline) Bson() mongo.Pipeline {
+	pipeline := make(mongo.Pipeline, 0, len(p.stages))
+
+	return append(pipeline, p.stages...)
+}
+
+// Aggregate runs the pipeline and decodes its output into dst, which must be a pointer to a slice.
+func (p *Pipeline) Aggregate(ctx context.Context, dst interface{}) error {
+	if err := p.Err(); err != nil {
+		return err
+	}
+
+	if p.collection == nil || p.collection.storage() == nil {
+		return ErrNoCollection
+	}
+
+	cursor, err := p.collection.storage().Aggregate(ctx, p.Bson())
+	if err != nil {
+		return p.collection.mapErr("aggregate", err)
+	}
+
+	return p.collection.mapErr("aggregate", cursor.All(ctx, dst))
+}
+
+func (p *Pipeline) clone() *Pipeline {
+	np := *p
+	np.stages = append(make([]bson.D, 0, len(p.stages)+1), p.stages...)
+
+	return &np
+}
+
+// with returns a copy of the pipeline with the stage {operator: value} appended.
+// Errors of the expressions used by the stage are recorded in the copy.
+func (p *Pipeline) with(operator string, value interface{}) *Pipeline {
+	np := p.clone()
+	np.stages = append(np.stages, bson.D{{Key: operator, Value: value}})
+	np.setErr(expr.Validate(value))
+
+	return np
+}
+
+func (p *Pipeline) withDocument(operator string, spec interface{}) *Pipeline {
+	np := p.with(operator, spec)
+	if !isDocument(spec) {
+		np.setErr(fmt.Errorf("%w: %s requires a document, got %T", ErrInvalidValue, operator, spec))
+	}
+
+	return np
+}
+
+// setErr must only be called on a pipeline that has not been returned to the caller yet.
+func (p *Pipeline) setErr(err error) {
+	if p.err == nil {
+		p.err = err
+	}
+}
+
+// Sum accumulates the sum of expr into field.
+func Sum(field string, expr interface{}) Accumulator {
+	return Accumulator{Field: field, Operator: operators.SUM, Expr: expr}
+}
+
+// Count accumulates the number of documents into field.
+func Count(field string) Accumulator {
+	return Accumulator{Field: field, Operator: operators.SUM, Expr: 1}
+}
+
+// Avg accumulates the average of expr into field.
+func Avg(field string, expr interface{}) Accumulator {
+	return Accumulator{Field: field, Operator: operators.AVG, Expr: expr}
+}
+
+// First accumulates expr of the first document into field.
+func First(field string, expr interface{}) Accumulator {
+	return Accumulator{Field: field, Operator: operators.FIRST, Expr: expr}
+}
+
+// Last accumulates expr of the last document into field.
+func Last(field string, expr interface{}) Accumulator {
+	return Accumulator{Field: field, Operator: operators.LAST, Expr: expr}
+}
+
+// Min accumulates the lowest value of expr into field.
+func Min(field string, expr interface{}) Accumulator {
+	return Accumulator{Field: field, Operator: operators.MIN, Expr: expr}
+}
+
+// Max accumulates the highest value of expr into field.
+func Max(field string, expr interface{}) Accumulator {
+	return Accumulator{Field: field, Operator: operators.MAX, Expr: expr}
+}
+
+// Push accumulates the array of every value of expr into field.
+func Push(field string, expr interface{}) Accumulator {
+	return Accumulator{Field: field, Operator: operators.PUSH, Expr: expr}
+}
+
+// AddToSet accumulates the array of the distinct values of expr into field.
+func AddToSet(field string, expr interface{}) Accumulator {
+	return Accumulator{Field: field, Operator: operators.ADD_TO_SET, Expr: expr}
+}
+
+// StdDevPop accumulates the population standard deviation of expr into field.
+func StdDevPop(field string, expr interface{}) Accumulator {
+	return Accumulator{Field: field, Operator: operators.STD_DEV_POP, Expr: expr}
+}
+
+// StdDevSamp accumulates the sample standard deviation of expr into field.
+func StdDevSamp(field string, expr interface{}) Accumulator {
+	return Accumulator{Field: field, Operator: operators.STD_DEV_SAMP, Expr: expr}
+}
+
+func accumulators(accs []Accumulator) bson.D {
+	doc := make(bson.D, 0, len(accs))
+	for _, acc := range accs {
+		doc = append(doc, bson.E{Key: acc.Field, Value: bson.D{{Key: acc.Operator, Value: acc.Expr}}})
+	}
+
+	return doc
+}
@@ -0,0 +1,109 @@
+package mongorm
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestPipelineBson(t *testing.T) {
+	tests := []struct {
+		name     string
+		pipeline *Pipeline
+		want     string
+	}{
+		{"match", NewPipeline().Match(NewQuery().Where("a", GT, 1)), `[{"$match":{"a":{"$gt":1}}}]`},
+		{"sort keys merge", NewPipeline().Sort("a", ASC).Sort("b", DESC), `[{"$sort":{"a":1,"b":-1}}]`},
+		{"group", NewPipeline().Group("$k", Sum("total", "$n"), Count("count")), `[{"$group":{"_id":"$k","total":{"$sum":"$n"},"count":{"$sum":1}}}]`},
+		{"unwind", NewPipeline().Unwind("tags", &UnwindOptions{PreserveNullAndEmptyArrays: true}), `[{"$unwind":{"path":"$tags","preserveNullAndEmptyArrays":true}}]`},
+		{"lookup", NewPipeline().Lookup("b", "bid", "_id", "bs"), `[{"$lookup":{"from":"b","localField":"bid","foreignField":"_id","as":"bs"}}]`},
+		{"facet", NewPipeline().Facet(map[string]*Pipeline{"y": NewPipeline().Count("n"), "x": NewPipeline().Limit(1)}), `[{"$facet":{"x":[{"$limit":1}],"y":[{"$count":"n"}]}}]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.pipeline.Err(); err != nil {
+				t.Fatalf("Err: %v", err)
+			}
+
+			if got := renderJSON(t, bson.M{"p": tt.pipeline.Bson()}); got != `{"p":`+tt.want+`}` {
+				t.Errorf("Bson() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPipelineErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		pipeline *Pipeline
+		want     error
+	}{
+		{"nil match", NewPipeline().Match(nil), ErrInvalidValue},
+		{"invalid match", NewPipeline().Match(NewQuery().Where("a", SIZE, -1)), ErrInvalidValue},
+		{"zero limit", NewPipeline().Limit(0), ErrInvalidValue},
+		{"project scalar", NewPipeline().Project(1), ErrInvalidValue},
+		{"bucket boundaries", NewPipeline().Bucket("$n", 1, nil), ErrInvalidValue},
+		{"out not last", NewPipeline().Out("x").Limit(1), ErrInvalidPipeline},
+		{"stage with two fields", NewPipeline().Stage(bson.D{{Key: "$limit", Value: 1}, {Key: "$skip", Value: 1}}), ErrInvalidPipeline},
+		{"nil lookup pipeline", NewPipeline().LookupPipeline("b", nil, nil, "bs"), ErrInvalidPipeline},
+		{"nil facet", NewPipeline().Facet(map[string]*Pipeline{"x": nil}), ErrInvalidPipeline},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.pipeline.Err(); !errors.Is(err, tt.want) {
+				t.Errorf("Err() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPipelineAggregate(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+
+	authors := db.Collection("authors")
+	for _, doc := range []string{`{"_id": 1, "name": "x"}`, `{"_id": 2, "name": "y"}`} {
+		if _, err := authors.InsertOne(ctx, extJSON(t, doc)); err != nil {
+			t.Fatalf("err insert %s: %v", doc, err)
+		}
+	}
+
+	posts := db.Collection("posts")
+	for _, doc := range []string{`{"_id": 1, "author": 1, "n": 3}`, `{"_id": 2, "author": 2, "n": 1}`, `{"_id": 3, "author": 1, "n": 2}`} {
+		if _, err := posts.InsertOne(ctx, extJSON(t, doc)); err != nil {
+			t.Fatalf("err insert %s: %v", doc, err)
+		}
+	}
+
+	var got []bson.M
+	err := posts.Pipeline().
+		Match(NewQuery().Where("author", EQ, 1)).
+		Sort("n", ASC).
+		Lookup("authors", "author", "_id", "authors").
+		Project(bson.D{{Key: "authors.name", Value: 1}}).
+		Aggregate(ctx, &got)
+	if err != nil {
+		t.Fatalf("Aggregate: %v", err)
+	}
+
+	want := []bson.M{
+		{"_id": int32(3), "authors": bson.A{bson.M{"name": "x"}}},
+		{"_id": int32(1), "authors": bson.A{bson.M{"name": "x"}}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Aggregate = %v, want %v", got, want)
+	}
+
+	if err := posts.Pipeline().Group("$author").Aggregate(ctx, &got); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Aggregate of $group in memory: error %v, want ErrUnsupported", err)
+	}
+
+	if err := NewPipeline().Aggregate(ctx, &got); !errors.Is(err, ErrNoCollection) {
+		t.Errorf("Aggregate of an unbound pipeline: error %v, want ErrNoCollection", err)
+	}
+}
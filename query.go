@@ -1,16 +1,32 @@
 package mongorm
 
 import (
+	"fmt"
+
 	"go.mongodb.org/mongo-driver/bson"
+	mongo_options "go.mongodb.org/mongo-driver/mongo/options"
 
+	"github.com/v1shn3vsk7/mongorm/expr"
 	"github.com/v1shn3vsk7/mongorm/internal/action"
 	"github.com/v1shn3vsk7/mongorm/internal/operators"
 )
 
+// Query is an immutable filter builder: every builder method returns a new Query and leaves
+// the receiver untouched, so a base query can be shared between goroutines and branched freely.
 type Query struct {
 	collection *Collection
 	actions    []*action.Action
-	query      bson.D
+	err        error
+
+	projection bson.D
+	sort       bson.D
+	limit      int64
+	skip       int64
+	hint       interface{}
+	collation  *mongo_options.Collation
+	deleted    deletedScope
+	version    *int64
+	preloads   []preloadSpec
 }
 
 func (c *Collection) Query() *Query {
@@ -20,93 +36,250 @@ func (c *Collection) Query() *Query {
 	}
 }
 
+// NewQuery returns a query that is not bound to a collection, e.g. the element conditions of ELEMMATCH
+// or the negated conditions of NOT. Inside ELEMMATCH an empty key applies the condition to the array
+// element itself; NOT only takes conditions with an empty key, e.g. NewQuery().Where("", GT, 5).
+func NewQuery() *Query {
+	return &Query{
+		actions: make([]*action.Action, 0),
+	}
+}
+
 func (q *Query) Where(key string, cond CondOperator, value interface{}) *Query {
-	q.actions = append(q.actions, &action.Action{
+	nq := q.with(&action.Action{
 		Type:     action.Where,
 		Key:      key,
 		Operator: cond.Uint8(),
 		Value:    value,
 	})
 
-	return q
+	if err := checkValue(cond, value); err != nil {
+		nq.setErr(fmt.Errorf("%w (key %q)", err, key))
+	}
+
+	return nq
 }
 
-func (q *Query) where(key string, cond CondOperator, value interface{}) {
+func where(key string, cond CondOperator, value interface{}) bson.E {
+	if key == "" {
+		// conditions on the element itself, e.g. {$elemMatch: {$gte: 80, $lt: 85}}
+		return bson.E{Key: cond.String(), Value: operand(cond, value)}
+	}
+
 	elem := bson.E{Key: key}
 
 	switch cond {
 	case EQ:
 		elem.Value = value
-	case NE:
-		elem.Value = bson.D{{Key: operators.NE, Value: value}}
-	case GT:
-		elem.Value = bson.D{{Key: operators.GT, Value: value}}
-	case GTE:
-		elem.Value = bson.D{{Key: operators.GTE, Value: value}}
-	case LT:
-		elem.Value = bson.D{{Key: operators.LT, Value: value}}
-	case LTE:
-		elem.Value = bson.D{{Key: operators.LTE, Value: value}}
+	default:
+		elem.Value = bson.D{{Key: cond.String(), Value: operand(cond, value)}}
 	}
 
-	q.query = append(q.query, elem)
+	return elem
+}
+
+// WhereExpr adds a condition given by an aggregation expression, which can compare fields of the same document.
+func (q *Query) WhereExpr(e expr.Expr) *Query {
+	nq := q.with(&action.Action{
+		Type:  action.Expr,
+		Value: e,
+	})
+	nq.setErr(e.Err())
+
+	return nq
 }
 
+// And joins the previous and the next condition with a logical AND. Consecutive conditions
+// are joined with AND by default, so the call only makes the chain easier to read.
 func (q *Query) And() *Query {
-	q.actions = append(q.actions, &action.Action{
+	return q.with(&action.Action{
 		Type: action.And,
 	})
+}
 
-	return q
+// Or splits the query into alternatives. AND binds tighter than OR, so
+// a.And().b.Or().c.And().d is rendered as (a AND b) OR (c AND d).
+func (q *Query) Or() *Query {
+	return q.with(&action.Action{
+		Type: action.Or,
+	})
 }
 
-func (q *Query) and() {
-	// make new query and copy previous
-	newQuery := make(bson.D, 0, len(q.query)+1)
-	for i := 1; i < len(q.query); i++ {
-		newQuery[i] = q.query[i]
-	}
+// Group adds the conditions built by fn as a single parenthesized term of the query.
+func (q *Query) Group(fn func(*Query) *Query) *Query {
+	sub := q.sub(fn)
 
-	// add $and operator to beginning of the query
-	newQuery[0] = bson.E{Key: operators.AND, Value: newQuery[1:]}
+	nq := q.with(&action.Action{
+		Type:     action.Group,
+		Children: sub.actions,
+	})
+	nq.setErr(sub.err)
 
-	q.query = newQuery
+	return nq
 }
 
-func (q *Query) Or() *Query {
-	q.actions = append(q.actions, &action.Action{
-		Type: action.Or,
+// Nor adds a term that matches documents failing every alternative built by fn.
+func (q *Query) Nor(fn func(*Query) *Query) *Query {
+	sub := q.sub(fn)
+
+	nq := q.with(&action.Action{
+		Type:     action.Nor,
+		Children: sub.actions,
 	})
+	nq.setErr(sub.err)
 
-	return q
+	return nq
 }
 
-func (q *Query) or() {
-	newQuery := make(bson.D, 0, len(q.query)+1)
-	for i := 1; i < len(q.query); i++ {
-		newQuery[i] = q.query[i]
+func (q *Query) sub(fn func(*Query) *Query) *Query {
+	sub := fn(&Query{collection: q.collection})
+	if sub == nil {
+		return &Query{collection: q.collection}
 	}
 
-	newQuery[0] = bson.E{Key: operators.OR, Value: newQuery[1:]}
+	return sub
+}
+
+// Clone returns a copy of the query. Builder methods already return copies, so Clone
+// is only needed to hand out an explicitly separate value.
+func (q *Query) Clone() *Query {
+	nq := *q
+	nq.actions = append(make([]*action.Action, 0, len(q.actions)), q.actions...)
+
+	return &nq
+}
+
+// with returns a copy of the query with act appended. Actions are never modified
+// after they are added, so the copies share them.
+func (q *Query) with(act *action.Action) *Query {
+	nq := *q
+	nq.actions = append(make([]*action.Action, 0, len(q.actions)+1), q.actions...)
+	nq.actions = append(nq.actions, act)
+
+	return &nq
+}
+
+// setErr must only be called on a query that has not been returned to the caller yet.
+func (q *Query) setErr(err error) {
+	if q.err == nil {
+		q.err = err
+	}
+}
 
-	q.query = newQuery
+// Err returns the first error found while building the query, e.g. a value that does not fit its operator.
+func (q *Query) Err() error {
+	return q.err
 }
 
+// Bson renders the filter document. It does not modify the query and is safe for concurrent use.
 func (q *Query) Bson() bson.D {
-	if len(q.actions) == 0 {
-		return q.query
+	return render(q.actions)
+}
+
+// render builds a filter document from the actions. Actions are split into
+// alternatives by Or, and the terms of every alternative are joined by AND.
+func render(actions []*action.Action) bson.D {
+	branches := split(actions)
+
+	switch len(branches) {
+	case 0:
+		return bson.D{}
+	case 1:
+		return conjunction(branches[0])
 	}
 
-	for _, act := range q.actions {
+	return bson.D{{Key: operators.OR, Value: disjunction(branches)}}
+}
+
+func split(actions []*action.Action) [][]*action.Action {
+	branches := make([][]*action.Action, 0, 1)
+	branch := make([]*action.Action, 0, len(actions))
+
+	for _, act := range actions {
 		switch act.Type {
-		case action.Where:
-			q.where(act.Key, CondOperator(act.Operator), act.Value)
-		case action.Or:
-			q.or()
 		case action.And:
-			q.and()
+			continue
+		case action.Or:
+			if len(branch) > 0 {
+				branches = append(branches, branch)
+			}
+			branch = make([]*action.Action, 0, len(actions))
+		default:
+			branch = append(branch, act)
 		}
 	}
 
-	return q.query
+	if len(branch) > 0 {
+		branches = append(branches, branch)
+	}
+
+	return branches
+}
+
+func disjunction(branches [][]*action.Action) bson.A {
+	arr := make(bson.A, 0, len(branches))
+	for _, branch := range branches {
+		arr = append(arr, conjunction(branch))
+	}
+
+	return arr
+}
+
+// conjunction joins the terms with AND. Terms are merged into one document when
+// their keys do not collide, otherwise they are wrapped into $and.
+func conjunction(terms []*action.Action) bson.D {
+	docs := make([]bson.D, 0, len(terms))
+	for _, term := range terms {
+		if doc := renderTerm(term); len(doc) > 0 {
+			docs = append(docs, doc)
+		}
+	}
+
+	switch len(docs) {
+	case 0:
+		return bson.D{}
+	case 1:
+		return docs[0]
+	}
+
+	merged := make(bson.D, 0, len(docs))
+	keys := make(map[string]struct{}, len(docs))
+
+	for _, doc := range docs {
+		for _, elem := range doc {
+			if _, ok := keys[elem.Key]; ok {
+				arr := make(bson.A, 0, len(docs))
+				for _, d := range docs {
+					arr = append(arr, d)
+				}
+
+				return bson.D{{Key: operators.AND, Value: arr}}
+			}
+
+			keys[elem.Key] = struct{}{}
+			merged = append(merged, elem)
+		}
+	}
+
+	return merged
+}
+
+func renderTerm(act *action.Action) bson.D {
+	switch act.Type {
+	case action.Where:
+		return bson.D{where(act.Key, CondOperator(act.Operator), act.Value)}
+	case action.Group:
+		return render(act.Children)
+	case action.Nor:
+		branches := split(act.Children)
+		if len(branches) == 0 {
+			return nil
+		}
+
+		return bson.D{{Key: operators.NOR, Value: disjunction(branches)}}
+	case action.Expr:
+		return bson.D{{Key: operators.EXPR, Value: act.Value}}
+	}
+
+	return nil
 }
@@ -0,0 +1,711 @@
+package mongorm
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	mongo_options "go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/v1shn3vsk7/mongorm/internal/matcher"
+	"github.com/v1shn3vsk7/mongorm/internal/operators"
+)
+
+// RelationKind is the kind of a relation between models.
+type RelationKind int
+
+const (
+	// BelongsTo relations reference one related document by a field of the model.
+	BelongsTo RelationKind = iota + 1
+	// HasOne relations are referenced by one related document.
+	HasOne
+	// HasMany relations are referenced by any number of related documents.
+	HasMany
+	// ManyToMany relations reference any number of related documents by an array field of the model.
+	ManyToMany
+)
+
+var relationKinds = map[string]RelationKind{
+	"belongsTo":  BelongsTo,
+	"hasOne":     HasOne,
+	"hasMany":    HasMany,
+	"manyToMany": ManyToMany,
+}
+
+// String returns the name of the tag option declaring the relation, e.g. "belongsTo".
+func (k RelationKind) String() string {
+	for name, kind := range relationKinds {
+		if kind == k {
+			return name
+		}
+	}
+
+	return fmt.Sprintf("RelationKind(%d)", int(k))
+}
+
+// Relation is a field of a model holding documents of another model, see Model.
+type Relation struct {
+	// Name is the Go name of the field.
+	Name string
+
+	Kind RelationKind
+
+	// Type is the struct type of the related documents, which must be registered to preload them.
+	Type reflect.Type
+
+	// Key is the Go name or bson path of the field holding the references: a field of the model
+	// for BelongsTo and ManyToMany relations, a field of the related model otherwise.
+	Key string
+
+	index []int
+	local *Field
+}
+
+// Relation returns the relation with the given Go name.
+func (m *Model) Relation(name string) (*Relation, bool) {
+	rel, ok := m.byRelation[name]
+
+	return rel, ok
+}
+
+// Preload makes One, All, Paginate, FindOneAndUpdate and the methods of typed collections load the
+// given relations, by the Go names of their fields, into the decoded documents. Every relation is
+// loaded with one query matching the references of all the documents with $in, through which the
+// related documents are scoped and hooked like the documents of any other query.
+func (q *Query) Preload(relations ...string) *Query {
+	return q.withPreloads(relations, false)
+}
+
+// PreloadLookup is Preload for relations joined to the documents by $lookup stages, so that One and
+// All read the documents and their relations in a single aggregation. Other methods load them like
+// Preload. Joining soft deleted models requires MongoDB 5.0, which accepts a pipeline with localField.
+func (q *Query) PreloadLookup(relations ...string) *Query {
+	return q.withPreloads(relations, true)
+}
+
+// preloadSpec is a relation given to Preload or PreloadLookup.
+type preloadSpec struct {
+	relation string
+	lookup   bool
+}
+
+func (q *Query) withPreloads(relations []string, lookup bool) *Query {
+	nq := q.Clone()
+	nq.preloads = append(make([]preloadSpec, 0, len(q.preloads)+len(relations)), q.preloads...)
+
+	for _, name := range relations {
+		nq.preloads = append(nq.preloads, preloadSpec{relation: name, lookup: lookup})
+	}
+
+	return nq
+}
+
+// hasLookups reports whether relations are preloaded by $lookup.
+func (q *Query) hasLookups() bool {
+	for _, p := range q.preloads {
+		if p.lookup {
+			return true
+		}
+	}
+
+	return false
+}
+
+// found completes the documents decoded into dst: it preloads their relations, but the ones
+// already joined by $lookup when joined is set, and calls their AfterFind hooks.
+func (q *Query) found(ctx context.Context, dst interface{}, joined bool) error {
+	if len(q.preloads) > 0 {
+		if err := q.checkPreload(dst); err != nil {
+			return err
+		}
+	}
+
+	for _, p := range q.preloads {
+		if joined && p.lookup {
+			continue
+		}
+
+		pl, err := q.preloader(p.relation)
+		if err != nil {
+			return err
+		}
+
+		if err := pl.load(ctx, documents(dst)); err != nil {
+			return err
+		}
+	}
+
+	return q.mapErr("after find", afterFind(ctx, dst))
+}
+
+// lookup runs the query as an aggregation joining the relations given to PreloadLookup, and
+// decodes the first matched document into dst when one is set or all of them otherwise.
+func (q *Query) lookup(ctx context.Context, dst interface{}, one bool) error {
+	if err := q.checkPreload(dst); err != nil {
+		return err
+	}
+
+	filter, err := q.filter()
+	if err != nil {
+		return err
+	}
+
+	pipeline := []bson.D{{{Key: operators.MATCH, Value: filter}}}
+	if q.sort != nil {
+		pipeline = append(pipeline, bson.D{{Key: operators.SORT, Value: q.sort}})
+	}
+	if q.skip > 0 {
+		pipeline = append(pipeline, bson.D{{Key: operators.SKIP, Value: q.skip}})
+	}
+	if one {
+		pipeline = append(pipeline, bson.D{{Key: operators.LIMIT, Value: int64(1)}})
+	} else if q.limit > 0 {
+		pipeline = append(pipeline, bson.D{{Key: operators.LIMIT, Value: q.limit}})
+	}
+
+	var (
+		preloaders []*preloader
+		joins      []string
+	)
+	for _, p := range q.preloads {
+		if !p.lookup {
+			continue
+		}
+
+		pl, err := q.preloader(p.relation)
+		if err != nil {
+			return err
+		}
+
+		preloaders = append(preloaders, pl)
+		joins = append(joins, pl.as())
+		pipeline = append(pipeline, bson.D{{Key: operators.LOOKUP, Value: pl.lookupStage()}})
+	}
+
+	// the projection follows the lookups, which need the references it may leave out
+	if projection := q.preloadProjection(joins...); projection != nil {
+		pipeline = append(pipeline, bson.D{{Key: operators.PROJECT, Value: projection}})
+	}
+
+	opts := mongo_options.Aggregate()
+	if q.hint != nil {
+		opts.SetHint(q.hint)
+	}
+	if q.collation != nil {
+		opts.SetCollation(q.collation)
+	}
+
+	cursor, err := q.collection.storage().Aggregate(ctx, pipeline, opts)
+	if err != nil {
+		return q.mapErr("aggregate", err)
+	}
+
+	var raws []bson.Raw
+	if err := cursor.All(ctx, &raws); err != nil {
+		return q.mapErr("aggregate", err)
+	}
+
+	if one && len(raws) == 0 {
+		return fmt.Errorf("%w: find one %s", ErrNotFound, q.collection.Name())
+	}
+
+	if err := decodeRaws(raws, dst, one); err != nil {
+		return q.mapErr("decode", err)
+	}
+
+	docs := documents(dst)
+	for _, pl := range preloaders {
+		for i, doc := range docs {
+			joined := reflect.New(reflect.SliceOf(pl.rel.Type))
+			if err := raws[i].Lookup(pl.as()).Unmarshal(joined.Interface()); err != nil {
+				return q.mapErr("decode", err)
+			}
+
+			if err := afterFind(ctx, joined.Interface()); err != nil {
+				return q.mapErr("after find", err)
+			}
+
+			pl.assign([]reflect.Value{doc}, joined.Elem())
+		}
+	}
+
+	return q.found(ctx, dst, true)
+}
+
+// preloadProjection returns the projection of the query keeping the references the documents of
+// the preloaded relations are matched by, and the fields in keep: selected when the projection
+// selects fields and no longer excluded otherwise. It is nil when the query has no projection
+// or when nothing remains excluded.
+func (q *Query) preloadProjection(keep ...string) bson.D {
+	if q.projection == nil || len(q.preloads) == 0 && len(keep) == 0 {
+		return q.projection
+	}
+
+	keep = append([]string{}, keep...)
+	for _, p := range q.preloads {
+		// the errors of unknown relations are returned when they are loaded
+		if pl, err := q.preloader(p.relation); err == nil {
+			keep = append(keep, pl.localField())
+		}
+	}
+
+	selects := false
+	for _, e := range q.projection {
+		if e.Value.(int32) == 1 {
+			selects = true
+		}
+	}
+
+	projection := bson.D{}
+	for _, e := range q.projection {
+		kept := false
+		for _, k := range keep {
+			// fields inside kept ones are kept with them, and kept fields are not excluded
+			if selects && strings.HasPrefix(e.Key, k+".") || !selects && overlaps(e.Key, k) || e.Key == "_id" && k == "_id" {
+				kept = true
+			}
+		}
+
+		if !kept {
+			projection = append(projection, e)
+		}
+	}
+
+	if selects {
+		for _, k := range keep {
+			if !selectsPath(projection, k) {
+				projection = append(projection, bson.E{Key: k, Value: int32(1)})
+			}
+		}
+	}
+
+	if len(projection) == 0 {
+		return nil
+	}
+
+	return projection
+}
+
+// selectsPath reports whether projection selects path or one of its parents.
+func selectsPath(projection bson.D, path string) bool {
+	for _, e := range projection {
+		if e.Value.(int32) == 1 && (e.Key == path || strings.HasPrefix(path, e.Key+".")) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// decodeRaws decodes the first document of raws into dst when one is set, or all of them into
+// dst, a pointer to a slice, otherwise.
+func decodeRaws(raws []bson.Raw, dst interface{}, one bool) error {
+	if one {
+		return bson.Unmarshal(raws[0], dst)
+	}
+
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("%w: destination must be a pointer to a slice, got %T", ErrInvalidValue, dst)
+	}
+
+	items := reflect.MakeSlice(rv.Elem().Type(), len(raws), len(raws))
+	for i, raw := range raws {
+		if err := bson.Unmarshal(raw, items.Index(i).Addr().Interface()); err != nil {
+			return err
+		}
+	}
+	rv.Elem().Set(items)
+
+	return nil
+}
+
+// preloader loads a relation of the documents of a query.
+type preloader struct {
+	rel *Relation
+
+	// owner and related are the models of the documents of the query and of the relation.
+	owner   *Model
+	related *Model
+
+	// collection is the collection of the related documents.
+	collection *Collection
+
+	// key is the field holding the references: a field of the owner for BelongsTo and
+	// ManyToMany, a field of the related model otherwise.
+	key *Field
+}
+
+// preloader returns the preloader of the relation of the documents of the query called name.
+func (q *Query) preloader(name string) (*preloader, error) {
+	if q.collection == nil || q.collection.model == nil {
+		return nil, fmt.Errorf("%w: the documents of the query have no relations to preload", ErrInvalidModel)
+	}
+
+	owner := q.collection.model
+
+	rel, ok := owner.Relation(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %v has no relation %q", ErrInvalidModel, owner.Type, name)
+	}
+
+	related, ok := q.collection.db.client.models.lookup(rel.Type)
+	if !ok {
+		return nil, fmt.Errorf("%w: %v", ErrUnknownModel, rel.Type)
+	}
+
+	pl := &preloader{rel: rel, owner: owner, related: related, key: rel.local}
+
+	switch rel.Kind {
+	case BelongsTo, ManyToMany:
+		if related.PK == nil {
+			return nil, fmt.Errorf("%w: %v has no primary key", ErrInvalidModel, related.Type)
+		}
+	case HasOne, HasMany:
+		if owner.PK == nil {
+			return nil, fmt.Errorf("%w: %v has no primary key", ErrInvalidModel, owner.Type)
+		}
+
+		if pl.key, ok = related.Field(rel.Key); !ok {
+			return nil, fmt.Errorf("%w: %v: %s key %q of %s is not a field of %v", ErrInvalidModel, owner.Type, rel.Kind, rel.Key, rel.Name, related.Type)
+		}
+	}
+
+	pl.collection = q.collection.db.Collection(related.Collection)
+	pl.collection.model, pl.collection.docType = related, related.Type
+
+	return pl, nil
+}
+
+// load loads the related documents of docs with one $in query and assigns them.
+func (pl *preloader) load(ctx context.Context, docs []reflect.Value) error {
+	refs := make([]interface{}, 0, len(docs))
+	seen := make(map[interface{}]bool)
+
+	for _, doc := range docs {
+		for _, ref := range pl.references(doc) {
+			if k := relationKey(ref); !seen[k] {
+				seen[k] = true
+				refs = append(refs, ref)
+			}
+		}
+	}
+
+	found := reflect.New(reflect.SliceOf(pl.rel.Type))
+	if len(refs) > 0 {
+		err := pl.collection.Query().Where(pl.foreignField(), IN, refs).All(ctx, found.Interface())
+		if err != nil {
+			return err
+		}
+	}
+
+	pl.assign(docs, found.Elem())
+
+	return nil
+}
+
+// assign sets the relation field of docs to the documents of found that reference them or they reference.
+func (pl *preloader) assign(docs []reflect.Value, found reflect.Value) {
+	byKey := make(map[interface{}][]reflect.Value)
+	for i := 0; i < found.Len(); i++ {
+		doc := found.Index(i)
+
+		var keys []interface{}
+		switch pl.rel.Kind {
+		case BelongsTo, ManyToMany:
+			keys = fieldValues(doc, pl.related.PK)
+		default:
+			keys = fieldValues(doc, pl.key)
+		}
+
+		for _, k := range keys {
+			k = relationKey(k)
+			byKey[k] = append(byKey[k], doc)
+		}
+	}
+
+	for _, doc := range docs {
+		var related []reflect.Value
+		for _, ref := range pl.references(doc) {
+			related = append(related, byKey[relationKey(ref)]...)
+		}
+
+		field := doc.FieldByIndex(pl.rel.index)
+
+		switch pl.rel.Kind {
+		case BelongsTo, HasOne:
+			field.Set(reflect.Zero(field.Type()))
+			if len(related) > 0 {
+				field.Set(relatedValue(field.Type(), related[0]))
+			}
+		default:
+			items := reflect.MakeSlice(field.Type(), 0, len(related))
+			for _, r := range related {
+				items = reflect.Append(items, relatedValue(field.Type().Elem(), r))
+			}
+			field.Set(items)
+		}
+	}
+}
+
+// references returns the values doc is referenced by or references.
+func (pl *preloader) references(doc reflect.Value) []interface{} {
+	switch pl.rel.Kind {
+	case BelongsTo, ManyToMany:
+		return fieldValues(doc, pl.key)
+	}
+
+	return fieldValues(doc, pl.owner.PK)
+}
+
+// foreignField is the path of the related documents matched by the references.
+func (pl *preloader) foreignField() string {
+	switch pl.rel.Kind {
+	case BelongsTo, ManyToMany:
+		return "_id"
+	}
+
+	return pl.key.Path
+}
+
+// localField is the path of the references in the documents of the query.
+func (pl *preloader) localField() string {
+	switch pl.rel.Kind {
+	case BelongsTo, ManyToMany:
+		return pl.key.Path
+	}
+
+	return "_id"
+}
+
+// as is the field the $lookup stage of the relation joins the related documents into.
+func (pl *preloader) as() string {
+	return "_preload_" + pl.rel.Name
+}
+
+func (pl *preloader) lookupStage() bson.D {
+	stage := bson.D{
+		{Key: "from", Value: pl.related.Collection},
+		{Key: "localField", Value: pl.localField()},
+		{Key: "foreignField", Value: pl.foreignField()},
+	}
+
+	if f := pl.related.DeletedAt; f != nil {
+		stage = append(stage, bson.E{Key: "pipeline", Value: bson.A{bson.D{{Key: operators.MATCH, Value: notDeleted(f)}}}})
+	}
+
+	return append(stage, bson.E{Key: "as", Value: pl.as()})
+}
+
+// parseRelation records the relation declared by the tag of sf, a field that is not stored.
+func (m *Model) parseRelation(sf reflect.StructField, index []int, name string) error {
+	tags, err := parseModelTag(sf.Tag.Get("mongorm"))
+	if err != nil {
+		return fmt.Errorf("%w: %v.%s: %v", ErrInvalidModel, m.Type, sf.Name, err)
+	}
+
+	kind, ok := relationOf(tags)
+	if !ok {
+		return nil
+	}
+
+	for option, other := range relationKinds {
+		if _, ok := tags[option]; ok && other != kind {
+			return fmt.Errorf("%w: %v.%s: a field declares one relation, got %s and %s", ErrInvalidModel, m.Type, sf.Name, kind, other)
+		}
+	}
+
+	if name != "" {
+		return fmt.Errorf("%w: %v.%s: relations must be fields of the model, not of a nested struct", ErrInvalidModel, m.Type, join(name, sf.Name))
+	}
+
+	t, want := sf.Type, "a struct or struct pointer"
+	if kind == HasMany || kind == ManyToMany {
+		want = "a slice of structs or struct pointers"
+		if t.Kind() != reflect.Slice {
+			return fmt.Errorf("%w: %v.%s: %s field must be %s, got %v", ErrInvalidModel, m.Type, sf.Name, kind, want, sf.Type)
+		}
+		t = t.Elem()
+	}
+
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct {
+		return fmt.Errorf("%w: %v.%s: %s field must be %s, got %v", ErrInvalidModel, m.Type, sf.Name, kind, want, sf.Type)
+	}
+
+	rel := &Relation{
+		Name:  sf.Name,
+		Kind:  kind,
+		Type:  t,
+		Key:   tags[kind.String()],
+		index: index,
+	}
+
+	m.Relations = append(m.Relations, rel)
+	m.byRelation[rel.Name] = rel
+
+	return nil
+}
+
+// resolveRelation finds the field of the model holding the references of a BelongsTo or ManyToMany relation.
+func (m *Model) resolveRelation(rel *Relation) error {
+	if rel.Kind != BelongsTo && rel.Kind != ManyToMany {
+		return nil
+	}
+
+	f, ok := m.Field(rel.Key)
+	if !ok {
+		return fmt.Errorf("%w: %v: %s key %q of %s is not a field", ErrInvalidModel, m.Type, rel.Kind, rel.Key, rel.Name)
+	}
+
+	if t := f.Type; rel.Kind == ManyToMany && t.Kind() != reflect.Slice && (t.Kind() != reflect.Pointer || t.Elem().Kind() != reflect.Slice) {
+		return fmt.Errorf("%w: %v: manyToMany key %s of %s must be a slice, got %v", ErrInvalidModel, m.Type, f.Name, rel.Name, t)
+	}
+
+	rel.local = f
+
+	return nil
+}
+
+// relationOf returns the kind of the relation declared by the options of a mongorm tag.
+func relationOf(tags map[string]string) (RelationKind, bool) {
+	for _, option := range []string{"belongsTo", "hasOne", "hasMany", "manyToMany"} {
+		if _, ok := tags[option]; ok {
+			return relationKinds[option], true
+		}
+	}
+
+	return 0, false
+}
+
+// checkPreload reports whether relations can be preloaded into dst: the documents of untyped
+// collections may be decoded into other types than the struct of the model.
+func (q *Query) checkPreload(dst interface{}) error {
+	if q.collection == nil || q.collection.model == nil {
+		return nil
+	}
+
+	t := reflect.TypeOf(dst)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t != nil && t.Kind() == reflect.Slice {
+		t = t.Elem()
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+	}
+
+	if t != q.collection.model.Type {
+		return fmt.Errorf("%w: relations of %v are preloaded into its documents, got %T", ErrInvalidValue, q.collection.model.Type, dst)
+	}
+
+	return nil
+}
+
+// documents returns the addressable structs decoded into dst, a pointer to a struct or to a slice
+// of structs or struct pointers.
+func documents(dst interface{}) []reflect.Value {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return nil
+	}
+
+	v = v.Elem()
+	for v.Kind() == reflect.Pointer && !v.IsNil() {
+		v = v.Elem()
+	}
+
+	if v.Kind() == reflect.Struct {
+		return []reflect.Value{v}
+	}
+
+	if v.Kind() != reflect.Slice {
+		return nil
+	}
+
+	docs := make([]reflect.Value, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		item := v.Index(i)
+		for item.Kind() == reflect.Pointer && !item.IsNil() {
+			item = item.Elem()
+		}
+
+		if item.Kind() == reflect.Struct {
+			docs = append(docs, item)
+		}
+	}
+
+	return docs
+}
+
+// fieldValues returns the non-zero values of the field f of doc, with slices expanded.
+func fieldValues(doc reflect.Value, f *Field) []interface{} {
+	v, err := doc.FieldByIndexErr(f.index)
+	if err != nil {
+		return nil
+	}
+
+	for v.Kind() == reflect.Pointer && !v.IsNil() {
+		v = v.Elem()
+	}
+
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		values := make([]interface{}, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			if elem := v.Index(i); !elem.IsZero() {
+				values = append(values, elem.Interface())
+			}
+		}
+
+		return values
+	}
+
+	if v.Kind() == reflect.Pointer || v.IsZero() {
+		return nil
+	}
+
+	return []interface{}{v.Interface()}
+}
+
+// relationKey returns a comparable key of a reference, equal for the values the server considers
+// equal, e.g. numbers of different types.
+func relationKey(ref interface{}) interface{} {
+	doc, err := matcher.Normalize(bson.D{{Key: "k", Value: ref}})
+	if err != nil || len(doc) != 1 {
+		return fmt.Sprintf("%T:%v", ref, ref)
+	}
+
+	k := doc[0].Value
+	if k == nil {
+		return nil
+	}
+
+	if f, ok := matcher.ToFloat(k); ok {
+		return f
+	}
+
+	if !reflect.TypeOf(k).Comparable() {
+		return fmt.Sprintf("%T:%v", k, k)
+	}
+
+	return k
+}
+
+// relatedValue converts the related document r to t, its struct type or a pointer to it.
+func relatedValue(t reflect.Type, r reflect.Value) reflect.Value {
+	if t.Kind() != reflect.Pointer {
+		return r
+	}
+
+	p := reflect.New(t.Elem())
+	p.Elem().Set(r)
+
+	return p
+}
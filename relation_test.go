@@ -0,0 +1,229 @@
+package mongorm
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type relAuthor struct {
+	ID    primitive.ObjectID `bson:"_id"`
+	Name  string             `bson:"name"`
+	Books []relBook          `bson:"-" mongorm:"hasMany:AuthorID"`
+}
+
+type relBook struct {
+	ID       primitive.ObjectID   `bson:"_id"`
+	Title    string               `bson:"title"`
+	AuthorID primitive.ObjectID   `bson:"author_id"`
+	TagIDs   []primitive.ObjectID `bson:"tag_ids"`
+	Author   *relAuthor           `bson:"-" mongorm:"belongsTo:AuthorID"`
+	Tags     []*relTag            `bson:"-" mongorm:"manyToMany:tag_ids"`
+}
+
+type relTag struct {
+	ID   primitive.ObjectID `bson:"_id"`
+	Name string             `bson:"name"`
+}
+
+// newRelationDB returns a database with two authors, of two books and of none, three books,
+// one of an unknown author, and two tags.
+func newRelationDB(t *testing.T) (*TypedCollection[relAuthor], *TypedCollection[relBook]) {
+	t.Helper()
+
+	ctx := context.Background()
+	db := newTestDB(t)
+	if err := db.client.Register(&relAuthor{}, &relBook{}, &relTag{}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	ann, bob := primitive.NewObjectID(), primitive.NewObjectID()
+	red, blue := primitive.NewObjectID(), primitive.NewObjectID()
+
+	authors := CollectionOf[relAuthor](db, "rel_authors")
+	if _, err := authors.InsertMany(ctx, []relAuthor{{ID: ann, Name: "ann"}, {ID: bob, Name: "bob"}}); err != nil {
+		t.Fatalf("InsertMany: %v", err)
+	}
+
+	tags := CollectionOf[relTag](db, "rel_tags")
+	if _, err := tags.InsertMany(ctx, []relTag{{ID: red, Name: "red"}, {ID: blue, Name: "blue"}}); err != nil {
+		t.Fatalf("InsertMany: %v", err)
+	}
+
+	books := CollectionOf[relBook](db, "rel_books")
+	_, err := books.InsertMany(ctx, []relBook{
+		{ID: primitive.NewObjectID(), Title: "a", AuthorID: ann, TagIDs: []primitive.ObjectID{red, blue}},
+		{ID: primitive.NewObjectID(), Title: "b", AuthorID: ann, TagIDs: []primitive.ObjectID{blue}},
+		{ID: primitive.NewObjectID(), Title: "c", AuthorID: primitive.NewObjectID()},
+	})
+	if err != nil {
+		t.Fatalf("InsertMany: %v", err)
+	}
+
+	return authors, books
+}
+
+// describeBook renders a book with its author and tags, e.g. "a by ann [red blue]".
+func describeBook(b relBook) string {
+	author := "nobody"
+	if b.Author != nil {
+		author = b.Author.Name
+	}
+
+	tags := make([]string, 0, len(b.Tags))
+	for _, tag := range b.Tags {
+		tags = append(tags, tag.Name)
+	}
+
+	return b.Title + " by " + author + " [" + strings.Join(tags, " ") + "]"
+}
+
+func describeBooks(books []relBook) []string {
+	described := make([]string, 0, len(books))
+	for _, b := range books {
+		described = append(described, describeBook(b))
+	}
+
+	return described
+}
+
+func TestPreload(t *testing.T) {
+	ctx := context.Background()
+	_, books := newRelationDB(t)
+
+	want := []string{"a by ann [red blue]", "b by ann [blue]", "c by nobody []"}
+
+	for _, lookup := range []bool{false, true} {
+		q := books.Query().Sort("title", ASC)
+		if lookup {
+			q = q.PreloadLookup("Author", "Tags")
+		} else {
+			q = q.Preload("Author", "Tags")
+		}
+
+		all, err := books.All(ctx, q)
+		if err != nil {
+			t.Fatalf("All, lookup %v: %v", lookup, err)
+		}
+		if got := describeBooks(all); !reflect.DeepEqual(got, want) {
+			t.Errorf("All, lookup %v = %v, want %v", lookup, got, want)
+		}
+
+		one, err := books.One(ctx, q.Where("title", EQ, "b"))
+		if err != nil {
+			t.Fatalf("One, lookup %v: %v", lookup, err)
+		}
+		if got := describeBook(one); got != want[1] {
+			t.Errorf("One, lookup %v = %s, want %s", lookup, got, want[1])
+		}
+
+		// queries of the collection of a registered model preload too
+		var untyped []relBook
+		if err := books.db.Collection("rel_books").Query().Sort("title", ASC).Preload("Author", "Tags").All(ctx, &untyped); err != nil {
+			t.Fatalf("Query.All, lookup %v: %v", lookup, err)
+		}
+		if got := describeBooks(untyped); !reflect.DeepEqual(got, want) {
+			t.Errorf("Query.All, lookup %v = %v, want %v", lookup, got, want)
+		}
+
+		var iterated []relBook
+		books.Iter(ctx, q)(func(b relBook, err error) bool {
+			if err != nil {
+				t.Fatalf("Iter, lookup %v: %v", lookup, err)
+			}
+			iterated = append(iterated, b)
+			return true
+		})
+		if got := describeBooks(iterated); !reflect.DeepEqual(got, want) {
+			t.Errorf("Iter, lookup %v = %v, want %v", lookup, got, want)
+		}
+	}
+}
+
+func TestPreloadHasMany(t *testing.T) {
+	ctx := context.Background()
+	authors, _ := newRelationDB(t)
+
+	for _, q := range []*Query{authors.Query().Preload("Books"), authors.Query().PreloadLookup("Books")} {
+		all, err := authors.All(ctx, q.Sort("name", ASC))
+		if err != nil {
+			t.Fatalf("All: %v", err)
+		}
+
+		got := make(map[string][]string)
+		for _, a := range all {
+			titles := []string{}
+			for _, b := range a.Books {
+				titles = append(titles, b.Title)
+			}
+			sort.Strings(titles)
+			got[a.Name] = titles
+		}
+
+		if want := map[string][]string{"ann": {"a", "b"}, "bob": {}}; !reflect.DeepEqual(got, want) {
+			t.Errorf("books of authors = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestPreloadProjection(t *testing.T) {
+	ctx := context.Background()
+	_, books := newRelationDB(t)
+
+	tests := []struct {
+		name string
+		q    *Query
+		want string
+	}{
+		{"select", books.Query().Select("title").Preload("Author", "Tags"), "a by ann [red blue]"},
+		{"select lookup", books.Query().Select("title").PreloadLookup("Author", "Tags"), "a by ann [red blue]"},
+		{"select without _id", books.Query().Select("title").Exclude("_id").PreloadLookup("Author"), "a by ann []"},
+		{"exclude", books.Query().Exclude("author_id", "tag_ids").Preload("Author", "Tags"), "a by ann [red blue]"},
+		{"exclude lookup", books.Query().Exclude("author_id", "tag_ids").PreloadLookup("Author", "Tags"), "a by ann [red blue]"},
+	}
+
+	for _, tt := range tests {
+		b, err := books.One(ctx, tt.q.Where("title", EQ, "a"))
+		if err != nil {
+			t.Fatalf("%s: One: %v", tt.name, err)
+		}
+
+		if got := describeBook(b); got != tt.want {
+			t.Errorf("%s: One = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+
+	// the projection still applies to the other fields
+	b, err := books.One(ctx, books.Query().Select("author_id").PreloadLookup("Author").Where("title", EQ, "a"))
+	if err != nil || b.Title != "" || b.Author == nil {
+		t.Errorf("One = %+v, %v, want the author without the title", b, err)
+	}
+
+	authors, err := CollectionOf[relAuthor](books.db, "rel_authors").All(ctx, books.Query().Exclude("_id").Preload("Books"))
+	if err != nil || len(authors) != 2 || len(authors[0].Books)+len(authors[1].Books) != 2 {
+		t.Errorf("All authors without _id = %+v, %v, want their books", authors, err)
+	}
+}
+
+func TestPreloadErrors(t *testing.T) {
+	ctx := context.Background()
+	_, books := newRelationDB(t)
+
+	if _, err := books.All(ctx, books.Query().Preload("Publisher")); !errors.Is(err, ErrInvalidModel) {
+		t.Errorf("All of an unknown relation: error %v, want ErrInvalidModel", err)
+	}
+
+	if _, err := books.All(ctx, books.Query().PreloadLookup("Publisher")); !errors.Is(err, ErrInvalidModel) {
+		t.Errorf("All of an unknown lookup: error %v, want ErrInvalidModel", err)
+	}
+
+	var docs []relBook
+	if err := testCollection(t).Query().Preload("Author").All(ctx, &docs); !errors.Is(err, ErrInvalidModel) {
+		t.Errorf("All of a collection without model: error %v, want ErrInvalidModel", err)
+	}
+}
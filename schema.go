@@ -0,0 +1,364 @@
+package mongorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	mongo_options "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ValidationLevel selects the writes the validator of a collection checks.
+type ValidationLevel string
+
+const (
+	// ValidationStrict validates all inserts and updates.
+	ValidationStrict ValidationLevel = "strict"
+
+	// ValidationModerate validates inserts and the updates of documents that are already valid.
+	ValidationModerate ValidationLevel = "moderate"
+
+	// ValidationOff disables validation.
+	ValidationOff ValidationLevel = "off"
+)
+
+// ValidationAction is what happens to writes of invalid documents.
+type ValidationAction string
+
+const (
+	// ValidationError rejects the write.
+	ValidationError ValidationAction = "error"
+
+	// ValidationWarn accepts the write and logs a warning on the server.
+	ValidationWarn ValidationAction = "warn"
+)
+
+// namespaceExistsCode is the server error code of creating a collection that exists.
+const namespaceExistsCode = 48
+
+var bsonTypes = map[reflect.Type]string{
+	timeType:                               "date",
+	reflect.TypeOf(primitive.DateTime(0)):  "date",
+	reflect.TypeOf(primitive.ObjectID{}):   "objectId",
+	reflect.TypeOf(primitive.Decimal128{}): "decimal",
+	reflect.TypeOf(primitive.Binary{}):     "binData",
+	reflect.TypeOf(primitive.Timestamp{}):  "timestamp",
+	reflect.TypeOf(primitive.Regex{}):      "regex",
+}
+
+// EnsureCollection creates the collection of model, a registered struct value or pointer, with the
+// $jsonSchema validator of the model or, when the collection exists, replaces its validator with collMod.
+// An empty level or action keeps the current one, which is "strict" and "error" for a new collection.
+// On an in-memory client the collection is created but documents are not validated.
+func (db *Database) EnsureCollection(ctx context.Context, model interface{}, level ValidationLevel, action ValidationAction) error {
+	m, err := db.client.Model(model)
+	if err != nil {
+		return err
+	}
+
+	if db.memory != nil {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		db.memory.Collection(m.Collection)
+
+		return nil
+	}
+
+	validator := bson.D{{Key: "$jsonSchema", Value: m.Schema}}
+
+	names, err := db.ListCollectionNames(ctx, bson.D{{Key: "name", Value: m.Collection}})
+	if err != nil {
+		return fmt.Errorf("mongorm: list collections: %w", err)
+	}
+
+	if len(names) == 0 {
+		opts := mongo_options.CreateCollection().SetValidator(validator)
+		if level != "" {
+			opts.SetValidationLevel(string(level))
+		}
+		if action != "" {
+			opts.SetValidationAction(string(action))
+		}
+
+		err := db.CreateCollection(ctx, m.Collection, opts)
+
+		// a collection created concurrently is modified instead
+		var cmdErr mongo.CommandError
+		if !errors.As(err, &cmdErr) || cmdErr.Code != namespaceExistsCode {
+			return db.mapErr("create collection", m.Collection, err)
+		}
+	}
+
+	cmd := bson.D{{Key: "collMod", Value: m.Collection}, {Key: "validator", Value: validator}}
+	if level != "" {
+		cmd = append(cmd, bson.E{Key: "validationLevel", Value: string(level)})
+	}
+	if action != "" {
+		cmd = append(cmd, bson.E{Key: "validationAction", Value: string(action)})
+	}
+
+	return db.mapErr("collMod", m.Collection, db.RunCommand(ctx, cmd).Err())
+}
+
+func (db *Database) mapErr(op, collection string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return fmt.Errorf("mongorm: %s %s: %w", op, collection, err)
+}
+
+// objectSchema returns the schema of the documents of the model: its fields stored at the top level
+// as properties, and those tagged required. seen holds the struct types being described, so that
+// recursive types end in a plain object.
+func (m *Model) objectSchema(seen map[reflect.Type]bool) (bson.D, error) {
+	properties := bson.D{}
+	required := bson.A{}
+
+	for _, f := range m.Fields {
+		if strings.Contains(f.Path, ".") {
+			continue
+		}
+
+		schema, err := f.schema(seen)
+		if errors.Is(err, ErrInvalidModel) {
+			return nil, err
+		} else if err != nil {
+			return nil, fmt.Errorf("%w: %v.%s: %v", ErrInvalidModel, m.Type, f.Name, err)
+		}
+
+		properties = append(properties, bson.E{Key: f.Path, Value: schema})
+
+		if _, ok := f.tags["required"]; ok {
+			required = append(required, f.Path)
+		}
+	}
+
+	schema := bson.D{{Key: "bsonType", Value: "object"}}
+	if len(required) > 0 {
+		schema = append(schema, bson.E{Key: "required", Value: required})
+	}
+
+	return append(schema, bson.E{Key: "properties", Value: properties}), nil
+}
+
+// schema returns the schema of the values of the field: the one of its type and the constraints of its tags.
+func (f *Field) schema(seen map[reflect.Type]bool) (bson.D, error) {
+	schema, nullable, err := typeSchema(f.Type, seen)
+	if err != nil {
+		return nil, err
+	}
+
+	t := f.Type
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if enum, ok := f.tags["enum"]; ok {
+		values := bson.A{}
+		for _, s := range strings.Split(enum, "|") {
+			v, err := parseValue(t, s)
+			if err != nil {
+				return nil, fmt.Errorf("enum: %v", err)
+			}
+			values = append(values, v)
+		}
+
+		if nullable {
+			values = append(values, nil)
+		}
+
+		schema = append(schema, bson.E{Key: "enum", Value: values})
+	}
+
+	for _, bound := range []string{"min", "max"} {
+		s, ok := f.tags[bound]
+		if !ok {
+			continue
+		}
+
+		e, err := boundSchema(t, bound, s)
+		if err != nil {
+			return nil, err
+		}
+
+		schema = append(schema, e)
+	}
+
+	if pattern, ok := f.tags["pattern"]; ok {
+		if t.Kind() != reflect.String {
+			return nil, fmt.Errorf("pattern requires a string field, got %v", f.Type)
+		}
+
+		schema = append(schema, bson.E{Key: "pattern", Value: pattern})
+	}
+
+	return schema, nil
+}
+
+// boundSchema returns the keyword of a min or max tag of a field of type t, depending on the kind of t.
+func boundSchema(t reflect.Type, bound, s string) (bson.E, error) {
+	var key string
+
+	switch {
+	case isNumberKind(t.Kind()) && bsonTypes[t] == "":
+		v, err := parseValue(t, s)
+		if err != nil {
+			return bson.E{}, fmt.Errorf("%s: %v", bound, err)
+		}
+
+		return bson.E{Key: map[string]string{"min": "minimum", "max": "maximum"}[bound], Value: v}, nil
+	case t.Kind() == reflect.String:
+		key = bound + "Length"
+	case (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && t.Elem().Kind() != reflect.Uint8:
+		key = bound + "Items"
+	default:
+		return bson.E{}, fmt.Errorf("%s requires a number, string or array field, got %v", bound, t)
+	}
+
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return bson.E{}, fmt.Errorf("%s: %q is not a length", bound, s)
+	}
+
+	return bson.E{Key: key, Value: n}, nil
+}
+
+// typeSchema returns the schema of the BSON values of Go type t and whether they can be null:
+// pointers, slices and maps are stored as null when nil. Types with their own encoding and
+// interfaces are not constrained.
+func typeSchema(t reflect.Type, seen map[reflect.Type]bool) (schema bson.D, nullable bool, err error) {
+	for t.Kind() == reflect.Pointer {
+		t, nullable = t.Elem(), true
+	}
+
+	if bsonType, ok := bsonTypes[t]; ok {
+		return bson.D{{Key: "bsonType", Value: bsonTypeValue([]string{bsonType}, nullable)}}, nullable, nil
+	}
+
+	if marshals(t) {
+		return bson.D{}, nullable, nil
+	}
+
+	var types []string
+	switch t.Kind() {
+	case reflect.String:
+		types = []string{"string"}
+	case reflect.Bool:
+		types = []string{"bool"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		types = []string{"int"}
+	case reflect.Int64:
+		types = []string{"long"}
+	case reflect.Int, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		// stored as int when the value fits
+		types = []string{"int", "long"}
+	case reflect.Float32, reflect.Float64:
+		types = []string{"double"}
+	case reflect.Slice, reflect.Array:
+		nullable = nullable || t.Kind() == reflect.Slice
+		if t.Elem().Kind() == reflect.Uint8 {
+			types = []string{"binData"}
+			break
+		}
+
+		types = []string{"array"}
+
+		items, _, err := typeSchema(t.Elem(), seen)
+		if err != nil {
+			return nil, false, err
+		}
+
+		if len(items) > 0 {
+			schema = append(schema, bson.E{Key: "items", Value: items})
+		}
+	case reflect.Map:
+		nullable = true
+		types = []string{"object"}
+
+		values, _, err := typeSchema(t.Elem(), seen)
+		if err != nil {
+			return nil, false, err
+		}
+
+		if len(values) > 0 {
+			schema = append(schema, bson.E{Key: "additionalProperties", Value: values})
+		}
+	case reflect.Struct:
+		if !nested(t) {
+			return bson.D{}, nullable, nil
+		}
+
+		if seen[t] {
+			return bson.D{{Key: "bsonType", Value: bsonTypeValue([]string{"object"}, nullable)}}, nullable, nil
+		}
+
+		seen[t] = true
+		defer delete(seen, t)
+
+		sub := &Model{Type: t, byPath: make(map[string]*Field), byName: make(map[string]*Field)}
+		if err := sub.parseFields(t, nil, "", ""); err != nil {
+			return nil, false, err
+		}
+
+		object, err := sub.objectSchema(seen)
+		if err != nil {
+			return nil, false, err
+		}
+
+		object[0].Value = bsonTypeValue([]string{"object"}, nullable)
+
+		return object, nullable, nil
+	default:
+		return bson.D{}, nullable, nil
+	}
+
+	return append(bson.D{{Key: "bsonType", Value: bsonTypeValue(types, nullable)}}, schema...), nullable, nil
+}
+
+func bsonTypeValue(types []string, nullable bool) interface{} {
+	if nullable {
+		types = append(types, "null")
+	}
+
+	if len(types) == 1 {
+		return types[0]
+	}
+
+	values := make(bson.A, 0, len(types))
+	for _, t := range types {
+		values = append(values, t)
+	}
+
+	return values
+}
+
+// parseValue parses s as a value of a field of type t for enum, min and max.
+func parseValue(t reflect.Type, s string) (interface{}, error) {
+	switch k := t.Kind(); {
+	case k == reflect.String:
+		return s, nil
+	case k == reflect.Bool:
+		return strconv.ParseBool(s)
+	case k >= reflect.Int && k <= reflect.Int64:
+		return strconv.ParseInt(s, 10, 64)
+	case k >= reflect.Uint && k <= reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, 63)
+		return int64(n), err
+	case k == reflect.Float32 || k == reflect.Float64:
+		return strconv.ParseFloat(s, 64)
+	}
+
+	return nil, fmt.Errorf("unsupported field type %v", t)
+}
+
+func isNumberKind(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Float64 && k != reflect.Uintptr
+}
@@ -0,0 +1,152 @@
+package mongorm
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type schemaNode struct {
+	Name     string        `bson:"name"`
+	Children []*schemaNode `bson:"children"`
+}
+
+type schemaModel struct {
+	ID      primitive.ObjectID `bson:"_id"`
+	Title   string             `bson:"title" mongorm:"required,min:1,max:200"`
+	Status  string             `bson:"status" mongorm:"required,enum:draft|published"`
+	Rating  int                `bson:"rating" mongorm:"min:0,max:5"`
+	Score   *float64           `bson:"score" mongorm:"enum:0.5|1"`
+	Count   int64              `bson:"count"`
+	Small   int32              `bson:"small"`
+	Active  bool               `bson:"active"`
+	Tags    []string           `bson:"tags" mongorm:"max:10"`
+	Raw     []byte             `bson:"raw"`
+	Attrs   map[string]int32   `bson:"attrs"`
+	At      time.Time          `bson:"at"`
+	Deleted *time.Time         `bson:"deleted"`
+	Slug    string             `bson:"slug" mongorm:"pattern:^[a-z,]+$"`
+	Any     interface{}        `bson:"any"`
+	Tree    schemaNode         `bson:"tree"`
+	Address struct {
+		City string `bson:"city" mongorm:"required"`
+	} `bson:"address"`
+	Audit `bson:",inline"`
+}
+
+func TestSchema(t *testing.T) {
+	client, err := NewMemoryClient()
+	if err != nil {
+		t.Fatalf("NewMemoryClient: %v", err)
+	}
+
+	if err := client.Register(schemaModel{}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	m, err := client.Model(schemaModel{})
+	if err != nil {
+		t.Fatalf("Model: %v", err)
+	}
+
+	want := `{"bsonType":"object","required":["title","status"],"properties":{` +
+		`"_id":{"bsonType":"objectId"},` +
+		`"title":{"bsonType":"string","minLength":1,"maxLength":200},` +
+		`"status":{"bsonType":"string","enum":["draft","published"]},` +
+		`"rating":{"bsonType":["int","long"],"minimum":0,"maximum":5},` +
+		`"score":{"bsonType":["double","null"],"enum":[0.5,1.0,null]},` +
+		`"count":{"bsonType":"long"},` +
+		`"small":{"bsonType":"int"},` +
+		`"active":{"bsonType":"bool"},` +
+		`"tags":{"bsonType":["array","null"],"items":{"bsonType":"string"},"maxItems":10},` +
+		`"raw":{"bsonType":["binData","null"]},` +
+		`"attrs":{"bsonType":["object","null"],"additionalProperties":{"bsonType":"int"}},` +
+		`"at":{"bsonType":"date"},` +
+		`"deleted":{"bsonType":["date","null"]},` +
+		`"slug":{"bsonType":"string","pattern":"^[a-z,]+$"},` +
+		`"any":{},` +
+		`"tree":{"bsonType":"object","properties":{"name":{"bsonType":"string"},"children":{"bsonType":["array","null"],"items":{"bsonType":["object","null"]}}}},` +
+		`"address":{"bsonType":"object","required":["city"],"properties":{"city":{"bsonType":"string"}}},` +
+		`"created_by":{"bsonType":"string"}}}`
+
+	if got := renderJSON(t, m.Schema); got != want {
+		t.Errorf("Schema =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestSchemaInvalidTags(t *testing.T) {
+	models := map[string]interface{}{
+		"enum value": struct {
+			N int `bson:"n" mongorm:"enum:1|x"`
+		}{},
+		"enum type": struct {
+			At time.Time `bson:"at" mongorm:"enum:now"`
+		}{},
+		"bound of a bool": struct {
+			B bool `bson:"b" mongorm:"min:1"`
+		}{},
+		"negative length": struct {
+			S string `bson:"s" mongorm:"max:-1"`
+		}{},
+		"bound of a date": struct {
+			At time.Time `bson:"at" mongorm:"min:0"`
+		}{},
+		"pattern of a number": struct {
+			N int `bson:"n" mongorm:"pattern:^1$"`
+		}{},
+		"nested": struct {
+			Sub struct {
+				N int `bson:"n" mongorm:"max:x"`
+			} `bson:"sub"`
+		}{},
+	}
+
+	for name, model := range models {
+		client, err := NewMemoryClient()
+		if err != nil {
+			t.Fatalf("NewMemoryClient: %v", err)
+		}
+
+		if err := client.Register(model); !errors.Is(err, ErrInvalidModel) {
+			t.Errorf("%s: Register error %v, want ErrInvalidModel", name, err)
+		}
+	}
+}
+
+func TestEnsureCollection(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+
+	if err := db.EnsureCollection(ctx, &schemaModel{}, ValidationStrict, ValidationError); !errors.Is(err, ErrUnknownModel) {
+		t.Errorf("EnsureCollection of an unregistered model: error %v, want ErrUnknownModel", err)
+	}
+
+	if err := db.client.Register(&schemaModel{}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := db.EnsureCollection(ctx, &schemaModel{}, ValidationModerate, ""); err != nil {
+			t.Fatalf("EnsureCollection: %v", err)
+		}
+	}
+
+	names, err := db.CollectionNames(ctx)
+	if err != nil || !reflect.DeepEqual(names, []string{"schema_models"}) {
+		t.Errorf("CollectionNames = %v, %v, want [schema_models]", names, err)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := db.EnsureCollection(canceled, schemaModel{}, "", ""); !errors.Is(err, context.Canceled) {
+		t.Errorf("EnsureCollection with a canceled context: error %v, want context.Canceled", err)
+	}
+
+	if err := (&Database{}).EnsureCollection(ctx, schemaModel{}, "", ""); !errors.Is(err, ErrUnknownModel) {
+		t.Errorf("EnsureCollection without a client: error %v, want ErrUnknownModel", err)
+	}
+}
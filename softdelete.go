@@ -0,0 +1,152 @@
+package mongorm
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/v1shn3vsk7/mongorm/internal/operators"
+)
+
+// deletedScope selects the documents of soft deleted models a query matches.
+type deletedScope int
+
+const (
+	scopeNotDeleted deletedScope = iota
+	scopeWithDeleted
+	scopeOnlyDeleted
+)
+
+// WithDeleted makes the query match soft deleted documents too. Queries of models with a
+// deletedAt field only match documents that are not deleted by default.
+func (q *Query) WithDeleted() *Query {
+	nq := q.Clone()
+	nq.deleted = scopeWithDeleted
+
+	return nq
+}
+
+// OnlyDeleted makes the query match soft deleted documents only.
+func (q *Query) OnlyDeleted() *Query {
+	nq := q.Clone()
+	nq.deleted = scopeOnlyDeleted
+
+	return nq
+}
+
+// Restore restores every soft deleted document matched by the query, whatever its scope,
+// by removing the deletedAt field, and returns the number of restored documents.
+// ErrInvalidModel is returned when the documents are not soft deleted.
+func (q *Query) Restore(ctx context.Context) (int64, error) {
+	f, err := q.deletedAt()
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := q.OnlyDeleted().UpdateMany(ctx, NewUpdate().Unset(f.Path))
+	if err != nil {
+		return 0, err
+	}
+
+	return res.ModifiedCount, nil
+}
+
+// ForceDeleteOne removes the first document matched by the query from the collection, also for
+// models with a deletedAt field, and returns the number of deleted documents. Like every query
+// it skips soft deleted documents unless WithDeleted or OnlyDeleted is called.
+func (q *Query) ForceDeleteOne(ctx context.Context) (int64, error) {
+	filter, err := q.filterDelete(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := q.collection.storage().DeleteOne(ctx, filter, q.deleteOptions())
+	if err != nil {
+		return 0, q.mapErr("delete one", err)
+	}
+
+	return res.DeletedCount, q.checkVersion("delete one", res.DeletedCount)
+}
+
+// ForceDeleteMany removes every document matched by the query from the collection, also for
+// models with a deletedAt field, and returns the number of deleted documents.
+func (q *Query) ForceDeleteMany(ctx context.Context) (int64, error) {
+	filter, err := q.filterDelete(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := q.collection.storage().DeleteMany(ctx, filter, q.deleteOptions())
+	if err != nil {
+		return 0, q.mapErr("delete many", err)
+	}
+
+	return res.DeletedCount, q.checkVersion("delete many", res.DeletedCount)
+}
+
+// softDelete sets the deletedAt field of the first or every document matched by the query
+// that is not deleted yet, and returns the number of deleted documents.
+func (q *Query) softDelete(ctx context.Context, f *Field, many bool) (int64, error) {
+	filter, err := q.filterDelete(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	if q.deleted != scopeNotDeleted {
+		filter = andFilter(filter, notDeleted(f))
+	}
+
+	now := q.collection.now()
+	u := q.collection.model.fieldUpdates(NewUpdate().Set(f.Path, now), now)
+
+	store, op := q.collection.storage().UpdateOne, "delete one"
+	if many {
+		store, op = q.collection.storage().UpdateMany, "delete many"
+	}
+
+	res, err := store(ctx, filter, u.Bson(), q.updateOptions(u))
+	if err != nil {
+		return 0, q.mapErr(op, err)
+	}
+
+	return res.ModifiedCount, q.checkVersion(op, res.ModifiedCount)
+}
+
+// scope restricts filter to the documents of the deleted scope of the query.
+func (q *Query) scope(filter bson.D) bson.D {
+	return scopeIn(q.collection, q.deleted, filter)
+}
+
+// scopeIn restricts filter to the documents of c in the deleted scope.
+func scopeIn(c *Collection, deleted deletedScope, filter bson.D) bson.D {
+	if c == nil || c.model == nil || c.model.DeletedAt == nil {
+		return filter
+	}
+
+	f := c.model.DeletedAt
+
+	switch deleted {
+	case scopeNotDeleted:
+		return andFilter(filter, notDeleted(f))
+	case scopeOnlyDeleted:
+		return andFilter(filter, bson.D{{Key: f.Path, Value: bson.D{{Key: operators.NE, Value: nil}}}})
+	}
+
+	return filter
+}
+
+// deletedAt returns the deletedAt field of the documents of the query.
+func (q *Query) deletedAt() (*Field, error) {
+	if q.collection == nil || q.collection.model == nil || q.collection.model.DeletedAt == nil {
+		return nil, fmt.Errorf("%w: the documents of the query are not soft deleted", ErrInvalidModel)
+	}
+
+	return q.collection.model.DeletedAt, nil
+}
+
+// notDeleted matches the documents whose deletedAt field is null or missing: pointers without
+// omitempty store null.
+func notDeleted(f *Field) bson.D {
+	return bson.D{{Key: f.Path, Value: nil}}
+}
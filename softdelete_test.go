@@ -0,0 +1,239 @@
+package mongorm
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/v1shn3vsk7/mongorm/options"
+)
+
+type softDoc struct {
+	ID        int        `bson:"_id"`
+	DeletedAt *time.Time `bson:"deleted_at" mongorm:"deletedAt"`
+}
+
+type softOmitDoc struct {
+	ID        int       `bson:"_id"`
+	DeletedAt time.Time `bson:"deleted_at,omitempty" mongorm:"deletedAt"`
+}
+
+var softNow = time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+// newSoftCollection returns the collection of softDoc holding documents 1 to 4: 1 with a null
+// deleted_at, 2 without one, and 3 and 4 deleted.
+func newSoftCollection(t *testing.T) *Collection {
+	t.Helper()
+
+	db := newTestDB(t, options.Client().SetClock(func() time.Time { return softNow }))
+	if err := db.client.Register(&softDoc{}, &softOmitDoc{}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	c := db.Collection("soft_docs")
+	for _, doc := range []string{
+		`{"_id": 1, "deleted_at": null}`,
+		`{"_id": 2}`,
+		`{"_id": 3, "deleted_at": {"$date": "2024-01-01T00:00:00Z"}}`,
+		`{"_id": 4, "deleted_at": {"$date": "2024-02-01T00:00:00Z"}}`,
+	} {
+		if _, err := c.InsertOne(context.Background(), extJSON(t, doc)); err != nil {
+			t.Fatalf("InsertOne: %v", err)
+		}
+	}
+
+	return c
+}
+
+// softIDs returns the _ids of the documents matched by q in ascending order.
+func softIDs(t *testing.T, q *Query) []int {
+	t.Helper()
+
+	var docs []softDoc
+	if err := q.Sort("_id", ASC).All(context.Background(), &docs); err != nil {
+		t.Fatalf("All: %v", err)
+	}
+
+	ids := []int{}
+	for _, doc := range docs {
+		ids = append(ids, doc.ID)
+	}
+
+	return ids
+}
+
+func TestSoftDeleteScopes(t *testing.T) {
+	ctx := context.Background()
+	c := newSoftCollection(t)
+
+	tests := []struct {
+		name string
+		q    *Query
+		want []int
+	}{
+		{"default", c.Query(), []int{1, 2}},
+		{"with deleted", c.Query().WithDeleted(), []int{1, 2, 3, 4}},
+		{"only deleted", c.Query().OnlyDeleted(), []int{3, 4}},
+		{"typed", CollectionOf[softDoc](c.db, "soft_docs").Query(), []int{1, 2}},
+		{"filter", c.Query().Where("_id", GTE, 2).OnlyDeleted(), []int{3, 4}},
+	}
+
+	for _, tt := range tests {
+		if ids := softIDs(t, tt.q); !reflect.DeepEqual(ids, tt.want) {
+			t.Errorf("%s: _ids = %v, want %v", tt.name, ids, tt.want)
+		}
+	}
+
+	if n, err := c.Query().Count(ctx); err != nil || n != 2 {
+		t.Errorf("Count = %d, %v, want 2", n, err)
+	}
+
+	if ok, err := c.Query().Where("_id", EQ, 3).Exists(ctx); err != nil || ok {
+		t.Errorf("Exists of a deleted document = %v, %v, want false", ok, err)
+	}
+
+	// the raw methods are not scoped
+	if n, err := c.CountDocuments(ctx, bson.D{}); err != nil || n != 4 {
+		t.Errorf("CountDocuments = %d, %v, want 4", n, err)
+	}
+}
+
+func TestSoftDeletePipeline(t *testing.T) {
+	c := newSoftCollection(t)
+
+	tests := []struct {
+		name string
+		p    *Pipeline
+		want []int
+	}{
+		{"default", c.Pipeline().Match(c.Query()), []int{1, 2}},
+		{"with deleted", c.Pipeline().Match(c.Query().WithDeleted()), []int{1, 2, 3, 4}},
+		{"only deleted", c.Pipeline().Match(c.Query().OnlyDeleted()), []int{3, 4}},
+		// only Match stages are scoped
+		{"without match", c.Pipeline(), []int{1, 2, 3, 4}},
+	}
+
+	for _, tt := range tests {
+		var docs []softDoc
+		if err := tt.p.Sort("_id", ASC).Aggregate(context.Background(), &docs); err != nil {
+			t.Fatalf("%s: Aggregate: %v", tt.name, err)
+		}
+
+		ids := []int{}
+		for _, doc := range docs {
+			ids = append(ids, doc.ID)
+		}
+
+		if !reflect.DeepEqual(ids, tt.want) {
+			t.Errorf("%s: _ids = %v, want %v", tt.name, ids, tt.want)
+		}
+	}
+}
+
+func TestSoftDelete(t *testing.T) {
+	ctx := context.Background()
+	c := newSoftCollection(t)
+
+	if n, err := c.Query().Where("_id", EQ, 1).DeleteOne(ctx); err != nil || n != 1 {
+		t.Fatalf("DeleteOne = %d, %v, want 1", n, err)
+	}
+
+	var doc softDoc
+	if err := c.Query().OnlyDeleted().Where("_id", EQ, 1).One(ctx, &doc); err != nil || doc.DeletedAt == nil || !doc.DeletedAt.Equal(softNow) {
+		t.Errorf("deleted document = %+v, %v, want deleted_at %v", doc, err, softNow)
+	}
+
+	// deleted documents keep the time they were first deleted at
+	if n, err := c.Query().WithDeleted().DeleteMany(ctx); err != nil || n != 1 {
+		t.Errorf("DeleteMany with deleted = %d, %v, want 1", n, err)
+	}
+
+	var stored bson.D
+	if err := c.FindOne(ctx, bson.D{{Key: "_id", Value: 3}}).Decode(&stored); err != nil {
+		t.Fatalf("FindOne: %v", err)
+	}
+	if got, want := renderJSON(t, stored), `{"_id":3,"deleted_at":{"$date":"2024-01-01T00:00:00Z"}}`; got != want {
+		t.Errorf("document deleted again = %s, want %s", got, want)
+	}
+
+	if ids := softIDs(t, c.Query()); len(ids) != 0 {
+		t.Errorf("_ids after DeleteMany = %v, want none", ids)
+	}
+
+	if n, err := c.Query().Where("_id", IN, []int{1, 3}).Restore(ctx); err != nil || n != 2 {
+		t.Errorf("Restore = %d, %v, want 2", n, err)
+	}
+
+	if ids := softIDs(t, c.Query()); !reflect.DeepEqual(ids, []int{1, 3}) {
+		t.Errorf("_ids after Restore = %v, want [1 3]", ids)
+	}
+
+	if err := c.FindOne(ctx, bson.D{{Key: "_id", Value: 3}}).Decode(&stored); err != nil {
+		t.Fatalf("FindOne: %v", err)
+	}
+	if got, want := renderJSON(t, stored), `{"_id":3}`; got != want {
+		t.Errorf("restored document = %s, want %s", got, want)
+	}
+
+	if _, err := newTestDB(t).Collection("c").Query().Restore(ctx); !errors.Is(err, ErrInvalidModel) {
+		t.Errorf("Restore of a collection without model: error %v, want ErrInvalidModel", err)
+	}
+}
+
+func TestForceDelete(t *testing.T) {
+	ctx := context.Background()
+	c := newSoftCollection(t)
+
+	// like every query, force deletes skip deleted documents by default
+	if n, err := c.Query().Where("_id", IN, []int{1, 3}).ForceDeleteMany(ctx); err != nil || n != 1 {
+		t.Errorf("ForceDeleteMany = %d, %v, want 1", n, err)
+	}
+
+	if n, err := c.Query().OnlyDeleted().Where("_id", EQ, 4).ForceDeleteOne(ctx); err != nil || n != 1 {
+		t.Errorf("ForceDeleteOne = %d, %v, want 1", n, err)
+	}
+
+	if ids := softIDs(t, c.Query().WithDeleted()); !reflect.DeepEqual(ids, []int{2, 3}) {
+		t.Errorf("_ids after force deletes = %v, want [2 3]", ids)
+	}
+}
+
+func TestSoftDeleteOmitEmpty(t *testing.T) {
+	ctx := context.Background()
+	db := newSoftCollection(t).db
+	c := CollectionOf[softOmitDoc](db, "soft_omit_docs")
+
+	if _, err := c.InsertMany(ctx, []softOmitDoc{{ID: 1}, {ID: 2}}); err != nil {
+		t.Fatalf("InsertMany: %v", err)
+	}
+
+	if n, err := c.Query().Where("_id", EQ, 1).DeleteOne(ctx); err != nil || n != 1 {
+		t.Fatalf("DeleteOne = %d, %v, want 1", n, err)
+	}
+
+	docs, err := c.All(ctx, nil)
+	if err != nil || len(docs) != 1 || docs[0].ID != 2 {
+		t.Errorf("All = %+v, %v, want document 2", docs, err)
+	}
+
+	deleted, err := c.One(ctx, c.Query().OnlyDeleted())
+	if err != nil || deleted.ID != 1 || !deleted.DeletedAt.Equal(softNow) {
+		t.Errorf("One deleted = %+v, %v, want document 1 deleted at %v", deleted, err, softNow)
+	}
+
+	client, err := NewMemoryClient()
+	if err != nil {
+		t.Fatalf("NewMemoryClient: %v", err)
+	}
+
+	invalid := struct {
+		DeletedAt time.Time `bson:"deleted_at" mongorm:"deletedAt"`
+	}{}
+	if err := client.Register(invalid); !errors.Is(err, ErrInvalidModel) {
+		t.Errorf("Register of a deletedAt time.Time without omitempty: error %v, want ErrInvalidModel", err)
+	}
+}
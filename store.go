@@ -0,0 +1,67 @@
+package mongorm
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mongo_options "go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/v1shn3vsk7/mongorm/internal/memstore"
+)
+
+// store is the storage behind a Collection: a *mongo.Collection for a server or an
+// in-memory collection for clients created with NewMemoryClient.
+type store interface {
+	Name() string
+	Find(ctx context.Context, filter interface{}, opts ...*mongo_options.FindOptions) (*mongo.Cursor, error)
+	FindOne(ctx context.Context, filter interface{}, opts ...*mongo_options.FindOneOptions) *mongo.SingleResult
+	CountDocuments(ctx context.Context, filter interface{}, opts ...*mongo_options.CountOptions) (int64, error)
+	Distinct(ctx context.Context, fieldName string, filter interface{}, opts ...*mongo_options.DistinctOptions) ([]interface{}, error)
+	Aggregate(ctx context.Context, pipeline interface{}, opts ...*mongo_options.AggregateOptions) (*mongo.Cursor, error)
+	InsertOne(ctx context.Context, document interface{}, opts ...*mongo_options.InsertOneOptions) (*mongo.InsertOneResult, error)
+	InsertMany(ctx context.Context, documents []interface{}, opts ...*mongo_options.InsertManyOptions) (*mongo.InsertManyResult, error)
+	UpdateOne(ctx context.Context, filter, update interface{}, opts ...*mongo_options.UpdateOptions) (*mongo.UpdateResult, error)
+	UpdateMany(ctx context.Context, filter, update interface{}, opts ...*mongo_options.UpdateOptions) (*mongo.UpdateResult, error)
+	FindOneAndUpdate(ctx context.Context, filter, update interface{}, opts ...*mongo_options.FindOneAndUpdateOptions) *mongo.SingleResult
+	DeleteOne(ctx context.Context, filter interface{}, opts ...*mongo_options.DeleteOptions) (*mongo.DeleteResult, error)
+	DeleteMany(ctx context.Context, filter interface{}, opts ...*mongo_options.DeleteOptions) (*mongo.DeleteResult, error)
+	BulkWrite(ctx context.Context, models []mongo.WriteModel, opts ...*mongo_options.BulkWriteOptions) (*mongo.BulkWriteResult, error)
+	CreateIndexes(ctx context.Context, models []mongo.IndexModel) ([]string, error)
+	ListIndexes(ctx context.Context) ([]bson.D, error)
+	DropIndex(ctx context.Context, name string) error
+}
+
+var (
+	_ store = driverStore{}
+	_ store = (*memstore.Collection)(nil)
+)
+
+// driverStore adapts *mongo.Collection to store.
+type driverStore struct {
+	*mongo.Collection
+}
+
+func (s driverStore) CreateIndexes(ctx context.Context, models []mongo.IndexModel) ([]string, error) {
+	return s.Indexes().CreateMany(ctx, models)
+}
+
+func (s driverStore) ListIndexes(ctx context.Context) ([]bson.D, error) {
+	cursor, err := s.Indexes().List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []bson.D
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	return docs, nil
+}
+
+func (s driverStore) DropIndex(ctx context.Context, name string) error {
+	_, err := s.Indexes().DropOne(ctx, name)
+
+	return err
+}
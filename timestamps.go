@@ -0,0 +1,128 @@
+package mongorm
+
+import (
+	"reflect"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/v1shn3vsk7/mongorm/internal/operators"
+)
+
+// timestampTypes are the types of the fields tagged createdAt, updatedAt and deletedAt.
+var timestampTypes = map[reflect.Type]bool{
+	timeType:                              true,
+	reflect.PointerTo(timeType):           true,
+	reflect.TypeOf(primitive.DateTime(0)): true,
+}
+
+// Now returns the time set in the createdAt, updatedAt and deletedAt fields of models: the time of the clock
+// of the client, see ClientOptions.SetClock, in UTC and truncated to the milliseconds BSON dates hold.
+func (c *Client) Now() time.Time {
+	return c.clock().UTC().Truncate(time.Millisecond)
+}
+
+// Now returns Client.Now of the client of the database, or the current time in UTC for
+// databases that do not belong to a client, e.g. &Database{Database: md}.
+func (db *Database) Now() time.Time {
+	if db == nil || db.client == nil {
+		return time.Now().UTC().Truncate(time.Millisecond)
+	}
+
+	return db.client.Now()
+}
+
+// now returns Database.Now of the database of the collection, or the current time in UTC for
+// collections that do not belong to one, e.g. &Collection{Collection: mc}.
+func (c *Collection) now() time.Time {
+	return c.db.Now()
+}
+
+// stampInsert sets the createdAt field of doc, unless it is set, and the updatedAt field to now.
+// doc is a pointer to a struct of the model, or to a pointer to one.
+func (m *Model) stampInsert(doc interface{}, now time.Time) {
+	if m == nil {
+		return
+	}
+
+	if v, ok := m.CreatedAt.settable(doc); ok && v.IsZero() {
+		setTimestamp(v, now)
+	}
+
+	m.stampUpdate(doc, now)
+}
+
+// stampUpdate sets the updatedAt field of doc to now.
+func (m *Model) stampUpdate(doc interface{}, now time.Time) {
+	if m == nil {
+		return
+	}
+
+	if v, ok := m.UpdatedAt.settable(doc); ok {
+		setTimestamp(v, now)
+	}
+}
+
+// stampUpdateBuilder returns u setting the updatedAt field to now and, when the update inserts a
+// document, the createdAt field. Fields the update already modifies are left to it.
+func (m *Model) stampUpdateBuilder(u *Update, now time.Time) *Update {
+	if m == nil {
+		return u
+	}
+
+	if m.UpdatedAt != nil && !u.modifies(m.UpdatedAt.Path) {
+		u = u.Set(m.UpdatedAt.Path, now)
+	}
+
+	if m.CreatedAt != nil && !u.modifies(m.CreatedAt.Path) {
+		u = u.SetOnInsert(m.CreatedAt.Path, now)
+	}
+
+	return u
+}
+
+// settable returns the settable value of the field in doc, or false when f is nil or the field
+// belongs to a nil embedded struct.
+func (f *Field) settable(doc interface{}) (reflect.Value, bool) {
+	if f == nil {
+		return reflect.Value{}, false
+	}
+
+	v := reflect.ValueOf(doc)
+	for v.Kind() == reflect.Pointer && !v.IsNil() {
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct || !v.CanSet() {
+		return reflect.Value{}, false
+	}
+
+	field, err := v.FieldByIndexErr(f.index)
+	if err != nil {
+		return reflect.Value{}, false
+	}
+
+	return field, true
+}
+
+func setTimestamp(v reflect.Value, now time.Time) {
+	switch v.Type() {
+	case timeType:
+		v.Set(reflect.ValueOf(now))
+	case reflect.PointerTo(timeType):
+		v.Set(reflect.ValueOf(&now))
+	default:
+		v.Set(reflect.ValueOf(primitive.NewDateTimeFromTime(now)))
+	}
+}
+
+// modifies reports whether the update modifies path, one of its parents or one of its children.
+func (u *Update) modifies(path string) bool {
+	for _, op := range u.ops {
+		if overlaps(op.path, path) || op.operator == operators.RENAME && overlaps(op.value.(string), path) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,195 @@
+package mongorm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/v1shn3vsk7/mongorm/options"
+)
+
+type stampedDoc struct {
+	ID        int        `bson:"_id"`
+	Name      string     `bson:"name"`
+	CreatedAt time.Time  `bson:"created_at" mongorm:"createdAt"`
+	UpdatedAt *time.Time `bson:"updated_at" mongorm:"updatedAt"`
+}
+
+// testClock is a settable clock for in-memory clients.
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func newStampedDB(t *testing.T) (*Database, *testClock) {
+	t.Helper()
+
+	clock := &testClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	db := newTestDB(t, options.Client().SetClock(clock.Now))
+	if err := db.client.Register(&stampedDoc{}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	return db, clock
+}
+
+// stamps returns the created_at and updated_at fields of the stored document with the given _id.
+func stamps(t *testing.T, c *Collection, id int) string {
+	t.Helper()
+
+	var doc struct {
+		CreatedAt *time.Time `bson:"created_at"`
+		UpdatedAt *time.Time `bson:"updated_at"`
+	}
+	if err := c.FindOne(context.Background(), bson.D{{Key: "_id", Value: id}}).Decode(&doc); err != nil {
+		t.Fatalf("FindOne %d: %v", id, err)
+	}
+
+	format := func(at *time.Time) string {
+		if at == nil {
+			return "null"
+		}
+
+		return at.UTC().Format(time.RFC3339)
+	}
+
+	return format(doc.CreatedAt) + " " + format(doc.UpdatedAt)
+}
+
+func TestNow(t *testing.T) {
+	local := time.FixedZone("UTC+2", 2*60*60)
+	db := newTestDB(t, options.Client().SetClock(func() time.Time {
+		return time.Date(2024, 1, 1, 2, 0, 0, 1234567, local)
+	}))
+
+	want := time.Date(2024, 1, 1, 0, 0, 0, 1000000, time.UTC)
+	if now := db.Now(); !now.Equal(want) || now.Location() != time.UTC {
+		t.Errorf("Now = %v, want %v", now, want)
+	}
+
+	if now := (&Database{}).Now(); now.Location() != time.UTC || now.Nanosecond()%int(time.Millisecond) != 0 {
+		t.Errorf("Now without a client = %v, want a UTC time in milliseconds", now)
+	}
+}
+
+func TestInsertTimestamps(t *testing.T) {
+	ctx := context.Background()
+	db, clock := newStampedDB(t)
+	c := CollectionOf[stampedDoc](db, "stamped_docs")
+
+	if _, err := c.InsertOne(ctx, stampedDoc{ID: 1}); err != nil {
+		t.Fatalf("InsertOne: %v", err)
+	}
+
+	// a set createdAt is kept
+	clock.now = clock.now.Add(time.Hour)
+	created := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := c.InsertMany(ctx, []stampedDoc{{ID: 2, CreatedAt: created}, {ID: 3}}); err != nil {
+		t.Fatalf("InsertMany: %v", err)
+	}
+
+	want := map[int]string{
+		1: "2024-01-01T00:00:00Z 2024-01-01T00:00:00Z",
+		2: "2023-06-01T00:00:00Z 2024-01-01T01:00:00Z",
+		3: "2024-01-01T01:00:00Z 2024-01-01T01:00:00Z",
+	}
+	for id, w := range want {
+		if got := stamps(t, c.Collection, id); got != w {
+			t.Errorf("timestamps of %d = %s, want %s", id, got, w)
+		}
+	}
+
+	// raw inserts are stored as given
+	if _, err := c.Collection.InsertOne(ctx, stampedDoc{ID: 4}); err != nil {
+		t.Fatalf("InsertOne: %v", err)
+	}
+	if got, w := stamps(t, c.Collection, 4), "0001-01-01T00:00:00Z null"; got != w {
+		t.Errorf("timestamps of a raw insert = %s, want %s", got, w)
+	}
+}
+
+func TestUpdateTimestamps(t *testing.T) {
+	ctx := context.Background()
+	db, clock := newStampedDB(t)
+	c := db.Collection("stamped_docs")
+
+	if _, err := CollectionOf[stampedDoc](db, "stamped_docs").InsertOne(ctx, stampedDoc{ID: 1}); err != nil {
+		t.Fatalf("InsertOne: %v", err)
+	}
+
+	clock.now = clock.now.Add(time.Hour)
+	if _, err := c.Query().Where("_id", EQ, 1).UpdateOne(ctx, NewUpdate().Set("name", "a")); err != nil {
+		t.Fatalf("UpdateOne: %v", err)
+	}
+	if got, w := stamps(t, c, 1), "2024-01-01T00:00:00Z 2024-01-01T01:00:00Z"; got != w {
+		t.Errorf("timestamps after UpdateOne = %s, want %s", got, w)
+	}
+
+	// upserts inserting a document set createdAt too
+	clock.now = clock.now.Add(time.Hour)
+	if _, err := c.Query().Where("_id", EQ, 2).Upsert(ctx, NewUpdate().Set("name", "b")); err != nil {
+		t.Fatalf("Upsert: %v", err)
+	}
+	if got, w := stamps(t, c, 2), "2024-01-01T02:00:00Z 2024-01-01T02:00:00Z"; got != w {
+		t.Errorf("timestamps after an inserting Upsert = %s, want %s", got, w)
+	}
+
+	clock.now = clock.now.Add(time.Hour)
+	if _, err := c.Query().Where("_id", EQ, 2).Upsert(ctx, NewUpdate().Set("name", "c")); err != nil {
+		t.Fatalf("Upsert: %v", err)
+	}
+	if got, w := stamps(t, c, 2), "2024-01-01T02:00:00Z 2024-01-01T03:00:00Z"; got != w {
+		t.Errorf("timestamps after an updating Upsert = %s, want %s", got, w)
+	}
+
+	// the timestamps set by an update are kept
+	explicit := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := c.Query().UpdateMany(ctx, NewUpdate().Set("updated_at", explicit)); err != nil {
+		t.Fatalf("UpdateMany: %v", err)
+	}
+	if got, w := stamps(t, c, 1), "2024-01-01T00:00:00Z 2020-01-01T00:00:00Z"; got != w {
+		t.Errorf("timestamps after setting updated_at = %s, want %s", got, w)
+	}
+
+	var doc stampedDoc
+	clock.now = clock.now.Add(time.Hour)
+	if err := c.Query().Where("_id", EQ, 1).FindOneAndUpdate(ctx, NewUpdate().Set("name", "d"), &doc); err != nil {
+		t.Fatalf("FindOneAndUpdate: %v", err)
+	}
+	if doc.UpdatedAt == nil || !doc.UpdatedAt.Equal(clock.now) {
+		t.Errorf("UpdatedAt after FindOneAndUpdate = %v, want %v", doc.UpdatedAt, clock.now)
+	}
+}
+
+func TestTimestampFields(t *testing.T) {
+	models := map[string]interface{}{
+		"string field": struct {
+			At string `bson:"at" mongorm:"createdAt"`
+		}{},
+		"int field": struct {
+			At int64 `bson:"at" mongorm:"updatedAt"`
+		}{},
+		"twice": struct {
+			A time.Time `bson:"a" mongorm:"updatedAt"`
+			B time.Time `bson:"b" mongorm:"updatedAt"`
+		}{},
+	}
+
+	for name, model := range models {
+		client, err := NewMemoryClient()
+		if err != nil {
+			t.Fatalf("NewMemoryClient: %v", err)
+		}
+
+		if err := client.Register(model); !errors.Is(err, ErrInvalidModel) {
+			t.Errorf("%s: Register error %v, want ErrInvalidModel", name, err)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package mongorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/v1shn3vsk7/mongorm/options"
+)
+
+const (
+	transientTransactionError      = "TransientTransactionError"
+	unknownTransactionCommitResult = "UnknownTransactionCommitResult"
+)
+
+// TxContext is the context of a transaction run by Client.Tx. Queries, collections and driver
+// operations called with it run in the transaction.
+type TxContext struct {
+	context.Context
+
+	attempt int
+}
+
+// Attempt returns the number of the current run of the transaction, starting at 1.
+func (tx TxContext) Attempt() int {
+	return tx.attempt
+}
+
+// txKey is the context key of the attempt of the transaction a context belongs to.
+type txKey struct{}
+
+// Tx runs fn in a transaction, which is committed when fn returns nil and aborted otherwise.
+// fn is run again when the transaction fails with a TransientTransactionError and the commit is
+// tried again when its result is unknown, up to TxOptions.MaxAttempts times, so fn must not have
+// effects outside of the transaction that cannot be repeated. Tx called with the context of a
+// transaction runs fn in that transaction.
+//
+// On an in-memory client transactions run one at a time, and aborting one restores the databases
+// to their state before it, which also reverts writes made outside of it meanwhile. fn is run again
+// when it returns a TransientTransactionError, as with a server; commits cannot fail.
+func (c *Client) Tx(ctx context.Context, fn func(ctx TxContext) error, opts ...*options.TxOptions) error {
+	if attempt, ok := ctx.Value(txKey{}).(int); ok {
+		return fn(TxContext{Context: ctx, attempt: attempt})
+	}
+
+	opt := options.MergeTxOptions(opts...)
+
+	attempts := 3
+	if opt.MaxAttempts != nil && *opt.MaxAttempts > 0 {
+		attempts = *opt.MaxAttempts
+	}
+
+	if c.memory != nil {
+		for attempt := 1; ; attempt++ {
+			err := c.memory.Transaction(func() error {
+				return fn(TxContext{Context: context.WithValue(ctx, txKey{}, attempt), attempt: attempt})
+			})
+			if err == nil || attempt >= attempts || ctx.Err() != nil || !hasErrorLabel(err, transientTransactionError) {
+				return err
+			}
+		}
+	}
+
+	sess, err := c.StartSession()
+	if err != nil {
+		return fmt.Errorf("mongorm: start session: %w", err)
+	}
+	defer sess.EndSession(context.WithoutCancel(ctx))
+
+	for attempt := 1; ; attempt++ {
+		err := c.runTx(ctx, sess, fn, opt, attempt, attempts)
+		if err == nil || attempt >= attempts || ctx.Err() != nil || !hasErrorLabel(err, transientTransactionError) {
+			return err
+		}
+	}
+}
+
+// runTx runs fn in a transaction of sess and commits it, trying the commit up to attempts times.
+func (c *Client) runTx(ctx context.Context, sess mongo.Session, fn func(ctx TxContext) error, opt *options.TxOptions, attempt, attempts int) error {
+	if err := sess.StartTransaction(opt.ToMongoOptions()); err != nil {
+		return fmt.Errorf("mongorm: start transaction: %w", err)
+	}
+
+	txCtx := TxContext{
+		Context: context.WithValue(mongo.NewSessionContext(ctx, sess), txKey{}, attempt),
+		attempt: attempt,
+	}
+
+	if err := fn(txCtx); err != nil {
+		_ = sess.AbortTransaction(context.WithoutCancel(ctx))
+		return err
+	}
+
+	for commit := 1; ; commit++ {
+		err := sess.CommitTransaction(ctx)
+		if err == nil {
+			return nil
+		}
+
+		if commit >= attempts || ctx.Err() != nil || !hasErrorLabel(err, unknownTransactionCommitResult) {
+			return fmt.Errorf("mongorm: commit transaction: %w", err)
+		}
+	}
+}
+
+func hasErrorLabel(err error, label string) bool {
+	var serverErr mongo.ServerError
+
+	return errors.As(err, &serverErr) && serverErr.HasErrorLabel(label)
+}
@@ -0,0 +1,196 @@
+package mongorm
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/v1shn3vsk7/mongorm/options"
+)
+
+var (
+	errTransient = &mongo.CommandError{Code: 112, Name: "WriteConflict", Labels: []string{transientTransactionError}}
+	errTx        = errors.New("tx failed")
+)
+
+// txNames returns the names stored in c, in insertion order.
+func txNames(t *testing.T, c *Collection) []string {
+	t.Helper()
+
+	var docs []struct {
+		Name string `bson:"name"`
+	}
+	if err := c.Query().All(context.Background(), &docs); err != nil {
+		t.Fatalf("All: %v", err)
+	}
+
+	names := make([]string, 0, len(docs))
+	for _, doc := range docs {
+		names = append(names, doc.Name)
+	}
+
+	return names
+}
+
+func TestTx(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	c := db.Collection("c")
+
+	err := db.client.Tx(ctx, func(tx TxContext) error {
+		_, err := c.InsertOne(tx, bson.D{{Key: "name", Value: "committed"}})
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Tx: %v", err)
+	}
+
+	err = db.client.Tx(ctx, func(tx TxContext) error {
+		if _, err := c.InsertOne(tx, bson.D{{Key: "name", Value: "aborted"}}); err != nil {
+			return err
+		}
+
+		return errTx
+	})
+	if !errors.Is(err, errTx) {
+		t.Errorf("Tx: error %v, want the error of fn", err)
+	}
+
+	if names := txNames(t, c); !reflect.DeepEqual(names, []string{"committed"}) {
+		t.Errorf("documents after an aborted transaction = %v, want [committed]", names)
+	}
+}
+
+func TestTxRetries(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		opts     []*options.TxOptions
+		failures int
+		fail     error
+		attempts []int
+		want     error
+	}{
+		{"transient error", nil, 2, errTransient, []int{1, 2, 3}, nil},
+		{"default attempts", nil, 3, errTransient, []int{1, 2, 3}, errTransient},
+		{"max attempts", []*options.TxOptions{options.Tx().SetMaxAttempts(5)}, 4, errTransient, []int{1, 2, 3, 4, 5}, nil},
+		{"single attempt", []*options.TxOptions{options.Tx().SetMaxAttempts(1)}, 1, errTransient, []int{1}, errTransient},
+		{"other error", nil, 1, errTx, []int{1}, errTx},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			c := db.Collection("c")
+
+			var attempts []int
+			err := db.client.Tx(ctx, func(tx TxContext) error {
+				attempts = append(attempts, tx.Attempt())
+
+				if _, err := c.InsertOne(tx, bson.D{{Key: "name", Value: "doc"}}); err != nil {
+					return err
+				}
+
+				if len(attempts) <= tt.failures {
+					return tt.fail
+				}
+
+				return nil
+			}, tt.opts...)
+
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Tx: error %v, want %v", err, tt.want)
+			}
+
+			if !reflect.DeepEqual(attempts, tt.attempts) {
+				t.Errorf("attempts = %v, want %v", attempts, tt.attempts)
+			}
+
+			// the writes of failed attempts are rolled back
+			want := []string{}
+			if tt.want == nil {
+				want = []string{"doc"}
+			}
+			if names := txNames(t, c); !reflect.DeepEqual(names, want) {
+				t.Errorf("documents = %v, want %v", names, want)
+			}
+		})
+	}
+
+	db := newTestDB(t)
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	runs := 0
+	err := db.client.Tx(canceled, func(TxContext) error {
+		runs++
+		return errTransient
+	})
+	if !errors.Is(err, errTransient) || runs != 1 {
+		t.Errorf("Tx with a canceled context = %v after %d runs, want no retry", err, runs)
+	}
+}
+
+func TestTxNested(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	c := db.Collection("c")
+
+	var inner []int
+	err := db.client.Tx(ctx, func(tx TxContext) error {
+		if _, err := c.InsertOne(tx, bson.D{{Key: "name", Value: "outer"}}); err != nil {
+			return err
+		}
+
+		err := db.client.Tx(tx, func(nested TxContext) error {
+			inner = append(inner, nested.Attempt())
+
+			_, err := c.InsertOne(nested, bson.D{{Key: "name", Value: "inner"}})
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		if tx.Attempt() == 1 {
+			return errTransient
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Tx: %v", err)
+	}
+
+	// the nested Tx runs in the attempt of the outer one rather than in a transaction of its own
+	if !reflect.DeepEqual(inner, []int{1, 2}) {
+		t.Errorf("attempts of the nested Tx = %v, want [1 2]", inner)
+	}
+
+	if names := txNames(t, c); !reflect.DeepEqual(names, []string{"outer", "inner"}) {
+		t.Errorf("documents = %v, want [outer inner]", names)
+	}
+
+	// an error of the nested Tx aborts the outer transaction once returned by it
+	err = db.client.Tx(ctx, func(tx TxContext) error {
+		return db.client.Tx(tx, func(nested TxContext) error {
+			if _, err := c.InsertOne(nested, bson.D{{Key: "name", Value: "aborted"}}); err != nil {
+				return err
+			}
+
+			return errTx
+		})
+	})
+	if !errors.Is(err, errTx) {
+		t.Errorf("Tx: error %v, want the error of the nested fn", err)
+	}
+
+	if names := txNames(t, c); !reflect.DeepEqual(names, []string{"outer", "inner"}) {
+		t.Errorf("documents after an aborted nested Tx = %v, want [outer inner]", names)
+	}
+}
@@ -0,0 +1,210 @@
+package mongorm
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/v1shn3vsk7/mongorm/options"
+)
+
+// TypedCollection is a collection whose documents are decoded into and encoded from T.
+// Raw driver methods stay available through the embedded Collection.
+type TypedCollection[T any] struct {
+	*Collection
+}
+
+// iterBatch is the number of documents Iter reads before preloading their relations.
+const iterBatch = 100
+
+// Iterator yields documents one by one together with the error that stopped the iteration.
+// Its signature matches iter.Seq2[T, error], so it can be ranged over with Go 1.23+.
+type Iterator[T any] func(yield func(T, error) bool)
+
+// CollectionOf returns the collection with the given name in db, typed with T.
+// The collection carries the model of T when T is registered.
+func CollectionOf[T any](db *Database, name string, opts ...*options.CollectionOptions) *TypedCollection[T] {
+	c := db.Collection(name, opts...)
+	c.docType = reflect.TypeOf((*T)(nil)).Elem()
+	// the model is the one of T, not of the collection name, since it describes the documents
+	if db.client != nil {
+		c.model, _ = db.client.models.lookup(structType(c.docType))
+	}
+
+	return &TypedCollection[T]{
+		Collection: c,
+	}
+}
+
+// CollectionFor returns the collection of the registered model T in db.
+// ErrUnknownModel is returned when T was not registered with Client.Register.
+func CollectionFor[T any](db *Database, opts ...*options.CollectionOptions) (*TypedCollection[T], error) {
+	t := structType(reflect.TypeOf((*T)(nil)).Elem())
+
+	if db.client == nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnknownModel, t)
+	}
+
+	m, ok := db.client.models.lookup(t)
+	if !ok {
+		return nil, fmt.Errorf("%w: %v", ErrUnknownModel, t)
+	}
+
+	return CollectionOf[T](db, m.Collection, opts...), nil
+}
+
+// One returns the first document matched by q. A nil q matches every document.
+func (c *TypedCollection[T]) One(ctx context.Context, q *Query) (T, error) {
+	var doc T
+	if err := c.bind(q).One(ctx, &doc); err != nil {
+		return doc, err
+	}
+
+	return doc, nil
+}
+
+// All returns every document matched by q. A nil q matches every document.
+func (c *TypedCollection[T]) All(ctx context.Context, q *Query) ([]T, error) {
+	docs := make([]T, 0)
+	if err := c.bind(q).All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	return docs, nil
+}
+
+// Iter streams the documents matched by q without loading them all into memory; with Preload it
+// reads batches of 100 documents to load their relations at once. The cursor is closed when the
+// iteration ends or yield returns false.
+func (c *TypedCollection[T]) Iter(ctx context.Context, q *Query) Iterator[T] {
+	q = c.bind(q)
+
+	return func(yield func(T, error) bool) {
+		var zero T
+
+		cursor, err := q.cursor(ctx)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		defer cursor.Close(ctx)
+
+		// relations are preloaded for batches of documents rather than one by one
+		size := 1
+		if len(q.preloads) > 0 {
+			size = iterBatch
+		}
+
+		batch := make([]T, 0, size)
+		flush := func() bool {
+			if err := q.found(ctx, &batch, false); err != nil {
+				yield(zero, err)
+				return false
+			}
+
+			for _, doc := range batch {
+				if !yield(doc, nil) {
+					return false
+				}
+			}
+
+			batch = batch[:0]
+
+			return true
+		}
+
+		for cursor.Next(ctx) {
+			var doc T
+			if err := cursor.Decode(&doc); err != nil {
+				yield(zero, q.mapErr("decode", err))
+				return
+			}
+
+			if batch = append(batch, doc); len(batch) == size && !flush() {
+				return
+			}
+		}
+
+		if len(batch) > 0 && !flush() {
+			return
+		}
+
+		if err := cursor.Err(); err != nil {
+			yield(zero, q.mapErr("find", err))
+		}
+	}
+}
+
+// InsertOne inserts doc and returns its _id.
+func (c *TypedCollection[T]) InsertOne(ctx context.Context, doc T) (interface{}, error) {
+	c.model.stampInsert(&doc, c.now())
+
+	if err := beforeInsert(ctx, &doc); err != nil {
+		return nil, c.mapErr("before insert", err)
+	}
+
+	res, err := c.storage().InsertOne(ctx, doc)
+	if err != nil {
+		return nil, c.mapErr("insert one", err)
+	}
+
+	if err := afterInsert(ctx, &doc); err != nil {
+		return res.InsertedID, c.mapErr("after insert", err)
+	}
+
+	return res.InsertedID, nil
+}
+
+// InsertMany inserts docs and returns their _ids in the same order.
+func (c *TypedCollection[T]) InsertMany(ctx context.Context, docs []T) ([]interface{}, error) {
+	if len(docs) == 0 {
+		return []interface{}{}, nil
+	}
+
+	docs = append([]T{}, docs...)
+	now := c.now()
+
+	raw := make([]interface{}, 0, len(docs))
+	for i := range docs {
+		c.model.stampInsert(&docs[i], now)
+
+		if err := beforeInsert(ctx, &docs[i]); err != nil {
+			return nil, c.mapErr("before insert", err)
+		}
+		raw = append(raw, docs[i])
+	}
+
+	res, err := c.storage().InsertMany(ctx, raw)
+	if err != nil {
+		return nil, c.mapErr("insert many", err)
+	}
+
+	for i := range docs {
+		if err := afterInsert(ctx, &docs[i]); err != nil {
+			return res.InsertedIDs, c.mapErr("after insert", err)
+		}
+	}
+
+	return res.InsertedIDs, nil
+}
+
+// bind returns q executed against this collection.
+func (c *TypedCollection[T]) bind(q *Query) *Query {
+	if q == nil {
+		return c.Query()
+	}
+
+	nq := q.Clone()
+	nq.collection = c.Collection
+
+	return nq
+}
+
+// structType returns t without its pointers: the models of documents of type *T are the ones of T.
+func structType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	return t
+}
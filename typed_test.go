@@ -0,0 +1,90 @@
+package mongorm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	mongo_options "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type item struct {
+	ID   int    `bson:"_id"`
+	Name string `bson:"name"`
+}
+
+func TestTypedCollection(t *testing.T) {
+	ctx := context.Background()
+	c := CollectionOf[item](newTestDB(t), "items")
+
+	if id, err := c.InsertOne(ctx, item{ID: 1, Name: "a"}); err != nil || id != int32(1) {
+		t.Fatalf("InsertOne = %v, %v, want 1", id, err)
+	}
+
+	ids, err := c.InsertMany(ctx, []item{{ID: 2, Name: "b"}, {ID: 3, Name: "c"}})
+	if err != nil || len(ids) != 2 {
+		t.Fatalf("InsertMany = %v, %v, want 2 ids", ids, err)
+	}
+
+	doc, err := c.One(ctx, c.Query().Where("name", EQ, "b"))
+	if err != nil || doc != (item{ID: 2, Name: "b"}) {
+		t.Errorf("One = %v, %v, want {2 b}", doc, err)
+	}
+
+	if _, err := c.One(ctx, c.Query().Where("name", EQ, "x")); !errors.Is(err, ErrNotFound) {
+		t.Errorf("One of nothing: error %v, want ErrNotFound", err)
+	}
+
+	// queries built without the collection are bound to it
+	docs, err := c.All(ctx, NewQuery().Where("_id", GTE, 2).Sort("_id", DESC))
+	if err != nil || len(docs) != 2 || docs[0].ID != 3 {
+		t.Errorf("All = %v, %v, want items 3 and 2", docs, err)
+	}
+
+	var seen []int
+	c.Iter(ctx, nil)(func(doc item, err error) bool {
+		if err != nil {
+			t.Fatalf("Iter: %v", err)
+		}
+
+		seen = append(seen, doc.ID)
+
+		return len(seen) < 2
+	})
+	if len(seen) != 2 {
+		t.Errorf("Iter stopped after %v, want 2 documents", seen)
+	}
+}
+
+func TestTypedCollectionOfPointers(t *testing.T) {
+	ctx := context.Background()
+	c := CollectionOf[*item](newTestDB(t), "items")
+
+	if _, err := c.InsertOne(ctx, &item{ID: 1, Name: "a"}); err != nil {
+		t.Fatalf("InsertOne: %v", err)
+	}
+
+	docs, err := c.All(ctx, nil)
+	if err != nil || len(docs) != 1 || *docs[0] != (item{ID: 1, Name: "a"}) {
+		t.Errorf("All = %v, %v, want [{1 a}]", docs, err)
+	}
+}
+
+func TestCollectionOfWithoutClient(t *testing.T) {
+	client, err := mongo.NewClient(mongo_options.Client().ApplyURI("mongodb://localhost:27017"))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	// databases built around a driver database have no mongorm client
+	db := &Database{Database: client.Database("test")}
+
+	if c := CollectionOf[item](db, "items"); c.Model() != nil {
+		t.Errorf("Model() = %v, want nil", c.Model())
+	}
+
+	if _, err := CollectionFor[item](db); !errors.Is(err, ErrUnknownModel) {
+		t.Errorf("CollectionFor: error %v, want ErrUnknownModel", err)
+	}
+}
@@ -0,0 +1,481 @@
+package mongorm
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	mongo_options "go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/v1shn3vsk7/mongorm/internal/matcher"
+	"github.com/v1shn3vsk7/mongorm/options"
+)
+
+// UnitOfWork collects the changes of model instances, given as pointers to registered structs,
+// and writes them in one transaction on Commit: new instances are inserted, tracked instances
+// that changed since they were loaded are updated with the fields that changed, and removed
+// instances are deleted. A UnitOfWork is safe for concurrent use.
+type UnitOfWork struct {
+	db *Database
+
+	mu      sync.Mutex
+	entries []*unitEntry
+	byDoc   map[interface{}]*unitEntry
+}
+
+type unitState int
+
+const (
+	unitTracked unitState = iota
+	unitNew
+	unitRemoved
+)
+
+type unitEntry struct {
+	model *Model
+	doc   interface{}
+	state unitState
+
+	// snapshot is the document as stored when it was loaded or last committed.
+	snapshot bson.D
+
+	// version is the version of the document when it was loaded or last committed.
+	version int64
+}
+
+// unitWrites are the writes of a commit to one collection.
+type unitWrites struct {
+	collection string
+	models     []mongo.WriteModel
+	updates    int64
+	deletes    int64
+
+	// versioned is set when the filters include the versions of the documents.
+	versioned bool
+}
+
+// UnitOfWork returns an empty unit of work on db.
+func (db *Database) UnitOfWork() *UnitOfWork {
+	return &UnitOfWork{
+		db:    db,
+		byDoc: make(map[interface{}]*unitEntry),
+	}
+}
+
+// Add registers new instances, inserted on Commit. A zero ObjectID primary key is generated right away.
+func (u *UnitOfWork) Add(docs ...interface{}) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	for _, doc := range docs {
+		m, err := u.model(doc)
+		if err != nil {
+			return err
+		}
+
+		if e, ok := u.byDoc[doc]; ok {
+			if e.state != unitNew {
+				return fmt.Errorf("%w: %T is already tracked by the unit of work", ErrInvalidValue, doc)
+			}
+			continue
+		}
+
+		if pk := m.PK.value(doc); pk.IsZero() && pk.Type() == reflect.TypeOf(primitive.ObjectID{}) {
+			pk.Set(reflect.ValueOf(primitive.NewObjectID()))
+		}
+
+		u.add(&unitEntry{model: m, doc: doc, state: unitNew})
+	}
+
+	return nil
+}
+
+// Track registers loaded instances, taking the snapshot their changes are compared with on Commit.
+// Tracking an instance again takes a new snapshot.
+func (u *UnitOfWork) Track(docs ...interface{}) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	for _, doc := range docs {
+		m, err := u.model(doc)
+		if err != nil {
+			return err
+		}
+
+		snapshot, err := matcher.Normalize(doc)
+		if err != nil {
+			return err
+		}
+
+		if e, ok := u.byDoc[doc]; ok {
+			e.state, e.snapshot, e.version = unitTracked, snapshot, m.version(doc)
+			continue
+		}
+
+		u.add(&unitEntry{model: m, doc: doc, state: unitTracked, snapshot: snapshot, version: m.version(doc)})
+	}
+
+	return nil
+}
+
+// Load runs q and decodes the matched documents into dst, a pointer to a struct for the first
+// document or to a slice of structs or struct pointers for all of them, and tracks them. The
+// elements of a slice are tracked by address, so the slice must not be grown afterwards.
+func (u *UnitOfWork) Load(ctx context.Context, q *Query, dst interface{}) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return fmt.Errorf("%w: %T is not a pointer", ErrInvalidValue, dst)
+	}
+
+	if v.Elem().Kind() != reflect.Slice {
+		if err := q.One(ctx, dst); err != nil {
+			return err
+		}
+
+		return u.Track(dst)
+	}
+
+	if err := q.All(ctx, dst); err != nil {
+		return err
+	}
+
+	items := v.Elem()
+	docs := make([]interface{}, 0, items.Len())
+	for i := 0; i < items.Len(); i++ {
+		item := items.Index(i)
+		if item.Kind() != reflect.Pointer {
+			item = item.Addr()
+		}
+		docs = append(docs, item.Interface())
+	}
+
+	return u.Track(docs...)
+}
+
+// Remove registers instances to delete on Commit. Removing a new instance forgets it. Instances
+// of models with a deletedAt field are soft deleted.
+func (u *UnitOfWork) Remove(docs ...interface{}) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	for _, doc := range docs {
+		m, err := u.model(doc)
+		if err != nil {
+			return err
+		}
+
+		if e, ok := u.byDoc[doc]; ok {
+			if e.state == unitNew {
+				u.forget(e)
+			} else {
+				e.state = unitRemoved
+			}
+			continue
+		}
+
+		u.add(&unitEntry{model: m, doc: doc, state: unitRemoved, version: m.version(doc)})
+	}
+
+	return nil
+}
+
+// Commit writes the registered changes with one ordered bulk write per collection in a transaction
+// run by Client.Tx. ErrNotFound is returned, and the transaction aborted, when a tracked or removed
+// instance no longer exists, and ErrVersionConflict for versioned models when its version changed
+// since it was loaded. After a successful commit new and changed instances are tracked with their
+// committed state and removed ones are forgotten; after a failed one the unit of work is unchanged,
+// while the fields the models maintain, e.g. updatedAt or the version, may have been set already.
+//
+// The BeforeInsert, BeforeUpdate and BeforeDelete hooks of the instances are called in the
+// transaction before anything is written, and the AfterInsert hooks once everything is written.
+func (u *UnitOfWork) Commit(ctx context.Context, opts ...*options.TxOptions) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if len(u.entries) == 0 {
+		return nil
+	}
+
+	var snapshots map[*unitEntry]bson.D
+
+	err := u.db.client.Tx(ctx, func(tx TxContext) error {
+		writes, s, err := u.plan(tx)
+		if err != nil {
+			return err
+		}
+		snapshots = s
+
+		for _, w := range writes {
+			c := u.db.Collection(w.collection)
+
+			res, err := c.storage().BulkWrite(tx, w.models)
+			if err != nil {
+				return c.mapErr("bulk write", err)
+			}
+
+			if res.MatchedCount < w.updates || res.DeletedCount < w.deletes {
+				notFound := ErrNotFound
+				if w.versioned {
+					notFound = ErrVersionConflict
+				}
+
+				return fmt.Errorf("mongorm: bulk write %s: %w: %d of %d updated and %d of %d deleted documents found",
+					w.collection, notFound, res.MatchedCount, w.updates, res.DeletedCount, w.deletes)
+			}
+		}
+
+		for _, e := range u.entries {
+			if e.state != unitNew {
+				continue
+			}
+
+			if err := afterInsert(tx, e.doc); err != nil {
+				return fmt.Errorf("mongorm: after insert %s: %w", e.model.Collection, err)
+			}
+		}
+
+		return nil
+	}, opts...)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range append([]*unitEntry{}, u.entries...) {
+		if e.state == unitRemoved {
+			u.forget(e)
+			continue
+		}
+
+		e.state, e.snapshot, e.version = unitTracked, snapshots[e], e.model.version(e.doc)
+	}
+
+	return nil
+}
+
+// plan calls the before hooks of the instances and returns the writes of the registered changes
+// grouped by collection, in the order the collections were first registered, and the snapshots
+// of new and tracked instances.
+func (u *UnitOfWork) plan(ctx context.Context) ([]*unitWrites, map[*unitEntry]bson.D, error) {
+	now := u.db.client.Now()
+
+	var writes []*unitWrites
+	byCollection := make(map[string]*unitWrites)
+	snapshots := make(map[*unitEntry]bson.D, len(u.entries))
+
+	for _, e := range u.entries {
+		var model mongo.WriteModel
+
+		switch e.state {
+		case unitNew:
+			e.model.stampInsert(e.doc, now)
+
+			if err := beforeInsert(ctx, e.doc); err != nil {
+				return nil, nil, fmt.Errorf("mongorm: before insert %s: %w", e.model.Collection, err)
+			}
+
+			doc, err := matcher.Normalize(e.doc)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			snapshots[e] = doc
+			model = mongo.NewInsertOneModel().SetDocument(doc)
+		case unitTracked:
+			doc, err := matcher.Normalize(e.doc)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			snapshots[e] = doc
+
+			update, err := diffUpdate(e.snapshot, doc)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%w: %T: %v", ErrInvalidValue, e.doc, err)
+			}
+
+			if update == nil {
+				continue
+			}
+
+			// the version is derived from the loaded one, so that a retried transaction sets it again
+			if e.model.UpdatedAt != nil || e.model.Version != nil {
+				e.model.stampUpdate(e.doc, now)
+				e.model.setVersion(e.doc, e.version+1)
+
+				if doc, err = matcher.Normalize(e.doc); err != nil {
+					return nil, nil, err
+				}
+				snapshots[e] = doc
+
+				if update, err = diffUpdate(e.snapshot, doc); err != nil {
+					return nil, nil, fmt.Errorf("%w: %T: %v", ErrInvalidValue, e.doc, err)
+				}
+			}
+
+			if update, err = beforeUpdate(ctx, e.doc, update); err != nil {
+				return nil, nil, fmt.Errorf("mongorm: before update %s: %w", e.model.Collection, err)
+			}
+
+			if update == nil || len(update.ops) == 0 {
+				return nil, nil, ErrEmptyUpdate
+			}
+
+			if update.err != nil {
+				return nil, nil, update.err
+			}
+
+			updateModel := mongo.NewUpdateOneModel().SetFilter(e.filter()).SetUpdate(update.Bson())
+			if update.arrayFilters != nil {
+				updateModel.SetArrayFilters(mongo_options.ArrayFilters{Filters: update.arrayFilters})
+			}
+			model = updateModel
+		case unitRemoved:
+			q := u.db.Collection(e.model.Collection).Query().Where("_id", EQ, e.model.PK.value(e.doc).Interface())
+			if err := beforeDelete(ctx, e.doc, q); err != nil {
+				return nil, nil, fmt.Errorf("mongorm: before delete %s: %w", e.model.Collection, err)
+			}
+
+			model = mongo.NewDeleteOneModel().SetFilter(e.filter())
+
+			// soft deleted instances are updated, and must not be deleted already
+			if f := e.model.DeletedAt; f != nil {
+				if v, ok := f.settable(e.doc); ok {
+					setTimestamp(v, now)
+				}
+				e.model.stampUpdate(e.doc, now)
+				e.model.setVersion(e.doc, e.version+1)
+
+				update := e.model.fieldUpdates(NewUpdate().Set(f.Path, now), now)
+				model = mongo.NewUpdateOneModel().SetFilter(andFilter(e.filter(), notDeleted(f))).SetUpdate(update.Bson())
+			}
+		}
+
+		w, ok := byCollection[e.model.Collection]
+		if !ok {
+			w = &unitWrites{collection: e.model.Collection, versioned: e.model.Version != nil}
+			byCollection[e.model.Collection] = w
+			writes = append(writes, w)
+		}
+
+		w.models = append(w.models, model)
+
+		switch model.(type) {
+		case *mongo.UpdateOneModel:
+			w.updates++
+		case *mongo.DeleteOneModel:
+			w.deletes++
+		}
+	}
+
+	return writes, snapshots, nil
+}
+
+// model returns the model of doc, which must be a non-nil pointer to a registered struct with a primary key.
+func (u *UnitOfWork) model(doc interface{}) (*Model, error) {
+	v := reflect.ValueOf(doc)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%w: %T is not a pointer to a struct", ErrInvalidModel, doc)
+	}
+
+	m, err := u.db.client.Model(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.PK == nil {
+		return nil, fmt.Errorf("%w: %v has no primary key", ErrInvalidModel, m.Type)
+	}
+
+	return m, nil
+}
+
+func (u *UnitOfWork) add(e *unitEntry) {
+	u.entries = append(u.entries, e)
+	u.byDoc[e.doc] = e
+}
+
+func (u *UnitOfWork) forget(e *unitEntry) {
+	delete(u.byDoc, e.doc)
+
+	for i, other := range u.entries {
+		if other == e {
+			u.entries = append(u.entries[:i:i], u.entries[i+1:]...)
+			break
+		}
+	}
+}
+
+// filter selects the stored document of the entry by its primary key and, for versioned models,
+// by the version it was loaded with.
+func (e *unitEntry) filter() bson.D {
+	filter := bson.D{{Key: "_id", Value: e.model.PK.value(e.doc).Interface()}}
+	if e.model.Version != nil {
+		filter = append(filter, bson.E{Key: e.model.Version.Path, Value: e.version})
+	}
+
+	return filter
+}
+
+// value returns the addressable value of the field in doc, a pointer to a struct of the model.
+func (f *Field) value(doc interface{}) reflect.Value {
+	return reflect.ValueOf(doc).Elem().FieldByIndex(f.index)
+}
+
+// diffUpdate returns the update turning the document before into after: $set of the changed and
+// added paths, descending into embedded documents, and $unset of the removed ones. It is nil
+// when nothing changed.
+func diffUpdate(before, after bson.D) (*Update, error) {
+	set, unset := bson.D{}, bson.D{}
+	diffDocs("", before, after, &set, &unset)
+
+	for _, e := range append(append(bson.D{}, set...), unset...) {
+		if e.Key == "_id" {
+			return nil, fmt.Errorf("the primary key cannot change")
+		}
+	}
+
+	if len(set) == 0 && len(unset) == 0 {
+		return nil, nil
+	}
+
+	update := NewUpdate()
+	for _, e := range set {
+		update = update.Set(e.Key, e.Value)
+	}
+	for _, e := range unset {
+		update = update.Unset(e.Key)
+	}
+
+	return update, update.Err()
+}
+
+func diffDocs(prefix string, before, after bson.D, set, unset *bson.D) {
+	for _, e := range after {
+		old, ok := matcher.Get(before, e.Key)
+
+		switch {
+		case !ok:
+			*set = append(*set, bson.E{Key: prefix + e.Key, Value: e.Value})
+		case matcher.Equal(old, e.Value) && reflect.TypeOf(old) == reflect.TypeOf(e.Value):
+		default:
+			oldDoc, oldIsDoc := old.(bson.D)
+			newDoc, newIsDoc := e.Value.(bson.D)
+
+			if oldIsDoc && newIsDoc && len(newDoc) > 0 {
+				diffDocs(prefix+e.Key+".", oldDoc, newDoc, set, unset)
+			} else {
+				*set = append(*set, bson.E{Key: prefix + e.Key, Value: e.Value})
+			}
+		}
+	}
+
+	for _, e := range before {
+		if _, ok := matcher.Get(after, e.Key); !ok {
+			*unset = append(*unset, bson.E{Key: prefix + e.Key, Value: ""})
+		}
+	}
+}
@@ -0,0 +1,312 @@
+package mongorm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/v1shn3vsk7/mongorm/options"
+)
+
+type unitAccount struct {
+	ID      primitive.ObjectID `bson:"_id"`
+	Name    string             `bson:"name"`
+	Profile struct {
+		City string `bson:"city"`
+		Zip  string `bson:"zip"`
+	} `bson:"profile"`
+	Tags      []string  `bson:"tags"`
+	Version   int64     `bson:"version" mongorm:"version"`
+	UpdatedAt time.Time `bson:"updated_at" mongorm:"updatedAt"`
+}
+
+type unitNote struct {
+	ID        int            `bson:"_id"`
+	Text      string         `bson:"text"`
+	Attrs     map[string]int `bson:"attrs,omitempty"`
+	DeletedAt *time.Time     `bson:"deleted_at" mongorm:"deletedAt"`
+}
+
+var unitNow = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func newUnitDB(t *testing.T) *Database {
+	t.Helper()
+
+	db := newTestDB(t, options.Client().SetClock(func() time.Time { return unitNow }))
+	if err := db.client.Register(&unitAccount{}, &unitNote{}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	return db
+}
+
+// storedJSON returns the stored document with the given _id in extended JSON.
+func storedJSON(t *testing.T, c *Collection, id interface{}) string {
+	t.Helper()
+
+	var doc bson.D
+	if err := c.FindOne(context.Background(), bson.D{{Key: "_id", Value: id}}).Decode(&doc); err != nil {
+		t.Fatalf("FindOne %v: %v", id, err)
+	}
+
+	return renderJSON(t, doc)
+}
+
+func TestDiffUpdate(t *testing.T) {
+	tests := []struct {
+		name          string
+		before, after string
+		want          string
+	}{
+		{"unchanged", `{"a": 1, "b": {"c": [1, 2]}}`, `{"a": 1, "b": {"c": [1, 2]}}`, ``},
+		{"changed field", `{"a": 1, "b": "x"}`, `{"a": 2, "b": "x"}`, `{"$set":{"a":2}}`},
+		{"type change", `{"a": 1}`, `{"a": {"$numberLong": "1"}}`, `{"$set":{"a":1}}`},
+		{"added field", `{"a": 1}`, `{"a": 1, "b": true}`, `{"$set":{"b":true}}`},
+		{"removed field", `{"a": 1, "b": true}`, `{"a": 1}`, `{"$unset":{"b":""}}`},
+		{"embedded document", `{"p": {"city": "x", "zip": "1"}}`, `{"p": {"city": "y", "zip": "1"}}`, `{"$set":{"p.city":"y"}}`},
+		{"removed embedded field", `{"p": {"city": "x", "zip": "1"}}`, `{"p": {"city": "x"}}`, `{"$unset":{"p.zip":""}}`},
+		{"emptied document", `{"p": {"city": "x"}}`, `{"p": {}}`, `{"$set":{"p":{}}}`},
+		{"document replacing a value", `{"p": null}`, `{"p": {"city": "x"}}`, `{"$set":{"p":{"city":"x"}}}`},
+		{"array", `{"tags": ["a", "b"]}`, `{"tags": ["a", "c"]}`, `{"$set":{"tags":["a","c"]}}`},
+		{"set and unset", `{"a": 1, "b": 2}`, `{"a": 3, "c": 4}`, `{"$set":{"a":3,"c":4},"$unset":{"b":""}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			update, err := diffUpdate(extJSON(t, tt.before), extJSON(t, tt.after))
+			if err != nil {
+				t.Fatalf("diffUpdate: %v", err)
+			}
+
+			got := ""
+			if update != nil {
+				got = renderJSON(t, update.Bson())
+			}
+
+			if got != tt.want {
+				t.Errorf("diffUpdate = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := diffUpdate(extJSON(t, `{"_id": 1}`), extJSON(t, `{"_id": 2}`)); err == nil {
+		t.Error("diffUpdate of a changed _id succeeded")
+	}
+}
+
+func TestUnitOfWork(t *testing.T) {
+	ctx := context.Background()
+	db := newUnitDB(t)
+	accounts := db.Collection("unit_accounts")
+
+	u := db.UnitOfWork()
+
+	account := &unitAccount{Name: "ann", Tags: []string{"a"}}
+	account.Profile.City = "paris"
+	if err := u.Add(account, account); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	if account.ID.IsZero() {
+		t.Error("Add did not generate the ObjectID of the instance")
+	}
+
+	if err := u.Commit(ctx); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	// the committed instance is tracked: only the changed fields are written
+	if _, err := accounts.UpdateByID(ctx, account.ID, bson.D{{Key: "$set", Value: bson.D{{Key: "profile.zip", Value: "75001"}}}}); err != nil {
+		t.Fatalf("UpdateByID: %v", err)
+	}
+
+	account.Profile.City = "lyon"
+	account.Tags = append(account.Tags, "b")
+	if err := u.Commit(ctx); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	want := `{"_id":{"$oid":"` + account.ID.Hex() + `"},"name":"ann","profile":{"city":"lyon","zip":"75001"},"tags":["a","b"],` +
+		`"version":1,"updated_at":{"$date":"2024-05-01T12:00:00Z"}}`
+	if got := storedJSON(t, accounts, account.ID); got != want {
+		t.Errorf("stored account = %s, want %s", got, want)
+	}
+
+	if account.Version != 1 {
+		t.Errorf("Version = %d, want 1", account.Version)
+	}
+
+	// an unchanged instance is not written
+	if err := u.Commit(ctx); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	if got := storedJSON(t, accounts, account.ID); got != want {
+		t.Errorf("stored account after an empty commit = %s, want %s", got, want)
+	}
+
+	if err := u.Remove(account); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if err := u.Commit(ctx); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	if n, err := accounts.CountDocuments(ctx, bson.D{}); err != nil || n != 0 {
+		t.Errorf("CountDocuments after Remove = %d, %v, want 0", n, err)
+	}
+
+	// the removed instance is forgotten, so it can be added again
+	if err := u.Add(account); err != nil {
+		t.Errorf("Add of a removed instance: %v", err)
+	}
+}
+
+func TestUnitOfWorkLoad(t *testing.T) {
+	ctx := context.Background()
+	db := newUnitDB(t)
+	notes := db.Collection("unit_notes")
+
+	for _, doc := range []string{`{"_id": 1, "text": "a", "attrs": {"x": 1, "y": 2}}`, `{"_id": 2, "text": "b"}`} {
+		if _, err := notes.InsertOne(ctx, extJSON(t, doc)); err != nil {
+			t.Fatalf("InsertOne: %v", err)
+		}
+	}
+
+	u := db.UnitOfWork()
+
+	var loaded []unitNote
+	if err := u.Load(ctx, notes.Query().Sort("_id", ASC), &loaded); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	var one unitNote
+	if err := u.Load(ctx, notes.Query().Where("_id", EQ, 2), &one); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	delete(loaded[0].Attrs, "y")
+	loaded[1].Text = "changed"
+	if err := u.Remove(&one); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+
+	if err := u.Commit(ctx); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	if got, want := storedJSON(t, notes, 1), `{"_id":1,"text":"a","attrs":{"x":1}}`; got != want {
+		t.Errorf("stored note 1 = %s, want %s", got, want)
+	}
+
+	// the soft delete of one and the change of loaded[1] are written to the same document
+	if got, want := storedJSON(t, notes, 2), `{"_id":2,"text":"changed","deleted_at":{"$date":"2024-05-01T12:00:00Z"}}`; got != want {
+		t.Errorf("stored note 2 = %s, want %s", got, want)
+	}
+
+	if err := u.Load(ctx, notes.Query(), one); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("Load into a struct value: error %v, want ErrInvalidValue", err)
+	}
+}
+
+func TestUnitOfWorkConflicts(t *testing.T) {
+	ctx := context.Background()
+	db := newUnitDB(t)
+	accounts := db.Collection("unit_accounts")
+
+	stored := &unitAccount{Name: "ann"}
+	u := db.UnitOfWork()
+	if err := u.Add(stored); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if err := u.Commit(ctx); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	// another writer changes the version
+	if _, err := accounts.UpdateByID(ctx, stored.ID, bson.D{{Key: "$inc", Value: bson.D{{Key: "version", Value: 1}}}}); err != nil {
+		t.Fatalf("UpdateByID: %v", err)
+	}
+
+	before := storedJSON(t, accounts, stored.ID)
+	stored.Name = "bob"
+	if err := u.Commit(ctx); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("Commit: error %v, want ErrVersionConflict", err)
+	}
+	if got := storedJSON(t, accounts, stored.ID); got != before {
+		t.Errorf("stored account after a conflict = %s, want %s", got, before)
+	}
+
+	// loading the current document resolves the conflict
+	var current unitAccount
+	u = db.UnitOfWork()
+	if err := u.Load(ctx, accounts.Query().Where("_id", EQ, stored.ID), &current); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	current.Name = "bob"
+	if err := u.Commit(ctx); err != nil || current.Version != 2 {
+		t.Errorf("Commit of the current document = %v with version %d, want version 2", err, current.Version)
+	}
+
+	u = db.UnitOfWork()
+	if err := u.Remove(stored); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if err := u.Commit(ctx); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("Commit removing a stale instance: error %v, want ErrVersionConflict", err)
+	}
+
+	missing := &unitNote{ID: 7, Text: "a"}
+	u = db.UnitOfWork()
+	if err := u.Track(missing); err != nil {
+		t.Fatalf("Track: %v", err)
+	}
+	missing.Text = "b"
+	if err := u.Commit(ctx); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Commit of a missing instance: error %v, want ErrNotFound", err)
+	}
+
+	note := &unitNote{ID: 8}
+	u = db.UnitOfWork()
+	if err := u.Track(note); err != nil {
+		t.Fatalf("Track: %v", err)
+	}
+	note.ID = 9
+	if err := u.Commit(ctx); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("Commit of a changed primary key: error %v, want ErrInvalidValue", err)
+	}
+}
+
+func TestUnitOfWorkInvalid(t *testing.T) {
+	db := newUnitDB(t)
+	u := db.UnitOfWork()
+
+	tracked := &unitNote{ID: 1}
+	if err := u.Track(tracked); err != nil {
+		t.Fatalf("Track: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		doc  interface{}
+		want error
+	}{
+		{"struct value", unitNote{}, ErrInvalidModel},
+		{"nil pointer", (*unitNote)(nil), ErrInvalidModel},
+		{"unregistered model", &item{}, ErrUnknownModel},
+		{"tracked instance", tracked, ErrInvalidValue},
+	}
+
+	for _, tt := range tests {
+		if err := u.Add(tt.doc); !errors.Is(err, tt.want) {
+			t.Errorf("Add of a %s: error %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	if err := (&Database{}).UnitOfWork().Add(&unitNote{}); !errors.Is(err, ErrUnknownModel) {
+		t.Errorf("Add without a client: error %v, want ErrUnknownModel", err)
+	}
+}
@@ -0,0 +1,280 @@
+package mongorm
+
+import (
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/v1shn3vsk7/mongorm/internal/operators"
+)
+
+// Update is an immutable builder of update documents. Like Query, every method returns
+// a new Update and leaves the receiver untouched.
+type Update struct {
+	ops          []updateOp
+	arrayFilters []interface{}
+	err          error
+}
+
+type updateOp struct {
+	operator string
+	path     string
+	value    interface{}
+}
+
+// PushModifiers are the optional modifiers of PushEach.
+type PushModifiers struct {
+	// Slice keeps only the first (positive) or last (negative) elements of the array after the push.
+	Slice *int
+	// Sort orders the array after the push: a SortDirection for arrays of values
+	// or a bson.D of fields and directions for arrays of documents.
+	Sort interface{}
+	// Position inserts the values at the given index instead of appending them.
+	Position *int
+}
+
+func NewUpdate() *Update {
+	return &Update{}
+}
+
+// Set sets field to value.
+func (u *Update) Set(field string, value interface{}) *Update {
+	return u.with(updateOp{operator: operators.SET, path: field, value: value})
+}
+
+// SetOnInsert sets field to value only when an upsert inserts a new document.
+func (u *Update) SetOnInsert(field string, value interface{}) *Update {
+	return u.with(updateOp{operator: operators.SET_ON_INSERT, path: field, value: value})
+}
+
+// Unset removes fields.
+func (u *Update) Unset(fields ...string) *Update {
+	nu := u
+	for _, field := range fields {
+		nu = nu.with(updateOp{operator: operators.UNSET, path: field, value: ""})
+	}
+
+	return nu
+}
+
+// Inc increments field by n.
+func (u *Update) Inc(field string, n interface{}) *Update {
+	return u.withNumber(operators.INC, field, n)
+}
+
+// Mul multiplies field by n.
+func (u *Update) Mul(field string, n interface{}) *Update {
+	return u.withNumber(operators.MUL, field, n)
+}
+
+// Min sets field to value if value is less than the current value.
+func (u *Update) Min(field string, value interface{}) *Update {
+	return u.with(updateOp{operator: operators.MIN, path: field, value: value})
+}
+
+// Max sets field to value if value is greater than the current value.
+func (u *Update) Max(field string, value interface{}) *Update {
+	return u.with(updateOp{operator: operators.MAX, path: field, value: value})
+}
+
+// Rename renames field from to to.
+func (u *Update) Rename(from, to string) *Update {
+	return u.with(updateOp{operator: operators.RENAME, path: from, value: to}, to)
+}
+
+// CurrentDate sets field to the current date of the server.
+func (u *Update) CurrentDate(field string) *Update {
+	return u.with(updateOp{operator: operators.CURRENT_DATE, path: field, value: true})
+}
+
+// CurrentTimestamp sets field to the current timestamp of the server.
+func (u *Update) CurrentTimestamp(field string) *Update {
+	return u.with(updateOp{operator: operators.CURRENT_DATE, path: field, value: bson.D{{Key: operators.TYPE, Value: "timestamp"}}})
+}
+
+// Push appends value to the array field.
+func (u *Update) Push(field string, value interface{}) *Update {
+	return u.with(updateOp{operator: operators.PUSH, path: field, value: value})
+}
+
+// PushEach appends every element of values, which must be a slice, to the array field.
+func (u *Update) PushEach(field string, values interface{}, mods *PushModifiers) *Update {
+	each := bson.D{{Key: operators.EACH, Value: values}}
+
+	if mods != nil {
+		if mods.Slice != nil {
+			each = append(each, bson.E{Key: operators.SLICE, Value: *mods.Slice})
+		}
+		if mods.Sort != nil {
+			each = append(each, bson.E{Key: operators.SORT, Value: mods.Sort})
+		}
+		if mods.Position != nil {
+			each = append(each, bson.E{Key: operators.POSITION, Value: *mods.Position})
+		}
+	}
+
+	nu := u.with(updateOp{operator: operators.PUSH, path: field, value: each})
+	if !isList(values) {
+		nu.setErr(fmt.Errorf("%w: %s %s requires a slice, got %T", ErrInvalidValue, operators.PUSH, operators.EACH, values))
+	}
+
+	return nu
+}
+
+// AddToSet appends value to the array field unless it is already present.
+func (u *Update) AddToSet(field string, value interface{}) *Update {
+	return u.with(updateOp{operator: operators.ADD_TO_SET, path: field, value: value})
+}
+
+// AddToSetEach appends every element of values, which must be a slice, that is not already present in the array field.
+func (u *Update) AddToSetEach(field string, values interface{}) *Update {
+	nu := u.with(updateOp{operator: operators.ADD_TO_SET, path: field, value: bson.D{{Key: operators.EACH, Value: values}}})
+	if !isList(values) {
+		nu.setErr(fmt.Errorf("%w: %s %s requires a slice, got %T", ErrInvalidValue, operators.ADD_TO_SET, operators.EACH, values))
+	}
+
+	return nu
+}
+
+// Pull removes the elements of the array field that are equal to cond or, if cond is a *Query, match it.
+// Inside the query an empty key applies the condition to the element itself.
+func (u *Update) Pull(field string, cond interface{}) *Update {
+	q, ok := cond.(*Query)
+	if !ok {
+		return u.with(updateOp{operator: operators.PULL, path: field, value: cond})
+	}
+
+	if q == nil {
+		nu := u.with(updateOp{operator: operators.PULL, path: field, value: nil})
+		nu.setErr(fmt.Errorf("%w: %s requires a non-nil *Query", ErrInvalidValue, operators.PULL))
+
+		return nu
+	}
+
+	nu := u.with(updateOp{operator: operators.PULL, path: field, value: q.Bson()})
+	nu.setErr(q.Err())
+
+	return nu
+}
+
+// PullAll removes every occurrence of the elements of values, which must be a slice, from the array field.
+func (u *Update) PullAll(field string, values interface{}) *Update {
+	nu := u.with(updateOp{operator: operators.PULL_ALL, path: field, value: values})
+	if !isList(values) {
+		nu.setErr(fmt.Errorf("%w: %s requires a slice, got %T", ErrInvalidValue, operators.PULL_ALL, values))
+	}
+
+	return nu
+}
+
+// Pop removes the first or the last element of the array field.
+func (u *Update) Pop(field string, first bool) *Update {
+	end := 1
+	if first {
+		end = -1
+	}
+
+	return u.with(updateOp{operator: operators.POP, path: field, value: end})
+}
+
+// ArrayFilters sets the filters that select the array elements updated through the $[<identifier>] positional operator.
+func (u *Update) ArrayFilters(filters ...*Query) *Update {
+	nu := u.clone()
+	nu.arrayFilters = make([]interface{}, 0, len(u.arrayFilters)+len(filters))
+	nu.arrayFilters = append(nu.arrayFilters, u.arrayFilters...)
+
+	for _, filter := range filters {
+		if filter == nil {
+			nu.setErr(fmt.Errorf("%w: array filters must not be nil", ErrInvalidValue))
+			continue
+		}
+
+		nu.arrayFilters = append(nu.arrayFilters, filter.Bson())
+		nu.setErr(filter.Err())
+	}
+
+	return nu
+}
+
+// Err returns the first error found while building the update.
+func (u *Update) Err() error {
+	return u.err
+}
+
+// Bson renders the update document, grouping the fields by operator in the order the operators were first used.
+func (u *Update) Bson() bson.D {
+	doc := make(bson.D, 0)
+	index := make(map[string]int)
+
+	for _, op := range u.ops {
+		i, ok := index[op.operator]
+		if !ok {
+			i = len(doc)
+			index[op.operator] = i
+			doc = append(doc, bson.E{Key: op.operator, Value: bson.D{}})
+		}
+
+		doc[i].Value = append(doc[i].Value.(bson.D), bson.E{Key: op.path, Value: op.value})
+	}
+
+	return doc
+}
+
+func (u *Update) withNumber(operator, field string, n interface{}) *Update {
+	nu := u.with(updateOp{operator: operator, path: field, value: n})
+	if !isNumber(n) {
+		nu.setErr(fmt.Errorf("%w: %s requires a number, got %T", ErrInvalidValue, operator, n))
+	}
+
+	return nu
+}
+
+func (u *Update) clone() *Update {
+	nu := *u
+	nu.ops = append(make([]updateOp, 0, len(u.ops)+1), u.ops...)
+
+	return &nu
+}
+
+// with returns a copy of the update with op appended. Paths are the fields op modifies besides its own path.
+func (u *Update) with(op updateOp, paths ...string) *Update {
+	nu := u.clone()
+	nu.checkPath(op.path)
+
+	for _, path := range paths {
+		nu.checkPath(path)
+	}
+
+	nu.ops = append(nu.ops, op)
+
+	return nu
+}
+
+// checkPath records an error if path or one of its parents or children is already modified by the update.
+func (u *Update) checkPath(path string) {
+	for _, op := range u.ops {
+		paths := []string{op.path}
+		if op.operator == operators.RENAME {
+			paths = append(paths, op.value.(string))
+		}
+
+		for _, p := range paths {
+			if overlaps(p, path) {
+				u.setErr(fmt.Errorf("%w: %q and %q", ErrConflictingUpdate, p, path))
+				return
+			}
+		}
+	}
+}
+
+// setErr must only be called on an update that has not been returned to the caller yet.
+func (u *Update) setErr(err error) {
+	if u.err == nil {
+		u.err = err
+	}
+}
+
+func overlaps(a, b string) bool {
+	return a == b || strings.HasPrefix(a, b+".") || strings.HasPrefix(b, a+".")
+}
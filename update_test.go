@@ -0,0 +1,132 @@
+package mongorm
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestUpdateBson(t *testing.T) {
+	slice := -2
+
+	tests := []struct {
+		name   string
+		update *Update
+		want   string
+	}{
+		{"grouped by operator", NewUpdate().Set("a", 1).Inc("n", 2).Set("b", "x"), `{"$set":{"a":1,"b":"x"},"$inc":{"n":2}}`},
+		{"unset", NewUpdate().Unset("a", "b"), `{"$unset":{"a":"","b":""}}`},
+		{"rename", NewUpdate().Rename("a", "b"), `{"$rename":{"a":"b"}}`},
+		{"push each", NewUpdate().PushEach("a", []int{1, 2}, &PushModifiers{Slice: &slice}), `{"$push":{"a":{"$each":[1,2],"$slice":-2}}}`},
+		{"pull query", NewUpdate().Pull("a", NewQuery().Where("", GTE, 5)), `{"$pull":{"a":{"$gte":5}}}`},
+		{"pull value", NewUpdate().Pull("a", 5), `{"$pull":{"a":5}}`},
+		{"pop first", NewUpdate().Pop("a", true), `{"$pop":{"a":-1}}`},
+		{"sibling paths", NewUpdate().Set("a.b", 1).Inc("a.c", 1), `{"$set":{"a.b":1},"$inc":{"a.c":1}}`},
+		{"common prefix", NewUpdate().Set("a", 1).Set("ab", 1), `{"$set":{"a":1,"ab":1}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.update.Err(); err != nil {
+				t.Fatalf("Err: %v", err)
+			}
+
+			if got := renderJSON(t, tt.update.Bson()); got != tt.want {
+				t.Errorf("Bson() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		update *Update
+		want   error
+	}{
+		{"same path", NewUpdate().Set("a", 1).Inc("a", 1), ErrConflictingUpdate},
+		{"parent path", NewUpdate().Set("a.b", 1).Unset("a"), ErrConflictingUpdate},
+		{"child path", NewUpdate().Set("a", 1).Set("a.b", 1), ErrConflictingUpdate},
+		{"rename target", NewUpdate().Rename("a", "b").Set("b.c", 1), ErrConflictingUpdate},
+		{"rename onto set", NewUpdate().Set("b", 1).Rename("a", "b"), ErrConflictingUpdate},
+		{"inc string", NewUpdate().Inc("a", "1"), ErrInvalidValue},
+		{"push each scalar", NewUpdate().PushEach("a", 1, nil), ErrInvalidValue},
+		{"pull all scalar", NewUpdate().PullAll("a", 1), ErrInvalidValue},
+		{"pull nil query", NewUpdate().Pull("a", (*Query)(nil)), ErrInvalidValue},
+		{"pull invalid query", NewUpdate().Pull("a", NewQuery().Where("", SIZE, -1)), ErrInvalidValue},
+		{"nil array filter", NewUpdate().Set("a.$[x]", 1).ArrayFilters(nil), ErrInvalidValue},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.update.Err(); !errors.Is(err, tt.want) {
+				t.Errorf("Err() = %v, want %v", err, tt.want)
+			}
+
+			// rendering an invalid update must not panic
+			_ = tt.update.Bson()
+		})
+	}
+}
+
+func TestQueryUpdates(t *testing.T) {
+	ctx := context.Background()
+	c := testCollection(t,
+		`{"_id": 1, "kind": "a", "n": 1, "tags": [1, 5, 7]}`,
+		`{"_id": 2, "kind": "a", "n": 2}`,
+		`{"_id": 3, "kind": "b", "n": 3}`,
+	)
+
+	res, err := c.Query().Where("_id", EQ, 1).UpdateOne(ctx, NewUpdate().Pull("tags", NewQuery().Where("", GTE, 5)))
+	if err != nil || res.ModifiedCount != 1 {
+		t.Fatalf("UpdateOne = %+v, %v, want 1 modified", res, err)
+	}
+
+	res, err = c.Query().Where("kind", EQ, "a").UpdateMany(ctx, NewUpdate().Inc("n", 10))
+	if err != nil || res.ModifiedCount != 2 {
+		t.Fatalf("UpdateMany = %+v, %v, want 2 modified", res, err)
+	}
+
+	res, err = c.Query().Where("_id", EQ, 4).Upsert(ctx, NewUpdate().Set("n", 4))
+	if err != nil || res.UpsertedCount != 1 {
+		t.Fatalf("Upsert = %+v, %v, want 1 upserted", res, err)
+	}
+
+	var got bson.M
+	if err := c.Query().Where("_id", EQ, 3).FindOneAndUpdate(ctx, NewUpdate().Set("kind", "c"), &got); err != nil {
+		t.Fatalf("FindOneAndUpdate: %v", err)
+	}
+	if got["kind"] != "c" {
+		t.Errorf("FindOneAndUpdate decoded %v, want the updated document", got)
+	}
+
+	var docs []bson.M
+	if err := c.Query().Sort("_id", ASC).All(ctx, &docs); err != nil {
+		t.Fatalf("All: %v", err)
+	}
+
+	want := []bson.M{
+		{"_id": int32(1), "kind": "a", "n": int32(11), "tags": bson.A{int32(1)}},
+		{"_id": int32(2), "kind": "a", "n": int32(12)},
+		{"_id": int32(3), "kind": "c", "n": int32(3)},
+		{"_id": int32(4), "n": int32(4)},
+	}
+	if !reflect.DeepEqual(docs, want) {
+		t.Errorf("documents = %v, want %v", docs, want)
+	}
+
+	if _, err := c.Query().UpdateOne(ctx, NewUpdate()); !errors.Is(err, ErrEmptyUpdate) {
+		t.Errorf("UpdateOne of an empty update: error %v, want ErrEmptyUpdate", err)
+	}
+
+	if _, err := c.Query().UpdateOne(ctx, NewUpdate().Set("a", 1).Unset("a")); !errors.Is(err, ErrConflictingUpdate) {
+		t.Errorf("UpdateOne of a conflicting update: error %v, want ErrConflictingUpdate", err)
+	}
+
+	if err := c.Query().Where("_id", EQ, 9).FindOneAndUpdate(ctx, NewUpdate().Set("a", 1), &got); !errors.Is(err, ErrNotFound) {
+		t.Errorf("FindOneAndUpdate of nothing: error %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,81 @@
+package mongorm
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// versionKinds are the kinds of the fields tagged version.
+var versionKinds = map[reflect.Kind]bool{
+	reflect.Int:   true,
+	reflect.Int32: true,
+	reflect.Int64: true,
+}
+
+// Version makes the updates and deletes of the query only apply to documents whose version field
+// is v, the version that was loaded, and return ErrVersionConflict when they match nothing. Updates
+// increment the version, so a document changed by another writer since it was loaded is never
+// overwritten. The documents of the query must have a field tagged version.
+//
+// The check is opt-in: a query does not know the version its caller loaded, so updates of
+// versioned documents without Version still increment the version but apply to whatever version
+// is stored, the last write winning. Units of work check the loaded version of their instances.
+func (q *Query) Version(v int64) *Query {
+	nq := q.Clone()
+	nq.version = &v
+
+	if q.collection == nil || q.collection.model == nil || q.collection.model.Version == nil {
+		nq.setErr(fmt.Errorf("%w: the documents of the query have no version field", ErrInvalidModel))
+	}
+
+	return nq
+}
+
+// versioned restricts filter to the documents of the expected version of the query.
+func (q *Query) versioned(filter bson.D) bson.D {
+	if q.version == nil || q.collection == nil || q.collection.model == nil || q.collection.model.Version == nil {
+		return filter
+	}
+
+	return andFilter(filter, bson.D{{Key: q.collection.model.Version.Path, Value: *q.version}})
+}
+
+// checkVersion returns ErrVersionConflict when the query expects a version and the write of op matched n documents.
+func (q *Query) checkVersion(op string, n int64) error {
+	if q.version != nil && n == 0 {
+		return q.mapErr(op, ErrVersionConflict)
+	}
+
+	return nil
+}
+
+// fieldUpdates returns u with the updates of the fields the model maintains: the timestamps and the version.
+// The version is incremented by every update, while the filter only checks it with Query.Version.
+func (m *Model) fieldUpdates(u *Update, now time.Time) *Update {
+	u = m.stampUpdateBuilder(u, now)
+
+	if m != nil && m.Version != nil && !u.modifies(m.Version.Path) {
+		u = u.Inc(m.Version.Path, 1)
+	}
+
+	return u
+}
+
+// version returns the version of doc, or 0 when the model has no version field.
+func (m *Model) version(doc interface{}) int64 {
+	if v, ok := m.Version.settable(doc); ok {
+		return v.Int()
+	}
+
+	return 0
+}
+
+// setVersion sets the version of doc.
+func (m *Model) setVersion(doc interface{}, version int64) {
+	if v, ok := m.Version.settable(doc); ok {
+		v.SetInt(version)
+	}
+}
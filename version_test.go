@@ -0,0 +1,145 @@
+package mongorm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type versionedDoc struct {
+	ID        int        `bson:"_id"`
+	Body      string     `bson:"body"`
+	Version   int32      `bson:"version" mongorm:"version"`
+	DeletedAt *time.Time `bson:"deleted_at" mongorm:"deletedAt"`
+}
+
+func newVersionedCollection(t *testing.T) *TypedCollection[versionedDoc] {
+	t.Helper()
+
+	db := newTestDB(t)
+	if err := db.client.Register(&versionedDoc{}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	c := CollectionOf[versionedDoc](db, "versioned_docs")
+	if _, err := c.InsertMany(context.Background(), []versionedDoc{{ID: 1, Body: "a"}, {ID: 2, Body: "b"}}); err != nil {
+		t.Fatalf("InsertMany: %v", err)
+	}
+
+	return c
+}
+
+// versionOf returns the stored body and version of the document with the given _id.
+func versionOf(t *testing.T, c *TypedCollection[versionedDoc], id int) (string, int32) {
+	t.Helper()
+
+	doc, err := c.One(context.Background(), c.Query().WithDeleted().Where("_id", EQ, id))
+	if err != nil {
+		t.Fatalf("One %d: %v", id, err)
+	}
+
+	return doc.Body, doc.Version
+}
+
+func TestVersionedUpdates(t *testing.T) {
+	ctx := context.Background()
+	c := newVersionedCollection(t)
+	q := c.Query().Where("_id", EQ, 1)
+
+	if _, err := q.Version(0).UpdateOne(ctx, NewUpdate().Set("body", "b")); err != nil {
+		t.Fatalf("UpdateOne: %v", err)
+	}
+	if body, v := versionOf(t, c, 1); body != "b" || v != 1 {
+		t.Errorf("document = %q version %d, want b version 1", body, v)
+	}
+
+	// the stale version conflicts and leaves the document unchanged
+	if _, err := q.Version(0).UpdateOne(ctx, NewUpdate().Set("body", "c")); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("UpdateOne of a stale version: error %v, want ErrVersionConflict", err)
+	}
+
+	var doc versionedDoc
+	if err := q.Version(0).FindOneAndUpdate(ctx, NewUpdate().Set("body", "c"), &doc); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("FindOneAndUpdate of a stale version: error %v, want ErrVersionConflict", err)
+	}
+	if body, v := versionOf(t, c, 1); body != "b" || v != 1 {
+		t.Errorf("document after conflicts = %q version %d, want b version 1", body, v)
+	}
+
+	if err := q.Version(1).FindOneAndUpdate(ctx, NewUpdate().Set("body", "c"), &doc); err != nil || doc.Version != 2 {
+		t.Errorf("FindOneAndUpdate = %+v, %v, want version 2", doc, err)
+	}
+
+	// without Version, updates apply to any version and still increment it
+	if _, err := q.UpdateOne(ctx, NewUpdate().Set("body", "d")); err != nil {
+		t.Fatalf("UpdateOne: %v", err)
+	}
+	if res, err := c.Query().UpdateMany(ctx, NewUpdate().Set("body", "e")); err != nil || res.ModifiedCount != 2 {
+		t.Fatalf("UpdateMany = %+v, %v, want 2 modified documents", res, err)
+	}
+	if body, v := versionOf(t, c, 1); body != "e" || v != 4 {
+		t.Errorf("document = %q version %d, want e version 4", body, v)
+	}
+
+	// updates setting the version are left to it
+	if _, err := q.UpdateOne(ctx, NewUpdate().Set("version", 10)); err != nil {
+		t.Fatalf("UpdateOne: %v", err)
+	}
+	if _, v := versionOf(t, c, 1); v != 10 {
+		t.Errorf("version after setting it = %d, want 10", v)
+	}
+
+	// a missing document is only a conflict when the query expects a version
+	missing := c.Query().Where("_id", EQ, 3)
+	if res, err := missing.UpdateOne(ctx, NewUpdate().Set("body", "x")); err != nil || res.MatchedCount != 0 {
+		t.Errorf("UpdateOne of a missing document = %+v, %v, want no match", res, err)
+	}
+	if _, err := missing.Version(0).UpdateMany(ctx, NewUpdate().Set("body", "x")); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("UpdateMany of a missing document: error %v, want ErrVersionConflict", err)
+	}
+}
+
+func TestVersionedDeletes(t *testing.T) {
+	ctx := context.Background()
+	c := newVersionedCollection(t)
+
+	// soft deletes are updates, which increment the version
+	if _, err := c.Query().Where("_id", EQ, 1).Version(1).DeleteOne(ctx); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("DeleteOne of a stale version: error %v, want ErrVersionConflict", err)
+	}
+	if n, err := c.Query().Where("_id", EQ, 1).Version(0).DeleteOne(ctx); err != nil || n != 1 {
+		t.Errorf("DeleteOne = %d, %v, want 1", n, err)
+	}
+	if _, v := versionOf(t, c, 1); v != 1 {
+		t.Errorf("version after a soft delete = %d, want 1", v)
+	}
+
+	if _, err := c.Query().WithDeleted().Where("_id", EQ, 1).Version(0).ForceDeleteOne(ctx); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("ForceDeleteOne of a stale version: error %v, want ErrVersionConflict", err)
+	}
+	if n, err := c.Query().WithDeleted().Where("_id", EQ, 1).Version(1).ForceDeleteOne(ctx); err != nil || n != 1 {
+		t.Errorf("ForceDeleteOne = %d, %v, want 1", n, err)
+	}
+}
+
+func TestVersionWithoutField(t *testing.T) {
+	ctx := context.Background()
+	c := testCollection(t, `{"_id": 1}`)
+
+	if _, err := c.Query().Version(1).UpdateOne(ctx, NewUpdate().Set("a", 1)); !errors.Is(err, ErrInvalidModel) {
+		t.Errorf("UpdateOne with the version of an unversioned collection: error %v, want ErrInvalidModel", err)
+	}
+
+	client, err := NewMemoryClient()
+	if err != nil {
+		t.Fatalf("NewMemoryClient: %v", err)
+	}
+
+	invalid := struct {
+		Version string `bson:"version" mongorm:"version"`
+	}{}
+	if err := client.Register(invalid); !errors.Is(err, ErrInvalidModel) {
+		t.Errorf("Register of a string version: error %v, want ErrInvalidModel", err)
+	}
+}